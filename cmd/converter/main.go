@@ -16,17 +16,91 @@ var flagTargetDir = flag.String(
 	"target_dir", "", "tests directory",
 )
 
+var flagFrom = flag.String(
+	"from", "gonkey", "source format, one of gonkey, openapi, postman, hurl, har, curl",
+)
+
+var flagTo = flag.String(
+	"to", "declarate", "target format, one of declarate, hurl",
+)
+
+var flagEnv = flag.String(
+	"env", "", "postman environment file",
+)
+
+var flagHost = flag.String(
+	"host", "", "har entries host regexp filter",
+)
+
+var flagPath = flag.String(
+	"path", "", "har entries path regexp filter",
+)
+
+var flagSource = flag.String(
+	"source", "", "source file, can be passed as the last argument after flags, example `-from openapi api.yaml`",
+)
+
+type convertor interface {
+	Convert() error
+}
+
 func main() {
 	flag.Parse()
-	if *flagSourceDir == "" {
-		log.Println("source directory empty, pass -source_dir flag")
-		return
+	// flags after the first argument are not parsed, so they are rejected
+	// instead of being silently ignored
+	if flag.NArg() > 1 || (*flagSource != "" && flag.NArg() > 0) {
+		log.Printf("unexpected arguments %v, pass flags before source file or use -source flag", flag.Args())
+		os.Exit(1)
+	}
+	source := *flagSource
+	if source == "" && flag.NArg() > 0 {
+		source = flag.Arg(0)
 	}
 	if *flagTargetDir == "" {
 		log.Println("target directory empty, pass -target_dir flag")
 		return
 	}
-	c := converter.New(*flagSourceDir, *flagTargetDir)
+	if *flagTo == "hurl" {
+		if *flagSourceDir == "" {
+			log.Println("source directory empty, pass -source_dir flag")
+			return
+		}
+		convert(converter.NewHurlExporter(*flagSourceDir, *flagTargetDir))
+		return
+	}
+	if *flagFrom != "gonkey" && source == "" {
+		log.Println("source file empty, pass -source flag")
+		return
+	}
+	var c convertor
+	switch *flagFrom {
+	case "gonkey":
+		if *flagSourceDir == "" {
+			log.Println("source directory empty, pass -source_dir flag")
+			return
+		}
+		c = converter.New(*flagSourceDir, *flagTargetDir)
+	case "openapi":
+		c = converter.NewOpenAPI(source, *flagTargetDir)
+	case "postman":
+		c = converter.NewPostman(source, *flagEnv, *flagTargetDir)
+	case "hurl":
+		c = converter.NewHurl(source, *flagTargetDir)
+	case "curl":
+		c = converter.NewCurl(source, *flagTargetDir)
+	case "har":
+		c = converter.NewHAR(source, *flagTargetDir, converter.HARFilter{
+			Host: *flagHost,
+			Path: *flagPath,
+		})
+	default:
+		log.Printf("unknown source format %s", *flagFrom)
+		os.Exit(1)
+	}
+	convert(c)
+}
+
+func convert(c convertor) {
 	if err := c.Convert(); err != nil {
 		log.Printf("convert failed, %s", err)
 		os.Exit(1)
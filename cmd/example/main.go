@@ -46,6 +46,21 @@ var (
 		false,
 		"clear persistent",
 	)
+	flagReproDir = flag.String(
+		"repro_dir",
+		"",
+		"directory for reproduction scripts of failed tests",
+	)
+	flagMaxDuration = flag.Duration(
+		"max_duration",
+		0,
+		"default max duration of steps without maxDuration, example `-max_duration 2s`",
+	)
+	flagSMTPAddr = flag.String(
+		"smtp_addr",
+		"",
+		"address of smtp sink for email steps, example `-smtp_addr 127.0.0.1:2525`",
+	)
 )
 
 type stringList []string
@@ -99,6 +114,9 @@ func main() {
 		Tags:            tags,
 		Filepathes:      filePathes,
 		AllPersistent:   true,
+		ReproDir:        *flagReproDir,
+		MaxDuration:     *flagMaxDuration,
+		SMTPAddr:        *flagSMTPAddr,
 	})
 	if err := s.Run(); err != nil {
 		log.Println(err)
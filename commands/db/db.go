@@ -11,6 +11,7 @@ import (
 	"github.com/xwb1989/sqlparser"
 
 	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/tools"
 )
 
 type Db struct {
@@ -147,6 +148,18 @@ func (e *Db) IsValid() error {
 	return nil
 }
 
+func (e *Db) Repro() string {
+	if e.Config == nil || e.Config.DbQuery == "" {
+		return ""
+	}
+	conn := e.Config.DbConn
+	if conn == "" {
+		conn = e.connectLoader.DefaultConnectionString()
+	}
+
+	return fmt.Sprintf("psql %s -c %s", tools.ShellQuote(conn), tools.ShellQuote(e.Config.DbQuery))
+}
+
 func (e *Db) ResponseBody() *string {
 	return e.responseBody
 }
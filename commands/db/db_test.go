@@ -0,0 +1,32 @@
+package db
+
+import "testing"
+
+func TestDb_Repro(t *testing.T) {
+	loader := NewPGLoader("postgres://postgres@127.0.0.1:5440/?sslmode=disable")
+	tests := []struct {
+		name     string
+		cfg      *CheckConfig
+		expected string
+	}{
+		{
+			name:     "default connection",
+			cfg:      &CheckConfig{DbQuery: "select name from users where name = 'bob'"},
+			expected: `psql 'postgres://postgres@127.0.0.1:5440/?sslmode=disable' -c 'select name from users where name = '\''bob'\'''`,
+		},
+		{
+			name:     "step connection",
+			cfg:      &CheckConfig{DbConn: "postgres://app@db/app", DbQuery: "select 1"},
+			expected: `psql 'postgres://app@db/app' -c 'select 1'`,
+		},
+		{name: "no query", cfg: &CheckConfig{DbResponse: "[]"}, expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Db{Config: tt.cfg, connectLoader: loader}
+			if got := e.Repro(); got != tt.expected {
+				t.Errorf("Repro() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
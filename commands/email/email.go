@@ -0,0 +1,209 @@
+package email
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dailymotion/allure-go"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/tools"
+	"github.com/ixpectus/declarate/variables"
+)
+
+const defaultTimeout = 10 * time.Second
+
+type Email struct {
+	Config       *Config
+	Vars         contract.Vars
+	Report       contract.ReportAttachement
+	comparer     contract.Comparer
+	sink         *Sink
+	responseBody *string
+	// matched messages are removed from sink when check passes, so poll
+	// retries see them
+	matched []*Message
+	// failure is set when messages are not received in time
+	failure *contract.TestError
+}
+
+type Unmarshaller struct {
+	sink     *Sink
+	comparer contract.Comparer
+}
+
+// NewUnmarshaller makes email command builder, sink is nil when smtp sink
+// is not configured
+func NewUnmarshaller(sink *Sink, comparer contract.Comparer) *Unmarshaller {
+	return &Unmarshaller{
+		sink:     sink,
+		comparer: comparer,
+	}
+}
+
+type Check struct {
+	Email *Config `yaml:"email,omitempty"`
+}
+
+type Config struct {
+	// To is regexp matched with recipients
+	To string `json:"to" yaml:"to"`
+	// Subject is regexp matched with subject
+	Subject string `json:"subject" yaml:"subject"`
+	// Body is regexp matched with text or html
+	Body string `json:"body" yaml:"body"`
+	// Count of messages to wait for
+	Count int `json:"count" yaml:"count"`
+	// Timeout of waiting for messages
+	Timeout          time.Duration          `json:"timeout" yaml:"timeout"`
+	Response         *string                `json:"response" yaml:"response"`
+	ComparisonParams contract.CompareParams `json:"comparisonParams" yaml:"comparisonParams"`
+}
+
+func (u *Unmarshaller) Build(unmarshal func(any) error) (contract.Doer, error) {
+	cfg := &Check{}
+	if err := unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("unmarshall email: %w", err)
+	}
+	if cfg.Email == nil {
+		return nil, nil
+	}
+
+	return &Email{
+		Config:   cfg.Email,
+		comparer: u.comparer,
+		sink:     u.sink,
+	}, nil
+}
+
+func (e *Email) SetVars(vv contract.Vars) {
+	e.Vars = vv
+}
+
+func (e *Email) SetReport(r contract.ReportAttachement) {
+	e.Report = r
+}
+
+func (e *Email) GetConfig() any {
+	return e.Config
+}
+
+func (e *Email) IsValid() error {
+	if e.sink == nil {
+		return fmt.Errorf("smtp sink is not configured")
+	}
+	if e.Config.Count < 0 {
+		return fmt.Errorf("email count must be positive")
+	}
+	for _, v := range []string{e.Config.To, e.Config.Subject, e.Config.Body} {
+		if _, err := regexp.Compile(variables.VariableRx.ReplaceAllString(v, "2")); err != nil {
+			return fmt.Errorf("invalid email filter `%s`: %w", v, err)
+		}
+	}
+	if e.Config.Response != nil {
+		c := variables.VariableRx.ReplaceAllString(*e.Config.Response, "2")
+		if !json.Valid([]byte(c)) {
+			return fmt.Errorf("cannot parse email response: `%v`", *e.Config.Response)
+		}
+	}
+
+	return nil
+}
+
+// filter compiles regexp with variables applied, empty regexp matches all
+func (e *Email) filter(s string) (*regexp.Regexp, error) {
+	rx, err := regexp.Compile(e.Vars.Apply(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid email filter `%s`: %w", s, err)
+	}
+
+	return rx, nil
+}
+
+func (e *Email) Do() error {
+	e.responseBody = nil
+	e.matched = nil
+	e.failure = nil
+	to, err := e.filter(e.Config.To)
+	if err != nil {
+		return err
+	}
+	subject, err := e.filter(e.Config.Subject)
+	if err != nil {
+		return err
+	}
+	body, err := e.filter(e.Config.Body)
+	if err != nil {
+		return err
+	}
+	count := e.Config.Count
+	if count == 0 {
+		count = 1
+	}
+	timeout := e.Config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	messages := e.sink.wait(func(m *Message) bool {
+		recipient := false
+		for _, v := range m.To {
+			if to.MatchString(v) {
+				recipient = true
+				break
+			}
+		}
+		return recipient &&
+			subject.MatchString(m.Subject) &&
+			(body.MatchString(m.Text) || body.MatchString(m.HTML))
+	}, count, timeout)
+	b, err := json.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("marshall email messages: %w", err)
+	}
+	res := string(b)
+	e.responseBody = &res
+	e.matched = messages
+	if e.Report != nil {
+		e.Report.AddAttachment("emails", allure.ApplicationJson, []byte(tools.JSONPrettyPrint(res)))
+	}
+	if len(messages) < count {
+		e.failure = &contract.TestError{
+			Title:         "email not received",
+			Expected:      fmt.Sprintf("%d messages to `%s` with subject `%s` and body `%s`", count, to, subject, body),
+			Actual:        fmt.Sprintf("%d messages", len(messages)),
+			Message:       fmt.Sprintf("timeout %v exceeded", timeout),
+			OriginalError: fmt.Errorf("email not received, timeout %v exceeded", timeout),
+		}
+	}
+
+	return nil
+}
+
+// ResponseBody returns array of matched messages
+func (e *Email) ResponseBody() *string {
+	return e.responseBody
+}
+
+func (e *Email) Check() error {
+	if err := e.check(); err != nil {
+		return err
+	}
+	e.sink.remove(e.matched)
+
+	return nil
+}
+
+func (e *Email) check() error {
+	if e.failure != nil {
+		return e.failure
+	}
+	if e.Config.Response == nil || e.responseBody == nil {
+		return nil
+	}
+	expected := strings.TrimSuffix(e.Vars.Apply(*e.Config.Response), "\n")
+
+	return tools.CompareJSON(e.comparer, "email differs", expected, *e.responseBody, e.Config.ComparisonParams)
+}
@@ -0,0 +1,175 @@
+package email
+
+import (
+	"errors"
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+func newSink(t *testing.T) (*Sink, *Unmarshaller) {
+	t.Helper()
+	sink := NewSink("127.0.0.1:0")
+	if err := sink.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sink.Stop() })
+	vv := testutil.Vars{}
+
+	return sink, NewUnmarshaller(sink, compare.New(contract.CompareParams{}, vv))
+}
+
+func send(t *testing.T, sink *Sink, to string, msg string) {
+	t.Helper()
+	host, _, _ := net.SplitHostPort(sink.Addr())
+	auth := smtp.PlainAuth("", "user", "password", host)
+	msg = strings.TrimPrefix(msg, "\n")
+	if err := smtp.SendMail(sink.Addr(), auth, "noreply@example.com", []string{to}, []byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const resetMessage = `
+From: Service <noreply@example.com>
+To: john@example.com
+Subject: =?UTF-8?Q?Reset_password_=E2=9C=93?=
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Your code is 123456, reset link https://example.com/reset?token=3Dabc
+
+--inner
+Content-Type: text/html; charset=utf-8
+
+<a href="https://example.com/reset?token=abc&amp;lang=en">Reset</a>
+--inner--
+
+--outer
+Content-Type: text/plain
+Content-Disposition: attachment; filename="terms.txt"
+Content-Transfer-Encoding: base64
+
+dGVybXM=
+--outer--
+`
+
+func TestEmail_Receive(t *testing.T) {
+	sink, u := newSink(t)
+	send(t, sink, "other@example.com", "Subject: Welcome\n\nHello\n")
+	vv := testutil.Vars{"user": "john"}
+	e := testutil.Build[*Email](t, u, `
+email:
+  to: "{{$user}}@example\\.com"
+  subject: Reset password
+  body: code is \d+
+  timeout: 5s
+  response: |
+    [{
+      "from": "noreply@example.com",
+      "to": ["john@example.com"],
+      "subject": "Reset password ✓",
+      "text": "Your code is 123456, reset link https://example.com/reset?token=abc\n",
+      "links": ["https://example.com/reset?token=abc", "https://example.com/reset?token=abc&lang=en"],
+      "attachments": [{"filename": "terms.txt", "contentType": "text/plain", "size": 5}]
+    }]
+`, vv)
+	if err := e.IsValid(); err != nil {
+		t.Fatalf("IsValid() error = %v", err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- e.Do()
+	}()
+	send(t, sink, "john@example.com", resetMessage)
+	if err := <-done; err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if err := e.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	welcome := testutil.Build[*Email](t, u, `
+email:
+  subject: Welcome
+  timeout: 1s
+  response: '[{"to": ["other@example.com"], "text": "Hello\n"}]'
+`, vv)
+	if err := welcome.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if err := welcome.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+}
+
+func TestEmail_Timeout(t *testing.T) {
+	sink, u := newSink(t)
+	send(t, sink, "john@example.com", "Subject: Welcome\n\nHello\n")
+	e := testutil.Build[*Email](t, u, `
+email:
+  to: john@
+  count: 2
+  timeout: 200ms
+`, testutil.Vars{})
+	if err := e.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	var testErr *contract.TestError
+	if err := e.Check(); !errors.As(err, &testErr) {
+		t.Errorf("Check() error = %v, want test error", err)
+	}
+	if len(sink.messages) != 1 {
+		t.Errorf("messages of failed check are removed from sink, got %d", len(sink.messages))
+	}
+}
+
+func TestEmail_PollRetry(t *testing.T) {
+	sink, u := newSink(t)
+	send(t, sink, "john@example.com", "Subject: Welcome\n\nHello\n")
+	step := `
+email:
+  to: john@
+  timeout: 1s
+  response: '[{"subject": "Welcome", "text": "Hello\n"}]'
+`
+	differs := testutil.Build[*Email](t, u, strings.Replace(step, "Hello", "Bye", 1), testutil.Vars{})
+	if err := differs.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	var testErr *contract.TestError
+	if err := differs.Check(); !errors.As(err, &testErr) {
+		t.Fatalf("Check() error = %v, want test error", err)
+	}
+	// the next poll attempt still sees the message
+	retry := testutil.Run[*Email](t, u, step, testutil.Vars{})
+	if err := retry.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	if len(sink.messages) != 0 {
+		t.Errorf("checked messages are left in sink, got %d", len(sink.messages))
+	}
+}
+
+func TestEmail_IsValid(t *testing.T) {
+	_, u := newSink(t)
+	e := testutil.Build[*Email](t, u, "email:\n  subject: '('\n", testutil.Vars{})
+	if err := e.IsValid(); err == nil {
+		t.Errorf("IsValid() error = nil, want invalid regexp error")
+	}
+	e = testutil.Build[*Email](t, NewUnmarshaller(nil, nil), "email:\n  subject: a\n", testutil.Vars{})
+	if err := e.IsValid(); err == nil {
+		t.Errorf("IsValid() error = nil, want sink is not configured error")
+	}
+}
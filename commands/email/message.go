@@ -0,0 +1,160 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+var linkRx = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+// Message is received email, it is step response
+type Message struct {
+	From        string            `json:"from"`
+	To          []string          `json:"to"`
+	Subject     string            `json:"subject"`
+	Headers     map[string]string `json:"headers"`
+	Text        string            `json:"text"`
+	HTML        string            `json:"html"`
+	Links       []string          `json:"links"`
+	Attachments []Attachment      `json:"attachments"`
+}
+
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int    `json:"size"`
+}
+
+// parse parses message data, from and to are smtp envelope addresses
+func parse(from string, to []string, data []byte) (*Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("read message: %w", err)
+	}
+	dec := &mime.WordDecoder{}
+	res := &Message{
+		From:        from,
+		To:          to,
+		Headers:     map[string]string{},
+		Links:       []string{},
+		Attachments: []Attachment{},
+	}
+	for k := range m.Header {
+		v, err := dec.DecodeHeader(m.Header.Get(k))
+		if err != nil {
+			v = m.Header.Get(k)
+		}
+		res.Headers[k] = v
+	}
+	res.Subject = res.Headers["Subject"]
+	err = res.addPart(
+		m.Header.Get("Content-Type"),
+		m.Header.Get("Content-Transfer-Encoding"),
+		m.Header.Get("Content-Disposition"),
+		m.Body,
+	)
+	if err != nil {
+		return nil, err
+	}
+	res.Links = links(res.Text, res.HTML)
+
+	return res, nil
+}
+
+// addPart adds text, html or attachment of part, multipart parts are added
+// recursively
+func (m *Message) addPart(contentType, encoding, disposition string, body io.Reader) error {
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("parse content type %s: %w", contentType, err)
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		r := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := r.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("read message part: %w", err)
+			}
+			err = m.addPart(
+				p.Header.Get("Content-Type"),
+				p.Header.Get("Content-Transfer-Encoding"),
+				p.Header.Get("Content-Disposition"),
+				p,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	switch strings.ToLower(encoding) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("read message part: %w", err)
+	}
+	filename := ""
+	if disposition != "" {
+		if d, dparams, err := mime.ParseMediaType(disposition); err == nil {
+			filename = dparams["filename"]
+			if d == "attachment" && filename == "" {
+				filename = "attachment"
+			}
+		}
+	}
+	if filename == "" {
+		filename = params["name"]
+	}
+	switch {
+	case filename != "":
+	case mediaType == "text/plain":
+		m.Text += string(content)
+		return nil
+	case mediaType == "text/html":
+		m.HTML += string(content)
+		return nil
+	}
+	m.Attachments = append(m.Attachments, Attachment{
+		Filename:    filename,
+		ContentType: mediaType,
+		Size:        len(content),
+	})
+
+	return nil
+}
+
+// links returns unique links of text and html in order of appearance
+func links(text, htmlBody string) []string {
+	res := []string{}
+	seen := map[string]bool{}
+	found := linkRx.FindAllString(text, -1)
+	for _, v := range linkRx.FindAllString(htmlBody, -1) {
+		found = append(found, html.UnescapeString(v))
+	}
+	for _, v := range found {
+		if !seen[v] {
+			seen[v] = true
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,233 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink is smtp server accepting any message, received messages are kept
+// until email step takes them
+type Sink struct {
+	addr     string
+	listener net.Listener
+	mu       sync.Mutex
+	messages []*Message
+	// received is closed and replaced when message is received
+	received chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewSink makes sink listening on addr, it accepts connections after Start
+func NewSink(addr string) *Sink {
+	return &Sink{
+		addr:     addr,
+		received: make(chan struct{}),
+	}
+}
+
+func (s *Sink) Start() error {
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("start smtp sink on %s: %w", s.addr, err)
+	}
+	s.listener = l
+	s.wg.Add(1)
+	go s.serve()
+
+	return nil
+}
+
+func (s *Sink) Stop() error {
+	if s.listener == nil {
+		return nil
+	}
+	err := s.listener.Close()
+	s.wg.Wait()
+
+	return err
+}
+
+// Addr returns listening address, it differs from configured one when
+// port is 0
+func (s *Sink) Addr() string {
+	if s.listener == nil {
+		return s.addr
+	}
+	return s.listener.Addr().String()
+}
+
+func (s *Sink) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("smtp sink accept: %v\n", err)
+			}
+			return
+		}
+		go s.handle(c)
+	}
+}
+
+// handle talks smtp with client, authentication is accepted without checks
+func (s *Sink) handle(c net.Conn) {
+	defer c.Close()
+	conn := textproto.NewConn(c)
+	_ = conn.PrintfLine("220 declarate smtp sink")
+	var (
+		from string
+		to   []string
+	)
+	for {
+		_ = c.SetDeadline(time.Now().Add(time.Minute))
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			_ = conn.PrintfLine("250 declarate")
+		case "EHLO":
+			_ = conn.PrintfLine("250-declarate")
+			_ = conn.PrintfLine("250-8BITMIME")
+			_ = conn.PrintfLine("250 AUTH PLAIN LOGIN")
+		case "AUTH":
+			if err := auth(conn, arg); err != nil {
+				return
+			}
+		case "MAIL":
+			from = address(arg)
+			to = nil
+			_ = conn.PrintfLine("250 OK")
+		case "RCPT":
+			to = append(to, address(arg))
+			_ = conn.PrintfLine("250 OK")
+		case "DATA":
+			if len(to) == 0 {
+				_ = conn.PrintfLine("503 RCPT first")
+				continue
+			}
+			_ = conn.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, err := conn.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg, err := parse(from, to, data)
+			if err != nil {
+				_ = conn.PrintfLine("554 %v", err)
+				continue
+			}
+			s.add(msg)
+			_ = conn.PrintfLine("250 OK")
+		case "RSET":
+			from = ""
+			to = nil
+			_ = conn.PrintfLine("250 OK")
+		case "NOOP":
+			_ = conn.PrintfLine("250 OK")
+		case "QUIT":
+			_ = conn.PrintfLine("221 bye")
+			return
+		default:
+			_ = conn.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+// auth accepts PLAIN and LOGIN authentication with any credentials
+func auth(conn *textproto.Conn, arg string) error {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	switch strings.ToUpper(mechanism) {
+	case "PLAIN":
+		if initial == "" {
+			_ = conn.PrintfLine("334 ")
+			if _, err := conn.ReadLine(); err != nil {
+				return err
+			}
+		}
+	case "LOGIN":
+		prompts := []string{"VXNlcm5hbWU6", "UGFzc3dvcmQ6"}
+		if initial != "" {
+			prompts = prompts[1:]
+		}
+		for _, v := range prompts {
+			_ = conn.PrintfLine("334 %s", v)
+			if _, err := conn.ReadLine(); err != nil {
+				return err
+			}
+		}
+	default:
+		return conn.PrintfLine("504 unrecognized authentication type")
+	}
+
+	return conn.PrintfLine("235 authentication successful")
+}
+
+// address extracts address from `FROM:<addr>` or `TO:<addr>` argument
+func address(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr = strings.TrimSpace(addr)
+	if i := strings.Index(addr, ">"); i >= 0 {
+		addr = addr[:i]
+	}
+
+	return strings.TrimPrefix(addr, "<")
+}
+
+func (s *Sink) add(msg *Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	close(s.received)
+	s.received = make(chan struct{})
+}
+
+// wait waits until count messages match or timeout exceeds, matched
+// messages are kept in sink until they are removed
+func (s *Sink) wait(match func(*Message) bool, count int, timeout time.Duration) []*Message {
+	deadline := time.Now().Add(timeout)
+	for {
+		s.mu.Lock()
+		matched := []*Message{}
+		for _, v := range s.messages {
+			if len(matched) < count && match(v) {
+				matched = append(matched, v)
+			}
+		}
+		received := s.received
+		if len(matched) == count || !time.Now().Before(deadline) {
+			s.mu.Unlock()
+			return matched
+		}
+		s.mu.Unlock()
+		select {
+		case <-received:
+		case <-time.After(time.Until(deadline)):
+		}
+	}
+}
+
+// remove removes messages, so next steps don't see them again
+func (s *Sink) remove(messages []*Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := map[*Message]bool{}
+	for _, v := range messages {
+		removed[v] = true
+	}
+	left := make([]*Message, 0, len(s.messages))
+	for _, v := range s.messages {
+		if !removed[v] {
+			left = append(left, v)
+		}
+	}
+	s.messages = left
+}
@@ -0,0 +1,456 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/dailymotion/allure-go"
+	"github.com/tidwall/gjson"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/ixpectus/declarate/commands/request"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/tools"
+	"github.com/ixpectus/declarate/variables"
+)
+
+const defaultPath = "/graphql"
+
+// GraphQL sends query through request command, so services, auth, transport
+// and cookies of request steps are used
+type GraphQL struct {
+	Config   *Config
+	Vars     contract.Vars
+	Report   contract.ReportAttachement
+	comparer contract.Comparer
+	request  *request.Request
+	schemas  *schemas
+	schema   string
+	fileName string
+	// status, data and errors of the last response
+	status *string
+	data   *string
+	errors []gjson.Result
+}
+
+type Unmarshaller struct {
+	requests *request.Unmarshaller
+	comparer contract.Comparer
+	schema   string
+	schemas  *schemas
+}
+
+type Option func(*Unmarshaller)
+
+// OptionSchema sets default schema file used to validate queries
+func OptionSchema(path string) Option {
+	return func(u *Unmarshaller) {
+		u.schema = path
+	}
+}
+
+func NewUnmarshaller(
+	requests *request.Unmarshaller,
+	comparer contract.Comparer,
+	opts ...Option,
+) *Unmarshaller {
+	u := &Unmarshaller{
+		requests: requests,
+		comparer: comparer,
+		schemas:  &schemas{schemas: map[string]*ast.Schema{}},
+	}
+	for _, v := range opts {
+		v(u)
+	}
+
+	return u
+}
+
+type Check struct {
+	GraphQL *Config `yaml:"graphql,omitempty"`
+}
+
+type Config struct {
+	Service       string            `json:"service" yaml:"service"`
+	Path          string            `json:"path" yaml:"path"`
+	Query         string            `json:"query" yaml:"query"`
+	OperationName string            `json:"operationName" yaml:"operationName"`
+	Variables     map[string]any    `json:"variables" yaml:"variables"`
+	HeadersVal    map[string]string `json:"headers" yaml:"headers"`
+	// Schema is path to schema file relative to the test file, query is
+	// validated against it
+	Schema string `json:"schema" yaml:"schema"`
+	// Response is expected data
+	Response *string `json:"response" yaml:"response"`
+	// ResponseStatus is expected http status, any 2xx status by default
+	ResponseStatus *string `json:"responseStatus" yaml:"responseStatus"`
+	// Errors are expected errors, response must not contain errors when empty
+	Errors           []ErrorConfig          `json:"errors" yaml:"errors"`
+	ComparisonParams contract.CompareParams `json:"comparisonParams" yaml:"comparisonParams"`
+}
+
+// ErrorConfig matches error by message regexp and by path joined with dots
+type ErrorConfig struct {
+	Message string `json:"message" yaml:"message"`
+	Path    string `json:"path" yaml:"path"`
+}
+
+func (u *Unmarshaller) Build(unmarshal func(any) error) (contract.Doer, error) {
+	cfg := &Check{}
+	if err := unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("unmarshall graphql: %w", err)
+	}
+	if cfg.GraphQL == nil {
+		return nil, nil
+	}
+	path := cfg.GraphQL.Path
+	if path == "" {
+		path = defaultPath
+	}
+	cmd, err := u.requests.Build(func(v any) error {
+		reqCfg, ok := v.(*request.RequestConfig)
+		if !ok {
+			return fmt.Errorf("unexpected request config %T", v)
+		}
+		reqCfg.Method = "POST"
+		reqCfg.RequestURL = path
+		reqCfg.Service = cfg.GraphQL.Service
+		reqCfg.HeadersVal = cfg.GraphQL.HeadersVal
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, ok := cmd.(*request.Request)
+	if !ok {
+		return nil, fmt.Errorf("unexpected request command %T", cmd)
+	}
+
+	return &GraphQL{
+		Config:   cfg.GraphQL,
+		comparer: u.comparer,
+		request:  req,
+		schemas:  u.schemas,
+		schema:   u.schema,
+	}, nil
+}
+
+func (e *GraphQL) SetVars(vv contract.Vars) {
+	e.Vars = vv
+	e.request.SetVars(vv)
+}
+
+func (e *GraphQL) SetReport(r contract.ReportAttachement) {
+	e.Report = r
+	e.request.SetReport(r)
+}
+
+func (e *GraphQL) SetFileName(fileName string) {
+	e.fileName = fileName
+	e.request.SetFileName(fileName)
+}
+
+func (e *GraphQL) GetConfig() any {
+	return e.Config
+}
+
+func (e *GraphQL) IsValid() error {
+	if strings.TrimSpace(e.Config.Query) == "" {
+		return fmt.Errorf("graphql query is empty")
+	}
+	if err := e.request.IsValid(); err != nil {
+		return err
+	}
+	if e.Config.Response != nil {
+		c := variables.VariableRx.ReplaceAllString(*e.Config.Response, "2")
+		if !json.Valid([]byte(c)) {
+			return fmt.Errorf("cannot parse graphql response: `%v`", *e.Config.Response)
+		}
+	}
+	for _, v := range e.Config.Errors {
+		if _, err := regexp.Compile(v.Message); err != nil {
+			return fmt.Errorf("compile graphql error message: %w", err)
+		}
+	}
+
+	return e.validateQuery()
+}
+
+// validateQuery checks query against schema, variables in query are
+// replaced like in response validation
+func (e *GraphQL) validateQuery() error {
+	path := e.schema
+	if e.Config.Schema != "" {
+		path = e.Config.Schema
+		if !filepath.IsAbs(path) && e.fileName != "" {
+			path = filepath.Join(filepath.Dir(e.fileName), path)
+		}
+	}
+	if path == "" {
+		return nil
+	}
+	schema, err := e.schemas.get(path)
+	if err != nil {
+		return err
+	}
+	query := variables.VariableRx.ReplaceAllString(e.Config.Query, "2")
+	if _, errs := gqlparser.LoadQuery(schema, query); len(errs) > 0 {
+		return fmt.Errorf("graphql query does not match schema %s: %w", path, errs)
+	}
+
+	return nil
+}
+
+func (e *GraphQL) Do() error {
+	e.status = nil
+	e.data = nil
+	e.errors = nil
+	body := map[string]any{
+		"query": e.Vars.Apply(e.Config.Query),
+	}
+	if e.Config.OperationName != "" {
+		body["operationName"] = e.Vars.Apply(e.Config.OperationName)
+	}
+	if e.Config.Variables != nil {
+		body["variables"] = e.apply(e.Config.Variables)
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshall graphql request: %w", err)
+	}
+	if e.Report != nil {
+		e.Report.AddAttachment("query", allure.TextPlain, []byte(e.Vars.Apply(e.Config.Query)))
+	}
+	e.request.Config.RequestTmpl = string(b)
+	if err := e.request.Do(); err != nil {
+		return err
+	}
+	envelope := e.request.Envelope()
+	if envelope == nil {
+		return nil
+	}
+	status := gjson.Get(*envelope, "status").String()
+	e.status = &status
+	res := gjson.Get(*envelope, "body")
+	if !res.IsObject() || (!res.Get("data").Exists() && !res.Get("errors").Exists()) {
+		// response without data is checked only by expected status
+		if e.Config.ResponseStatus != nil {
+			return nil
+		}
+		return fmt.Errorf("unexpected graphql response, status %s: %s", status, res.String())
+	}
+	data := res.Get("data").Raw
+	if data == "" {
+		data = "null"
+	}
+	e.data = &data
+	e.errors = res.Get("errors").Array()
+
+	return nil
+}
+
+// apply substitutes variables in string values, yaml maps are converted to
+// json compatible ones
+func (e *GraphQL) apply(v any) any {
+	switch vv := v.(type) {
+	case string:
+		return e.Vars.Apply(vv)
+	case map[string]any:
+		res := make(map[string]any, len(vv))
+		for k, v := range vv {
+			res[k] = e.apply(v)
+		}
+		return res
+	case map[any]any:
+		res := make(map[string]any, len(vv))
+		for k, v := range vv {
+			res[fmt.Sprintf("%v", k)] = e.apply(v)
+		}
+		return res
+	case []any:
+		res := make([]any, len(vv))
+		for i, v := range vv {
+			res[i] = e.apply(v)
+		}
+		return res
+	}
+
+	return v
+}
+
+func (e *GraphQL) Repro() string {
+	return e.request.Repro()
+}
+
+// ResponseBody returns data, so variables are taken from it
+func (e *GraphQL) ResponseBody() *string {
+	return e.data
+}
+
+func (e *GraphQL) Check() error {
+	if e.status == nil {
+		return nil
+	}
+	if err := e.checkStatus(); err != nil {
+		return err
+	}
+	if e.data == nil {
+		return nil
+	}
+	if err := e.checkErrors(); err != nil {
+		return err
+	}
+	if e.Config.Response == nil {
+		return nil
+	}
+	expected := strings.TrimSuffix(e.Vars.Apply(*e.Config.Response), "\n")
+	errs, err := e.comparer.CompareJsonBody(expected, *e.data, e.Config.ComparisonParams)
+	if len(errs) > 0 {
+		msg := ""
+		for i, v := range errs {
+			if i < len(errs)-1 {
+				msg += v.Error() + "\n"
+			} else {
+				msg += v.Error()
+			}
+		}
+
+		return &contract.TestError{
+			Title:         "graphql data differs",
+			Expected:      tools.JSONPrettyPrint(expected),
+			Actual:        tools.JSONPrettyPrint(*e.data),
+			Message:       msg,
+			OriginalError: fmt.Errorf("graphql data differs: %v", msg),
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("compare json failed: %w", err)
+	}
+
+	return nil
+}
+
+// checkStatus compares status with expected one, any 2xx status passes
+// when it is not set
+func (e *GraphQL) checkStatus() error {
+	if e.Config.ResponseStatus == nil {
+		if strings.HasPrefix(*e.status, "2") && len(*e.status) == 3 {
+			return nil
+		}
+		return &contract.TestError{
+			Title:         "graphql status differs",
+			Expected:      "2xx",
+			Actual:        *e.status,
+			Message:       fmt.Sprintf("unexpected status %s", *e.status),
+			OriginalError: fmt.Errorf("graphql status differs: unexpected status %s", *e.status),
+		}
+	}
+	expected := strings.TrimSuffix(e.Vars.Apply(*e.Config.ResponseStatus), "\n")
+	errs := e.comparer.Compare(expected, *e.status, e.Config.ComparisonParams)
+	if len(errs) > 0 {
+		msgs := make([]string, 0, len(errs))
+		for _, v := range errs {
+			msgs = append(msgs, v.Error())
+		}
+		msg := strings.Join(msgs, "\n")
+		return &contract.TestError{
+			Title:         "graphql status differs",
+			Expected:      expected,
+			Actual:        *e.status,
+			Message:       msg,
+			OriginalError: fmt.Errorf("graphql status differs: %v", msg),
+		}
+	}
+
+	return nil
+}
+
+func (e *GraphQL) checkErrors() error {
+	actual := make([]string, 0, len(e.errors))
+	for _, v := range e.errors {
+		actual = append(actual, errorString(v.Get("message").String(), errorPath(v)))
+	}
+	var msgs []string
+	if len(e.Config.Errors) == 0 && len(e.errors) > 0 {
+		msgs = append(msgs, "unexpected graphql errors")
+	}
+	expected := make([]string, 0, len(e.Config.Errors))
+	for _, v := range e.Config.Errors {
+		message := e.Vars.Apply(v.Message)
+		path := e.Vars.Apply(v.Path)
+		expected = append(expected, errorString(message, path))
+		rx, err := regexp.Compile(message)
+		if err != nil {
+			return fmt.Errorf("compile graphql error message: %w", err)
+		}
+		found := false
+		for _, got := range e.errors {
+			if rx.MatchString(got.Get("message").String()) && (path == "" || path == errorPath(got)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			msgs = append(msgs, fmt.Sprintf("error %s not found", errorString(message, path)))
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	msg := strings.Join(msgs, "\n")
+
+	return &contract.TestError{
+		Title:         "graphql errors differ",
+		Expected:      strings.Join(expected, "\n"),
+		Actual:        strings.Join(actual, "\n"),
+		Message:       msg,
+		OriginalError: fmt.Errorf("graphql errors differ: %v", msg),
+	}
+}
+
+func errorPath(v gjson.Result) string {
+	parts := []string{}
+	for _, p := range v.Get("path").Array() {
+		parts = append(parts, p.String())
+	}
+
+	return strings.Join(parts, ".")
+}
+
+func errorString(message, path string) string {
+	if path == "" {
+		return message
+	}
+	return path + ": " + message
+}
+
+// schemas caches parsed schema files
+type schemas struct {
+	mu      sync.Mutex
+	schemas map[string]*ast.Schema
+}
+
+func (s *schemas) get(path string) (*ast.Schema, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if schema, ok := s.schemas[path]; ok {
+		return schema, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read graphql schema: %w", err)
+	}
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: path, Input: string(b)})
+	if err != nil {
+		return nil, fmt.Errorf("parse graphql schema %s: %w", path, err)
+	}
+	s.schemas[path] = schema
+
+	return schema, nil
+}
@@ -0,0 +1,230 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ixpectus/declarate/commands/request"
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+// petsServer answers pet query, unknown pet is returned with error
+func petsServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			Query         string            `json:"query"`
+			OperationName string            `json:"operationName"`
+			Variables     map[string]string `json:"variables"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if r.URL.Path != "/graphql" || req.OperationName != "Pet" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.Variables["id"] != "1" {
+			w.Write([]byte(`{"data":{"pet":null},"errors":[{"message":"pet ` + req.Variables["id"] + ` not found","path":["pet"]}]}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"pet":{"id":"1","name":"Rex"}}}`))
+	}))
+}
+
+func TestGraphQL(t *testing.T) {
+	srv := petsServer(t)
+	defer srv.Close()
+	vv := testutil.Vars{"id": "1"}
+	cmp := compare.New(contract.CompareParams{}, vv)
+	u := NewUnmarshaller(request.NewUnmarshaller(srv.URL, cmp), cmp)
+
+	tests := []struct {
+		name    string
+		step    string
+		wantErr bool
+		data    string
+	}{
+		{
+			name: "data",
+			step: `
+graphql:
+  query: 'query Pet($id: ID!) { pet(id: $id) { id name } }'
+  operationName: Pet
+  variables:
+    id: "{{$id}}"
+  response: '{"pet": {"name": "Rex", "id": "$matchRegexp(^[0-9]+$)"}}'
+`,
+			data: `{"pet":{"id":"1","name":"Rex"}}`,
+		},
+		{
+			name: "data differs",
+			step: `
+graphql:
+  query: 'query Pet($id: ID!) { pet(id: $id) { id name } }'
+  operationName: Pet
+  variables:
+    id: "{{$id}}"
+  response: '{"pet": {"name": "Tom"}}'
+`,
+			wantErr: true,
+		},
+		{
+			name: "expected error",
+			step: `
+graphql:
+  query: 'query Pet($id: ID!) { pet(id: $id) { id name } }'
+  operationName: Pet
+  variables:
+    id: "2"
+  errors:
+    - message: not found$
+      path: pet
+`,
+			data: `{"pet":null}`,
+		},
+		{
+			name: "unexpected error",
+			step: `
+graphql:
+  query: 'query Pet($id: ID!) { pet(id: $id) { id name } }'
+  operationName: Pet
+  variables:
+    id: "2"
+`,
+			wantErr: true,
+		},
+		{
+			name: "error path differs",
+			step: `
+graphql:
+  query: 'query Pet($id: ID!) { pet(id: $id) { id name } }'
+  operationName: Pet
+  variables:
+    id: "2"
+  errors:
+    - message: not found
+      path: owner
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testutil.Build[*GraphQL](t, u, tt.step, vv)
+			if err := g.IsValid(); err != nil {
+				t.Fatalf("IsValid() error = %v", err)
+			}
+			if err := g.Do(); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			err := g.Check()
+			var testErr *contract.TestError
+			if tt.wantErr && !errors.As(err, &testErr) {
+				t.Errorf("Check() error = %v, want test error", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Check() error = %v", err)
+			}
+			if tt.data != "" && *g.ResponseBody() != tt.data {
+				t.Errorf("ResponseBody() = %s, want %s", *g.ResponseBody(), tt.data)
+			}
+		})
+	}
+}
+
+func TestGraphQL_Schema(t *testing.T) {
+	u := NewUnmarshaller(request.NewUnmarshaller("", nil), nil)
+	valid := testutil.Build[*GraphQL](t, u, `
+graphql:
+  schema: schema.graphql
+  query: 'query Pet($id: ID!) { pet(id: $id) { name } }'
+`, testutil.Vars{})
+	if err := valid.IsValid(); err != nil {
+		t.Errorf("IsValid() error = %v", err)
+	}
+	invalid := testutil.Build[*GraphQL](t, u, `
+graphql:
+  schema: schema.graphql
+  query: 'query Pet($id: ID!) { pet(id: $id) { age } }'
+`, testutil.Vars{})
+	if err := invalid.IsValid(); err == nil || !strings.Contains(err.Error(), "age") {
+		t.Errorf("IsValid() error = %v, want unknown field error", err)
+	}
+}
+
+func TestGraphQL_UnexpectedResponse(t *testing.T) {
+	srv := petsServer(t)
+	defer srv.Close()
+	u := NewUnmarshaller(request.NewUnmarshaller(srv.URL, nil), nil)
+	g := testutil.Build[*GraphQL](t, u, `
+graphql:
+  path: /other
+  query: "{ pet(id: 1) { name } }"
+  operationName: Pet
+`, testutil.Vars{})
+	if err := g.Do(); err == nil {
+		t.Errorf("expected error for non graphql response")
+	}
+}
+
+func TestGraphQL_ResponseStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"data":null,"errors":[{"message":"internal"}]}`))
+	}))
+	defer srv.Close()
+	vv := testutil.Vars{}
+	cmp := compare.New(contract.CompareParams{}, vv)
+	u := NewUnmarshaller(request.NewUnmarshaller(srv.URL, cmp), cmp)
+
+	tests := []struct {
+		name    string
+		step    string
+		wantErr bool
+	}{
+		{
+			name:    "not 2xx by default",
+			step:    "graphql:\n  query: '{ pet { id } }'\n  errors:\n    - message: internal\n",
+			wantErr: true,
+		},
+		{
+			name: "expected status",
+			step: "graphql:\n  query: '{ pet { id } }'\n  responseStatus: 500\n  errors:\n    - message: internal\n",
+		},
+		{
+			name:    "other status",
+			step:    "graphql:\n  query: '{ pet { id } }'\n  responseStatus: 200\n  errors:\n    - message: internal\n",
+			wantErr: true,
+		},
+		{
+			name: "expected status without data",
+			step: "graphql:\n  path: /other\n  query: '{ pet { id } }'\n  responseStatus: 404\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testutil.Valid[*GraphQL](t, u, tt.step, vv)
+			if err := g.Do(); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			err := g.Check()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var testErr *contract.TestError
+			if err != nil && !errors.As(err, &testErr) {
+				t.Errorf("Check() error = %v, want test error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/grpcreflect"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// conns stores connections by address and tls settings, so they are
+// shared between steps
+type conns struct {
+	mu    sync.Mutex
+	conns map[string]*gogrpc.ClientConn
+}
+
+func newConns() *conns {
+	return &conns{
+		conns: map[string]*gogrpc.ClientConn{},
+	}
+}
+
+func (c *conns) get(address string, withTLS, insecureSkipVerify bool) (*gogrpc.ClientConn, error) {
+	key := fmt.Sprintf("%s %v %v", address, withTLS, insecureSkipVerify)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if conn, ok := c.conns[key]; ok {
+		return conn, nil
+	}
+	creds := insecure.NewCredentials()
+	if withTLS {
+		creds = credentials.NewTLS(&tls.Config{
+			InsecureSkipVerify: insecureSkipVerify, //nolint:gosec
+		})
+	}
+	conn, err := gogrpc.Dial(address, gogrpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", address, err)
+	}
+	c.conns[key] = conn
+
+	return conn, nil
+}
+
+func (c *conns) closeAll() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for k, conn := range c.conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close grpc connection %s: %w", k, err))
+		}
+		delete(c.conns, k)
+	}
+
+	return errors.Join(errs...)
+}
+
+// descriptors stores parsed proto files by import paths and files, so steps
+// with the same files parse them once
+type descriptors struct {
+	mu    sync.Mutex
+	files map[string][]*desc.FileDescriptor
+}
+
+func newDescriptors() *descriptors {
+	return &descriptors{
+		files: map[string][]*desc.FileDescriptor{},
+	}
+}
+
+func (d *descriptors) get(importPaths, files []string) ([]*desc.FileDescriptor, error) {
+	key := strings.Join(importPaths, ",") + " " + strings.Join(files, ",")
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if fds, ok := d.files[key]; ok {
+		return fds, nil
+	}
+	parser := protoparse.Parser{ImportPaths: importPaths}
+	fds, err := parser.ParseFiles(files...)
+	if err != nil {
+		return nil, fmt.Errorf("parse proto files: %w", err)
+	}
+	d.files[key] = fds
+
+	return fds, nil
+}
+
+// splitMethod splits `package.Service/Method` or `package.Service.Method`
+func splitMethod(method string) (string, string, error) {
+	if service, name, ok := strings.Cut(method, "/"); ok {
+		return service, name, nil
+	}
+	i := strings.LastIndex(method, ".")
+	if i <= 0 || i == len(method)-1 {
+		return "", "", fmt.Errorf("method `%s` must be in form package.Service/Method", method)
+	}
+
+	return method[:i], method[i+1:], nil
+}
+
+// methodDescriptor finds method in proto files or with server reflection
+// when files are not set
+func (e *GRPC) methodDescriptor(ctx context.Context, conn *gogrpc.ClientConn) (protoreflect.MethodDescriptor, error) {
+	serviceName, methodName, err := splitMethod(e.Vars.Apply(e.Config.Method))
+	if err != nil {
+		return nil, err
+	}
+	var service *desc.ServiceDescriptor
+	if len(e.Config.ProtoFiles) > 0 {
+		service, err = e.protoService(serviceName)
+	} else {
+		client := grpcreflect.NewClientAuto(ctx, conn)
+		defer client.Reset()
+		service, err = client.ResolveService(serviceName)
+		if err != nil {
+			err = fmt.Errorf("resolve service %s with reflection: %w", serviceName, err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	method := service.UnwrapService().Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, fmt.Errorf("method %s not found in service %s", methodName, serviceName)
+	}
+
+	return method, nil
+}
+
+func (e *GRPC) protoService(serviceName string) (*desc.ServiceDescriptor, error) {
+	importPaths := []string{}
+	for _, v := range e.Config.ImportPaths {
+		importPaths = append(importPaths, e.filePath(v))
+	}
+	if len(importPaths) == 0 {
+		importPaths = append(importPaths, e.filePath("."))
+	}
+	files := []string{}
+	for _, v := range e.Config.ProtoFiles {
+		files = append(files, e.filePath(v))
+	}
+	files, err := protoparse.ResolveFilenames(importPaths, files...)
+	if err != nil {
+		return nil, fmt.Errorf("resolve proto files: %w", err)
+	}
+	fds, err := e.descriptors.get(importPaths, files)
+	if err != nil {
+		return nil, err
+	}
+	for _, fd := range fds {
+		if service := fd.FindService(serviceName); service != nil {
+			return service, nil
+		}
+	}
+
+	return nil, fmt.Errorf("service %s not found in proto files", serviceName)
+}
+
+// filePath makes path relative to the test file
+func (e *GRPC) filePath(path string) string {
+	path = e.Vars.Apply(path)
+	if filepath.IsAbs(path) || e.fileName == "" {
+		return path
+	}
+
+	return filepath.Join(filepath.Dir(e.fileName), path)
+}
@@ -0,0 +1,442 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dailymotion/allure-go"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/tools"
+	"github.com/ixpectus/declarate/variables"
+)
+
+const (
+	defaultTimeout     = 10 * time.Second
+	defaultMaxMessages = 100
+)
+
+type GRPC struct {
+	Config         *Config
+	Vars           contract.Vars
+	Report         contract.ReportAttachement
+	comparer       contract.Comparer
+	conns          *conns
+	descriptors    *descriptors
+	defaultAddress string
+	fileName       string
+	responseBody   *string
+	status         *status.Status
+	trailer        metadata.MD
+	repro          string
+}
+
+type Unmarshaller struct {
+	defaultAddress string
+	comparer       contract.Comparer
+	conns          *conns
+	descriptors    *descriptors
+}
+
+func NewUnmarshaller(defaultAddress string, comparer contract.Comparer) *Unmarshaller {
+	return &Unmarshaller{
+		defaultAddress: defaultAddress,
+		comparer:       comparer,
+		conns:          newConns(),
+		descriptors:    newDescriptors(),
+	}
+}
+
+// Stop closes connections shared between steps
+func (u *Unmarshaller) Stop() error {
+	return u.conns.closeAll()
+}
+
+type Check struct {
+	GRPC *Config `yaml:"grpc,omitempty"`
+}
+
+type Config struct {
+	// Address is server address, default address is used when empty
+	Address string `json:"address" yaml:"address"`
+	// Method is full method name, `package.Service/Method`
+	Method string `json:"method" yaml:"method"`
+	// ProtoFiles are paths to proto files relative to the test file, server
+	// reflection is used when empty
+	ProtoFiles  []string          `json:"protoFiles" yaml:"protoFiles"`
+	ImportPaths []string          `json:"importPaths" yaml:"importPaths"`
+	Metadata    map[string]string `json:"metadata" yaml:"metadata"`
+	Request     string            `json:"request" yaml:"request"`
+	TLS         bool              `json:"tls" yaml:"tls"`
+	// InsecureSkipVerify skips server certificate verification with tls
+	InsecureSkipVerify bool          `json:"insecureSkipVerify" yaml:"insecureSkipVerify"`
+	Timeout            time.Duration `json:"timeout" yaml:"timeout"`
+	// MaxMessages limits messages read from server stream
+	MaxMessages int `json:"maxMessages" yaml:"maxMessages"`
+	// Response is expected message, array of messages for server stream
+	Response *string `json:"response" yaml:"response"`
+	// Status is expected status code name or number, OK by default
+	Status           string                 `json:"status" yaml:"status"`
+	StatusMessage    string                 `json:"statusMessage" yaml:"statusMessage"`
+	Trailers         map[string]string      `json:"trailers" yaml:"trailers"`
+	ComparisonParams contract.CompareParams `json:"comparisonParams" yaml:"comparisonParams"`
+}
+
+func (u *Unmarshaller) Build(unmarshal func(any) error) (contract.Doer, error) {
+	cfg := &Check{}
+	if err := unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("unmarshall grpc: %w", err)
+	}
+	if cfg.GRPC == nil {
+		return nil, nil
+	}
+
+	return &GRPC{
+		Config:         cfg.GRPC,
+		comparer:       u.comparer,
+		conns:          u.conns,
+		descriptors:    u.descriptors,
+		defaultAddress: u.defaultAddress,
+	}, nil
+}
+
+func (e *GRPC) SetVars(vv contract.Vars) {
+	e.Vars = vv
+}
+
+func (e *GRPC) SetReport(r contract.ReportAttachement) {
+	e.Report = r
+}
+
+func (e *GRPC) SetFileName(fileName string) {
+	e.fileName = fileName
+}
+
+func (e *GRPC) GetConfig() any {
+	return e.Config
+}
+
+func (e *GRPC) IsValid() error {
+	if e.Config.Method == "" {
+		return fmt.Errorf("grpc method is empty")
+	}
+	if _, _, err := splitMethod(e.Config.Method); err != nil {
+		return err
+	}
+	if e.Config.Address == "" && e.defaultAddress == "" {
+		return fmt.Errorf("grpc address is empty and default address is not set")
+	}
+	if e.Config.Response != nil {
+		c := variables.VariableRx.ReplaceAllString(*e.Config.Response, "2")
+		if !json.Valid([]byte(c)) {
+			return fmt.Errorf("cannot parse grpc response: `%v`", *e.Config.Response)
+		}
+	}
+	if e.Config.Status != "" {
+		if _, err := parseCode(e.Config.Status); err != nil && !variables.VariableRx.MatchString(e.Config.Status) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *GRPC) address() string {
+	if e.Config.Address != "" {
+		return e.Vars.Apply(e.Config.Address)
+	}
+	return e.defaultAddress
+}
+
+func (e *GRPC) Do() error {
+	e.responseBody = nil
+	e.status = nil
+	e.trailer = nil
+	timeout := e.Config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	address := e.address()
+	conn, err := e.conns.get(address, e.Config.TLS, e.Config.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	method, err := e.methodDescriptor(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if method.IsStreamingClient() {
+		return fmt.Errorf("method %s is client streaming, only unary and server streaming methods are supported", method.FullName())
+	}
+	request := dynamicpb.NewMessage(method.Input())
+	body := strings.TrimSpace(e.Vars.Apply(e.Config.Request))
+	if body != "" {
+		if err := protojson.Unmarshal([]byte(body), request); err != nil {
+			return fmt.Errorf("unmarshall grpc request to %s: %w", method.Input().FullName(), err)
+		}
+	}
+	md := metadata.MD{}
+	for k, v := range e.Config.Metadata {
+		md.Append(e.Vars.Apply(k), e.Vars.Apply(v))
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	fullMethod := fmt.Sprintf("/%s/%s", method.Parent().FullName(), method.Name())
+	e.repro = repro(address, e.Config, fullMethod, body, md)
+	if e.Report != nil {
+		e.Report.AddAttachment("request", allure.TextPlain, []byte(e.repro))
+	}
+
+	var (
+		messages []string
+		callErr  error
+	)
+	if method.IsStreamingServer() {
+		messages, e.trailer, callErr = e.stream(ctx, conn, method, fullMethod, request)
+	} else {
+		response := dynamicpb.NewMessage(method.Output())
+		callErr = conn.Invoke(ctx, fullMethod, request, response, gogrpc.Trailer(&e.trailer))
+		if callErr == nil {
+			messages = append(messages, marshal(response))
+		}
+	}
+	e.status = status.Convert(callErr)
+	res := "null"
+	switch {
+	case method.IsStreamingServer():
+		res = "[" + strings.Join(messages, ",") + "]"
+	case len(messages) > 0:
+		res = messages[0]
+	}
+	e.responseBody = &res
+	if e.Report != nil {
+		e.Report.AddAttachment("response", allure.ApplicationJson, []byte(tools.JSONPrettyPrint(res)))
+		meta := map[string]string{
+			"status": e.status.Code().String(),
+		}
+		if e.status.Message() != "" {
+			meta["message"] = e.status.Message()
+		}
+		for k, v := range e.trailer {
+			meta["trailer "+k] = strings.Join(v, ", ")
+		}
+		e.Report.AddAttachment("status", allure.TextPlain, []byte(tools.FormatVariables(meta)))
+	}
+
+	return nil
+}
+
+// stream reads server stream until it ends, max messages are read or timeout
+// is exceeded, in the latter cases stream is cancelled and status is OK, so
+// received messages are compared
+func (e *GRPC) stream(
+	ctx context.Context,
+	conn *gogrpc.ClientConn,
+	method protoreflect.MethodDescriptor,
+	fullMethod string,
+	request *dynamicpb.Message,
+) ([]string, metadata.MD, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	maxMessages := e.Config.MaxMessages
+	if maxMessages <= 0 {
+		maxMessages = defaultMaxMessages
+	}
+	stream, err := conn.NewStream(ctx, &gogrpc.StreamDesc{ServerStreams: true}, fullMethod)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := stream.SendMsg(request); err != nil {
+		return nil, nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, nil, err
+	}
+	messages := []string{}
+	for len(messages) < maxMessages {
+		response := dynamicpb.NewMessage(method.Output())
+		err := stream.RecvMsg(response)
+		if errors.Is(err, io.EOF) {
+			return messages, stream.Trailer(), nil
+		}
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return messages, stream.Trailer(), nil
+		}
+		if err != nil {
+			return messages, stream.Trailer(), err
+		}
+		messages = append(messages, marshal(response))
+	}
+
+	return messages, stream.Trailer(), nil
+}
+
+// marshal renders message as protojson, zero values are kept, so they can
+// be compared
+func marshal(m *dynamicpb.Message) string {
+	b, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(m)
+	if err != nil {
+		return "null"
+	}
+	return string(b)
+}
+
+func (e *GRPC) Repro() string {
+	return e.repro
+}
+
+// repro renders grpcurl command
+func repro(address string, cfg *Config, fullMethod, body string, md metadata.MD) string {
+	args := []string{"grpcurl"}
+	if !cfg.TLS {
+		args = append(args, "-plaintext")
+	} else if cfg.InsecureSkipVerify {
+		args = append(args, "-insecure")
+	}
+	for _, v := range cfg.ImportPaths {
+		args = append(args, "-import-path", tools.ShellQuote(v))
+	}
+	for _, v := range cfg.ProtoFiles {
+		args = append(args, "-proto", tools.ShellQuote(v))
+	}
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range md[k] {
+			args = append(args, "-H", tools.ShellQuote(k+": "+v))
+		}
+	}
+	if body != "" {
+		args = append(args, "-d", tools.ShellQuote(body))
+	}
+	args = append(args, tools.ShellQuote(address), tools.ShellQuote(strings.TrimPrefix(fullMethod, "/")))
+
+	return strings.Join(args, " ")
+}
+
+func (e *GRPC) ResponseBody() *string {
+	return e.responseBody
+}
+
+func (e *GRPC) Check() error {
+	// status of successful call is nil, so response shows the call is done
+	if e.responseBody == nil {
+		return nil
+	}
+	if err := e.checkStatus(); err != nil {
+		return err
+	}
+	if err := e.checkTrailers(); err != nil {
+		return err
+	}
+	if e.Config.Response == nil || e.responseBody == nil {
+		return nil
+	}
+	expected := strings.TrimSuffix(e.Vars.Apply(*e.Config.Response), "\n")
+
+	return tools.CompareJSON(e.comparer, "grpc response differs", expected, *e.responseBody, e.Config.ComparisonParams)
+}
+
+func (e *GRPC) checkStatus() error {
+	expected := codes.OK
+	if e.Config.Status != "" {
+		code, err := parseCode(e.Vars.Apply(e.Config.Status))
+		if err != nil {
+			return err
+		}
+		expected = code
+	}
+	got := e.status.Code()
+	if got != expected {
+		return &contract.TestError{
+			Title:         "grpc status differs",
+			Expected:      expected.String(),
+			Actual:        got.String() + ": " + e.status.Message(),
+			Message:       fmt.Sprintf("status differs, expected %s, got %s", expected, got),
+			OriginalError: fmt.Errorf("grpc status differs, expected %s, got %s: %s", expected, got, e.status.Message()),
+		}
+	}
+	if e.Config.StatusMessage == "" {
+		return nil
+	}
+	expectedMessage := e.Vars.Apply(e.Config.StatusMessage)
+	if errs := e.comparer.Compare(expectedMessage, e.status.Message(), e.Config.ComparisonParams); len(errs) > 0 {
+		return tools.Differs("grpc status message differs", expectedMessage, e.status.Message(), errs)
+	}
+
+	return nil
+}
+
+func (e *GRPC) checkTrailers() error {
+	if len(e.Config.Trailers) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(e.Config.Trailers))
+	for k := range e.Config.Trailers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var errs, expectedValues, actualValues []string
+	for _, k := range keys {
+		expected := e.Vars.Apply(e.Config.Trailers[k])
+		values := e.trailer.Get(k)
+		got := strings.Join(values, ", ")
+		expectedValues = append(expectedValues, k+": "+expected)
+		actualValues = append(actualValues, k+": "+got)
+		if len(values) == 0 {
+			errs = append(errs, fmt.Sprintf("trailer %s not found", k))
+			continue
+		}
+		for _, err := range e.comparer.Compare(expected, got, e.Config.ComparisonParams) {
+			errs = append(errs, fmt.Sprintf("%s: %s", k, err.Error()))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	msg := strings.Join(errs, "\n")
+
+	return &contract.TestError{
+		Title:         "grpc trailers differ",
+		Expected:      strings.Join(expectedValues, "\n"),
+		Actual:        strings.Join(actualValues, "\n"),
+		Message:       msg,
+		OriginalError: fmt.Errorf("grpc trailers differ: %v", msg),
+	}
+}
+
+// parseCode parses status code by number or by name, names are case
+// insensitive and may be written with underscores, `NOT_FOUND` or `NotFound`
+func parseCode(s string) (codes.Code, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return codes.Code(n), nil
+	}
+	normalize := func(s string) string {
+		return strings.ToLower(strings.ReplaceAll(s, "_", ""))
+	}
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if normalize(c.String()) == normalize(s) {
+			return c, nil
+		}
+	}
+
+	return codes.Unknown, fmt.Errorf("unknown grpc status `%s`", s)
+}
@@ -0,0 +1,253 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+// healthServer runs health service with reflection, unary calls get
+// trailer with requested service name
+func healthServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := gogrpc.NewServer(gogrpc.UnaryInterceptor(func(
+		ctx context.Context,
+		req any,
+		info *gogrpc.UnaryServerInfo,
+		handler gogrpc.UnaryHandler,
+	) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		gogrpc.SetTrailer(ctx, metadata.Pairs("x-request-id", strings.Join(md.Get("x-request-id"), ",")))
+		return handler(ctx, req)
+	}))
+	h := health.NewServer()
+	h.SetServingStatus("pets", grpc_health_v1.HealthCheckResponse_SERVING)
+	grpc_health_v1.RegisterHealthServer(srv, h)
+	reflection.Register(srv)
+	go srv.Serve(l)
+	t.Cleanup(srv.Stop)
+
+	return l.Addr().String()
+}
+
+func TestGRPC(t *testing.T) {
+	vv := testutil.Vars{"service": "pets", "id": "42"}
+	cmp := compare.New(contract.CompareParams{}, vv)
+	u := NewUnmarshaller(healthServer(t), cmp)
+
+	tests := []struct {
+		name     string
+		step     string
+		wantErr  bool
+		response string
+	}{
+		{
+			name: "reflection",
+			step: `
+grpc:
+  method: grpc.health.v1.Health/Check
+  request: '{"service": "{{$service}}"}'
+  response: '{"status": "SERVING"}'
+`,
+			response: `{"status":"SERVING"}`,
+		},
+		{
+			name: "proto file",
+			step: `
+grpc:
+  method: grpc.health.v1.Health.Check
+  protoFiles: [health.proto]
+  request: '{"service": "{{$service}}"}'
+  response: '{"status": "SERVING"}'
+`,
+			response: `{"status":"SERVING"}`,
+		},
+		{
+			name: "status and trailers",
+			step: `
+grpc:
+  method: grpc.health.v1.Health/Check
+  metadata:
+    x-request-id: "{{$id}}"
+  request: '{"service": "unknown"}'
+  status: NOT_FOUND
+  statusMessage: $matchRegexp(unknown service)
+  trailers:
+    x-request-id: "42"
+`,
+			response: `null`,
+		},
+		{
+			name: "response differs",
+			step: `
+grpc:
+  method: grpc.health.v1.Health/Check
+  request: '{"service": "{{$service}}"}'
+  response: '{"status": "NOT_SERVING"}'
+`,
+			wantErr: true,
+		},
+		{
+			name: "status differs",
+			step: `
+grpc:
+  method: grpc.health.v1.Health/Check
+  request: '{"service": "unknown"}'
+`,
+			wantErr: true,
+		},
+		{
+			name: "server stream",
+			step: `
+grpc:
+  method: grpc.health.v1.Health/Watch
+  protoFiles: [health.proto]
+  request: '{"service": "pets"}'
+  maxMessages: 1
+  timeout: 1s
+  response: '[{"status": "SERVING"}]'
+`,
+			response: `[{"status":"SERVING"}]`,
+		},
+		{
+			name: "server stream timeout",
+			step: `
+grpc:
+  method: grpc.health.v1.Health/Watch
+  request: '{"service": "pets"}'
+  maxMessages: 2
+  timeout: 100ms
+  response: '[{"status": "SERVING"}]'
+`,
+			response: `[{"status":"SERVING"}]`,
+		},
+		{
+			name: "server stream timeout differs",
+			step: `
+grpc:
+  method: grpc.health.v1.Health/Watch
+  request: '{"service": "pets"}'
+  timeout: 100ms
+  response: '[{"status": "SERVING"}, {"status": "NOT_SERVING"}]'
+`,
+			wantErr:  true,
+			response: `[{"status":"SERVING"}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testutil.Build[*GRPC](t, u, tt.step, vv)
+			if err := g.IsValid(); err != nil {
+				t.Fatalf("IsValid() error = %v", err)
+			}
+			if err := g.Do(); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			err := g.Check()
+			var testErr *contract.TestError
+			if tt.wantErr && !errors.As(err, &testErr) {
+				t.Errorf("Check() error = %v, want test error", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Check() error = %v", err)
+			}
+			if tt.response != "" {
+				got := strings.ReplaceAll(*g.ResponseBody(), " ", "")
+				if got != tt.response {
+					t.Errorf("ResponseBody() = %s, want %s", got, tt.response)
+				}
+			}
+		})
+	}
+}
+
+func TestGRPC_Repro(t *testing.T) {
+	u := NewUnmarshaller(healthServer(t), nil)
+	g := testutil.Build[*GRPC](t, u, `
+grpc:
+  method: grpc.health.v1.Health/Check
+  protoFiles: [health.proto]
+  metadata:
+    authorization: Bearer {{$token}}
+  request: '{"service": "pets"}'
+`, testutil.Vars{"token": "abc"})
+	if err := g.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	want := `grpcurl -plaintext -proto 'health.proto' -H 'authorization: Bearer abc' -d '{"service": "pets"}'`
+	if !strings.HasPrefix(g.Repro(), want) || !strings.HasSuffix(g.Repro(), `'grpc.health.v1.Health/Check'`) {
+		t.Errorf("Repro() = %s", g.Repro())
+	}
+}
+
+func TestGRPC_DescriptorsCache(t *testing.T) {
+	u := NewUnmarshaller(healthServer(t), nil)
+	step := `
+grpc:
+  method: grpc.health.v1.Health/Check
+  protoFiles: [health.proto]
+  request: '{"service": "pets"}'
+`
+	testutil.Run[*GRPC](t, u, step, testutil.Vars{})
+	if len(u.descriptors.files) != 1 {
+		t.Fatalf("expected one parsed file set, got %d", len(u.descriptors.files))
+	}
+	var parsed *desc.FileDescriptor
+	for _, fds := range u.descriptors.files {
+		parsed = fds[0]
+	}
+	testutil.Run[*GRPC](t, u, step, testutil.Vars{})
+	for _, fds := range u.descriptors.files {
+		if fds[0] != parsed {
+			t.Errorf("proto files are parsed again")
+		}
+	}
+}
+
+func TestUnmarshaller_Stop(t *testing.T) {
+	u := NewUnmarshaller(healthServer(t), nil)
+	testutil.Run[*GRPC](t, u, "grpc:\n  method: grpc.health.v1.Health/Check\n  request: '{}'\n", testutil.Vars{})
+	opened := []*gogrpc.ClientConn{}
+	for _, v := range u.conns.conns {
+		opened = append(opened, v)
+	}
+	if len(opened) != 1 {
+		t.Fatalf("expected one connection, got %d", len(opened))
+	}
+	if err := u.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if len(u.conns.conns) != 0 || opened[0].GetState() != connectivity.Shutdown {
+		t.Errorf("connection is not closed, state %v", opened[0].GetState())
+	}
+}
+
+func TestParseCode(t *testing.T) {
+	for _, v := range []string{"NOT_FOUND", "NotFound", "not_found", "5"} {
+		code, err := parseCode(v)
+		if err != nil || code.String() != "NotFound" {
+			t.Errorf("parseCode(%s) = %v, %v", v, code, err)
+		}
+	}
+	if _, err := parseCode("missing"); err == nil {
+		t.Errorf("expected error for unknown status")
+	}
+}
@@ -0,0 +1,162 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+
+	"github.com/ixpectus/declarate/internal/collector"
+)
+
+// consume starts consumer, with fromNow end offsets are resolved before
+// return, so messages produced by the next steps are not missed
+func (e *Kafka) consume() (*collector.Collector, error) {
+	cfg := e.Config.Consume
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	topic := e.Vars.Apply(e.Config.Topic)
+	brokers := e.brokers()
+	opts := []kgo.Opt{kgo.SeedBrokers(brokers...)}
+	if cfg.FromNow {
+		offsets, err := endOffsets(brokers, topic)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{topic: offsets}))
+	} else {
+		opts = append(opts, kgo.ConsumeTopics(topic), kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+	}
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("make kafka consumer: %w", err)
+	}
+	stop := collector.Config{
+		Count:    cfg.Count,
+		Timeout:  timeout,
+		Comparer: e.comparer,
+		Params:   e.Config.ComparisonParams,
+	}
+	if cfg.Until != nil {
+		stop.Until = strings.TrimSuffix(e.Vars.Apply(*cfg.Until), "\n")
+	}
+	c := collector.New(stop)
+	e.readers.start(func(ctx context.Context) {
+		consumeRecords(ctx, client, c, timeout)
+	})
+
+	return c, nil
+}
+
+// readers tracks running consumers, so background ones are stopped after
+// suite run instead of reading until their timeout
+type readers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newReaders() *readers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &readers{ctx: ctx, cancel: cancel}
+}
+
+func (r *readers) start(read func(ctx context.Context)) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		read(r.ctx)
+	}()
+}
+
+// closeAll stops consumers and waits for their clients to close
+func (r *readers) closeAll() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// consumeRecords reads topic until stop condition, timeout or stop of
+// parent context
+func consumeRecords(parent context.Context, client *kgo.Client, c *collector.Collector, timeout time.Duration) {
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	for {
+		fetches := client.PollFetches(ctx)
+		if ctx.Err() != nil {
+			c.Close(nil, true)
+			return
+		}
+		for _, v := range fetches.Errors() {
+			c.Close(fmt.Errorf("consume %s: %w", v.Topic, v.Err), false)
+			return
+		}
+		iter := fetches.RecordIter()
+		for !iter.Done() {
+			if c.Add(render(iter.Next())) {
+				c.Close(nil, false)
+				return
+			}
+		}
+	}
+}
+
+// endOffsets returns current end offsets of all topic partitions
+func endOffsets(brokers []string, topic string) (map[int32]kgo.Offset, error) {
+	client, err := kgo.NewClient(kgo.SeedBrokers(brokers...))
+	if err != nil {
+		return nil, fmt.Errorf("make kafka client: %w", err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	metaReq := kmsg.NewPtrMetadataRequest()
+	metaTopic := kmsg.NewMetadataRequestTopic()
+	metaTopic.Topic = kmsg.StringPtr(topic)
+	metaReq.Topics = append(metaReq.Topics, metaTopic)
+	meta, err := metaReq.RequestWith(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("request %s metadata: %w", topic, err)
+	}
+	if len(meta.Topics) != 1 {
+		return nil, fmt.Errorf("topic %s not found", topic)
+	}
+	if err := kerr.ErrorForCode(meta.Topics[0].ErrorCode); err != nil {
+		return nil, fmt.Errorf("request %s metadata: %w", topic, err)
+	}
+
+	listReq := kmsg.NewPtrListOffsetsRequest()
+	listTopic := kmsg.NewListOffsetsRequestTopic()
+	listTopic.Topic = topic
+	for _, p := range meta.Topics[0].Partitions {
+		partition := kmsg.NewListOffsetsRequestTopicPartition()
+		partition.Partition = p.Partition
+		// -1 is the latest offset
+		partition.Timestamp = -1
+		listTopic.Partitions = append(listTopic.Partitions, partition)
+	}
+	listReq.Topics = append(listReq.Topics, listTopic)
+	list, err := listReq.RequestWith(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("list %s offsets: %w", topic, err)
+	}
+	res := map[int32]kgo.Offset{}
+	for _, t := range list.Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return nil, fmt.Errorf("list %s offsets: %w", topic, err)
+			}
+			res[p.Partition] = kgo.NewOffset().At(p.Offset)
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,355 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dailymotion/allure-go"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/internal/collector"
+	"github.com/ixpectus/declarate/tools"
+	"github.com/ixpectus/declarate/variables"
+)
+
+const defaultTimeout = 10 * time.Second
+
+type Kafka struct {
+	Config         *Config
+	Vars           contract.Vars
+	Report         contract.ReportAttachement
+	comparer       contract.Comparer
+	producers      *producers
+	consumers      *collector.Registry
+	readers        *readers
+	defaultBrokers []string
+	responseBody   *string
+	// failure is set when messages are not consumed in time
+	failure *contract.TestError
+	// waited is background consumer, it is removed after successful check
+	waited *collector.Collector
+}
+
+type Unmarshaller struct {
+	brokers   []string
+	comparer  contract.Comparer
+	producers *producers
+	consumers *collector.Registry
+	readers   *readers
+}
+
+func NewUnmarshaller(brokers []string, comparer contract.Comparer) *Unmarshaller {
+	return &Unmarshaller{
+		brokers:   brokers,
+		comparer:  comparer,
+		producers: &producers{clients: map[string]*kgo.Client{}},
+		consumers: collector.NewRegistry(),
+		readers:   newReaders(),
+	}
+}
+
+type Check struct {
+	Kafka *Config `yaml:"kafka,omitempty"`
+}
+
+type Config struct {
+	// Brokers are seed brokers, default brokers are used when empty
+	Brokers []string `json:"brokers" yaml:"brokers"`
+	Topic   string   `json:"topic" yaml:"topic"`
+	// Name identifies background consumer, step with the name and without
+	// produce and consume waits for it
+	Name             string                 `json:"name" yaml:"name"`
+	Produce          []Message              `json:"produce" yaml:"produce"`
+	Consume          *ConsumeConfig         `json:"consume" yaml:"consume"`
+	Response         *string                `json:"response" yaml:"response"`
+	ComparisonParams contract.CompareParams `json:"comparisonParams" yaml:"comparisonParams"`
+}
+
+type Message struct {
+	Key     string            `json:"key" yaml:"key"`
+	Headers map[string]string `json:"headers" yaml:"headers"`
+	Value   string            `json:"value" yaml:"value"`
+}
+
+type ConsumeConfig struct {
+	// Until stops consuming after message matching it
+	Until *string `json:"until" yaml:"until"`
+	// Count stops consuming after count messages
+	Count int `json:"count" yaml:"count"`
+	// Timeout stops consuming, it is a failure when until or count are set
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+	// FromNow skips messages produced before consumer start
+	FromNow    bool `json:"fromNow" yaml:"fromNow"`
+	Background bool `json:"background" yaml:"background"`
+}
+
+// Stop closes producers shared between steps and stops consumers
+func (u *Unmarshaller) Stop() error {
+	u.producers.closeAll()
+	u.readers.closeAll()
+	return nil
+}
+
+func (u *Unmarshaller) Build(unmarshal func(any) error) (contract.Doer, error) {
+	cfg := &Check{}
+	if err := unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("unmarshall kafka: %w", err)
+	}
+	if cfg.Kafka == nil {
+		return nil, nil
+	}
+
+	return &Kafka{
+		Config:         cfg.Kafka,
+		comparer:       u.comparer,
+		producers:      u.producers,
+		consumers:      u.consumers,
+		readers:        u.readers,
+		defaultBrokers: u.brokers,
+	}, nil
+}
+
+func (e *Kafka) SetVars(vv contract.Vars) {
+	e.Vars = vv
+}
+
+func (e *Kafka) SetReport(r contract.ReportAttachement) {
+	e.Report = r
+}
+
+func (e *Kafka) GetConfig() any {
+	return e.Config
+}
+
+func (e *Kafka) IsValid() error {
+	switch {
+	case len(e.Config.Produce) > 0 && e.Config.Consume != nil:
+		return fmt.Errorf("impossible to fill produce and consume simultaneously, choose one of")
+	case len(e.Config.Produce) == 0 && e.Config.Consume == nil && e.Config.Name == "":
+		return fmt.Errorf("kafka step requires produce, consume or name of background consumer")
+	case (len(e.Config.Produce) > 0 || e.Config.Consume != nil) && e.Config.Topic == "":
+		return fmt.Errorf("kafka topic is empty")
+	case len(e.Config.Brokers) == 0 && len(e.defaultBrokers) == 0:
+		return fmt.Errorf("kafka brokers are empty and default brokers are not set")
+	}
+	if c := e.Config.Consume; c != nil && c.Background {
+		if e.Config.Name == "" {
+			return fmt.Errorf("background kafka consumer requires name")
+		}
+		if e.Config.Response != nil {
+			return fmt.Errorf("background kafka consumer has no response, check it in the step waiting for %s", e.Config.Name)
+		}
+	}
+	checks := []*string{e.Config.Response}
+	if e.Config.Consume != nil {
+		checks = append(checks, e.Config.Consume.Until)
+	}
+	for _, v := range checks {
+		if v == nil {
+			continue
+		}
+		c := variables.VariableRx.ReplaceAllString(*v, "2")
+		if !json.Valid([]byte(c)) {
+			return fmt.Errorf("cannot parse kafka message: `%v`", *v)
+		}
+	}
+
+	return nil
+}
+
+func (e *Kafka) brokers() []string {
+	brokers := e.defaultBrokers
+	if len(e.Config.Brokers) > 0 {
+		brokers = []string{}
+		for _, v := range e.Config.Brokers {
+			brokers = append(brokers, e.Vars.Apply(v))
+		}
+	}
+
+	return brokers
+}
+
+func (e *Kafka) Do() error {
+	e.responseBody = nil
+	e.failure = nil
+	e.waited = nil
+	switch {
+	case len(e.Config.Produce) > 0:
+		return e.produce()
+	case e.Config.Consume != nil:
+		c, err := e.consume()
+		if err != nil {
+			return err
+		}
+		if e.Config.Consume.Background {
+			e.consumers.Set(e.Config.Name, c)
+			return nil
+		}
+		return e.wait(c)
+	}
+	c := e.consumers.Get(e.Config.Name)
+	if c == nil {
+		return fmt.Errorf("kafka consumer %s is not started", e.Config.Name)
+	}
+	e.waited = c
+
+	return e.wait(c)
+}
+
+func (e *Kafka) produce() error {
+	client, err := e.producers.get(e.brokers())
+	if err != nil {
+		return err
+	}
+	topic := e.Vars.Apply(e.Config.Topic)
+	records := []*kgo.Record{}
+	for _, v := range e.Config.Produce {
+		r := &kgo.Record{
+			Topic: topic,
+			Value: []byte(strings.TrimSuffix(e.Vars.Apply(v.Value), "\n")),
+		}
+		if v.Key != "" {
+			r.Key = []byte(e.Vars.Apply(v.Key))
+		}
+		keys := make([]string, 0, len(v.Headers))
+		for k := range v.Headers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			r.Headers = append(r.Headers, kgo.RecordHeader{
+				Key:   e.Vars.Apply(k),
+				Value: []byte(e.Vars.Apply(v.Headers[k])),
+			})
+		}
+		records = append(records, r)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	if err := client.ProduceSync(ctx, records...).FirstErr(); err != nil {
+		return fmt.Errorf("produce to %s: %w", topic, err)
+	}
+	produced := []string{}
+	for _, r := range records {
+		produced = append(produced, render(r))
+	}
+	res := "[" + strings.Join(produced, ",") + "]"
+	e.responseBody = &res
+	if e.Report != nil {
+		e.Report.AddAttachment("produced", allure.ApplicationJson, []byte(tools.JSONPrettyPrint(res)))
+	}
+
+	return nil
+}
+
+func (e *Kafka) wait(c *collector.Collector) error {
+	res, failure, err := c.Wait("kafka messages not consumed", "messages")
+	if err != nil {
+		return err
+	}
+	e.responseBody = &res
+	if e.Report != nil {
+		e.Report.AddAttachment("consumed", allure.ApplicationJson, []byte(tools.JSONPrettyPrint(res)))
+	}
+	e.failure = failure
+
+	return nil
+}
+
+// ResponseBody returns produced records with offsets or consumed messages
+func (e *Kafka) ResponseBody() *string {
+	return e.responseBody
+}
+
+func (e *Kafka) Check() error {
+	if err := e.check(); err != nil {
+		return err
+	}
+	// waited consumer is kept until check passes, so poll retries see it
+	if e.waited != nil {
+		e.consumers.Remove(e.Config.Name, e.waited)
+	}
+
+	return nil
+}
+
+func (e *Kafka) check() error {
+	if e.failure != nil {
+		return e.failure
+	}
+	if e.Config.Response == nil || e.responseBody == nil {
+		return nil
+	}
+	expected := strings.TrimSuffix(e.Vars.Apply(*e.Config.Response), "\n")
+
+	return tools.CompareJSON(e.comparer, "kafka messages differ", expected, *e.responseBody, e.Config.ComparisonParams)
+}
+
+// render renders record as json, json value is kept as is and other values
+// become json strings
+func render(r *kgo.Record) string {
+	headers := map[string]string{}
+	for _, h := range r.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	value := json.RawMessage(r.Value)
+	if !json.Valid(r.Value) {
+		value, _ = json.Marshal(string(r.Value))
+	}
+	b, _ := json.Marshal(struct {
+		Topic     string            `json:"topic"`
+		Partition int32             `json:"partition"`
+		Offset    int64             `json:"offset"`
+		Key       string            `json:"key"`
+		Headers   map[string]string `json:"headers"`
+		Value     json.RawMessage   `json:"value"`
+		Timestamp string            `json:"timestamp"`
+	}{
+		Topic:     r.Topic,
+		Partition: r.Partition,
+		Offset:    r.Offset,
+		Key:       string(r.Key),
+		Headers:   headers,
+		Value:     value,
+		Timestamp: r.Timestamp.Format(time.RFC3339Nano),
+	})
+
+	return string(b)
+}
+
+// producers stores producer clients by brokers
+type producers struct {
+	mu      sync.Mutex
+	clients map[string]*kgo.Client
+}
+
+func (p *producers) get(brokers []string) (*kgo.Client, error) {
+	key := strings.Join(brokers, ",")
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if client, ok := p.clients[key]; ok {
+		return client, nil
+	}
+	client, err := kgo.NewClient(kgo.SeedBrokers(brokers...))
+	if err != nil {
+		return nil, fmt.Errorf("make kafka producer: %w", err)
+	}
+	p.clients[key] = client
+
+	return client, nil
+}
+
+func (p *producers) closeAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, client := range p.clients {
+		client.Close()
+		delete(p.clients, key)
+	}
+}
@@ -0,0 +1,190 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+func newCluster(t *testing.T) *Unmarshaller {
+	t.Helper()
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(2, "orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cluster.Close)
+	vv := testutil.Vars{}
+
+	return NewUnmarshaller(cluster.ListenAddrs(), compare.New(contract.CompareParams{}, vv))
+}
+
+func TestKafka_ProduceConsume(t *testing.T) {
+	u := newCluster(t)
+	vv := testutil.Vars{"id": "42"}
+	produced := testutil.Run[*Kafka](t, u, `
+kafka:
+  topic: orders
+  produce:
+    - key: "{{$id}}"
+      headers:
+        type: created
+      value: '{"id": "{{$id}}", "status": "created"}'
+    - key: other
+      value: plain
+`, vv)
+	if err := produced.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	if !strings.Contains(*produced.ResponseBody(), `"offset":0`) {
+		t.Errorf("ResponseBody() = %s, want offsets", *produced.ResponseBody())
+	}
+
+	consumed := testutil.Run[*Kafka](t, u, `
+kafka:
+  topic: orders
+  consume:
+    until: '{"key": "{{$id}}"}'
+    timeout: 5s
+  response: |
+    [{"key": "{{$id}}", "headers": {"type": "created"}, "value": {"id": "{{$id}}", "status": "created"}}]
+  comparisonParams:
+    allowArrayExtraItems: true
+    ignoreArraysOrdering: true
+`, vv)
+	if err := consumed.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	count := testutil.Run[*Kafka](t, u, `
+kafka:
+  topic: orders
+  consume:
+    count: 2
+    timeout: 5s
+`, vv)
+	if err := count.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	if !strings.Contains(*count.ResponseBody(), `"value":"plain"`) {
+		t.Errorf("ResponseBody() = %s, want plain value as string", *count.ResponseBody())
+	}
+}
+
+func TestKafka_BackgroundFromNow(t *testing.T) {
+	u := newCluster(t)
+	vv := testutil.Vars{}
+	testutil.Run[*Kafka](t, u, `
+kafka:
+  topic: orders
+  produce:
+    - key: old
+      value: '{"n": 1}'
+`, vv)
+	start := testutil.Run[*Kafka](t, u, `
+kafka:
+  topic: orders
+  name: orders
+  consume:
+    fromNow: true
+    background: true
+    count: 1
+    timeout: 5s
+`, vv)
+	if start.ResponseBody() != nil {
+		t.Errorf("background step has response %s", *start.ResponseBody())
+	}
+	testutil.Run[*Kafka](t, u, `
+kafka:
+  topic: orders
+  produce:
+    - key: new
+      value: '{"n": 2}'
+`, vv)
+	wait := testutil.Run[*Kafka](t, u, `
+kafka:
+  name: orders
+  response: '[{"key": "new", "value": {"n": 2}}]'
+`, vv)
+	if err := wait.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+}
+
+func TestKafka_Timeout(t *testing.T) {
+	u := newCluster(t)
+	k := testutil.Run[*Kafka](t, u, `
+kafka:
+  topic: orders
+  consume:
+    until: '{"key": "missing"}'
+    timeout: 200ms
+`, testutil.Vars{})
+	var testErr *contract.TestError
+	if err := k.Check(); !errors.As(err, &testErr) {
+		t.Errorf("Check() error = %v, want test error", err)
+	}
+}
+
+func TestUnmarshaller_Stop(t *testing.T) {
+	u := newCluster(t)
+	testutil.Run[*Kafka](t, u, "kafka:\n  topic: orders\n  produce:\n    - value: plain\n", testutil.Vars{})
+	opened := []*kgo.Client{}
+	for _, v := range u.producers.clients {
+		opened = append(opened, v)
+	}
+	if len(opened) != 1 {
+		t.Fatalf("expected one producer, got %d", len(opened))
+	}
+	if err := u.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if len(u.producers.clients) != 0 {
+		t.Errorf("expected producer to be removed")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := opened[0].Ping(ctx); err == nil {
+		t.Errorf("expected closed producer")
+	}
+}
+
+func TestUnmarshaller_StopConsumers(t *testing.T) {
+	u := newCluster(t)
+	testutil.Run[*Kafka](t, u, `
+kafka:
+  topic: orders
+  name: orders
+  consume:
+    background: true
+    count: 1
+    timeout: 1m
+`, testutil.Vars{})
+	c := u.consumers.Get("orders")
+	if c == nil {
+		t.Fatalf("expected background consumer")
+	}
+	stopped := make(chan error)
+	go func() {
+		stopped <- u.Stop()
+	}()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("Stop() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Stop() does not stop background consumer")
+	}
+	if _, failure, err := c.Wait("kafka messages not consumed", "messages"); err != nil || failure == nil {
+		t.Errorf("Wait() = %v, %v, want failure of stopped consumer", failure, err)
+	}
+}
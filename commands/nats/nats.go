@@ -0,0 +1,408 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dailymotion/allure-go"
+	gonats "github.com/nats-io/nats.go"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/internal/collector"
+	"github.com/ixpectus/declarate/tools"
+	"github.com/ixpectus/declarate/variables"
+)
+
+const (
+	defaultTimeout        = 10 * time.Second
+	defaultRequestTimeout = 5 * time.Second
+)
+
+type NATS struct {
+	Config       *Config
+	Vars         contract.Vars
+	Report       contract.ReportAttachement
+	comparer     contract.Comparer
+	conns        *conns
+	subs         *collector.Registry
+	defaultURL   string
+	responseBody *string
+	// failure is set when messages are not received in time
+	failure *contract.TestError
+	// waited is background subscription, it is removed after successful check
+	waited *collector.Collector
+}
+
+type Unmarshaller struct {
+	url      string
+	comparer contract.Comparer
+	conns    *conns
+	subs     *collector.Registry
+}
+
+func NewUnmarshaller(url string, comparer contract.Comparer) *Unmarshaller {
+	return &Unmarshaller{
+		url:      url,
+		comparer: comparer,
+		conns:    &conns{conns: map[string]*gonats.Conn{}},
+		subs:     collector.NewRegistry(),
+	}
+}
+
+type Check struct {
+	NATS *Config `yaml:"nats,omitempty"`
+}
+
+type Config struct {
+	// URL is server url, default url is used when empty
+	URL     string `json:"url" yaml:"url"`
+	Subject string `json:"subject" yaml:"subject"`
+	// Name identifies background subscription, step with the name and
+	// without publish, request and subscribe waits for it
+	Name string `json:"name" yaml:"name"`
+	// JetStream publishes with stream acknowledgement and subscribes to
+	// messages stored in stream
+	JetStream        bool                   `json:"jetStream" yaml:"jetStream"`
+	Publish          []Message              `json:"publish" yaml:"publish"`
+	Request          *RequestConfig         `json:"request" yaml:"request"`
+	Subscribe        *SubscribeConfig       `json:"subscribe" yaml:"subscribe"`
+	Response         *string                `json:"response" yaml:"response"`
+	ComparisonParams contract.CompareParams `json:"comparisonParams" yaml:"comparisonParams"`
+}
+
+type Message struct {
+	Headers map[string]string `json:"headers" yaml:"headers"`
+	Data    string            `json:"data" yaml:"data"`
+}
+
+type RequestConfig struct {
+	Headers map[string]string `json:"headers" yaml:"headers"`
+	Data    string            `json:"data" yaml:"data"`
+	// Timeout of waiting for reply
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+type SubscribeConfig struct {
+	// Until stops receiving after message matching it
+	Until *string `json:"until" yaml:"until"`
+	// Count stops receiving after count messages
+	Count int `json:"count" yaml:"count"`
+	// Timeout stops receiving, it is a failure when until or count are set
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+	// FromNow skips messages stored in stream before subscription, it is
+	// used with jetStream, core subscription receives new messages only
+	FromNow    bool `json:"fromNow" yaml:"fromNow"`
+	Background bool `json:"background" yaml:"background"`
+}
+
+// Stop closes connections shared between steps
+func (u *Unmarshaller) Stop() error {
+	u.conns.closeAll()
+	return nil
+}
+
+func (u *Unmarshaller) Build(unmarshal func(any) error) (contract.Doer, error) {
+	cfg := &Check{}
+	if err := unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("unmarshall nats: %w", err)
+	}
+	if cfg.NATS == nil {
+		return nil, nil
+	}
+
+	return &NATS{
+		Config:     cfg.NATS,
+		comparer:   u.comparer,
+		conns:      u.conns,
+		subs:       u.subs,
+		defaultURL: u.url,
+	}, nil
+}
+
+func (e *NATS) SetVars(vv contract.Vars) {
+	e.Vars = vv
+}
+
+func (e *NATS) SetReport(r contract.ReportAttachement) {
+	e.Report = r
+}
+
+func (e *NATS) GetConfig() any {
+	return e.Config
+}
+
+func (e *NATS) modesCount() int {
+	res := 0
+	if len(e.Config.Publish) > 0 {
+		res++
+	}
+	if e.Config.Request != nil {
+		res++
+	}
+	if e.Config.Subscribe != nil {
+		res++
+	}
+
+	return res
+}
+
+func (e *NATS) IsValid() error {
+	modes := e.modesCount()
+	switch {
+	case modes > 1:
+		return fmt.Errorf("impossible to fill publish, request and subscribe simultaneously, choose one of")
+	case modes == 0 && e.Config.Name == "":
+		return fmt.Errorf("nats step requires publish, request, subscribe or name of background subscription")
+	case modes > 0 && e.Config.Subject == "":
+		return fmt.Errorf("nats subject is empty")
+	case e.Config.URL == "" && e.defaultURL == "":
+		return fmt.Errorf("nats url is empty and default url is not set")
+	case e.Config.JetStream && e.Config.Request != nil:
+		return fmt.Errorf("nats request is not supported with jetStream")
+	}
+	if s := e.Config.Subscribe; s != nil && s.Background {
+		if e.Config.Name == "" {
+			return fmt.Errorf("background nats subscription requires name")
+		}
+		if e.Config.Response != nil {
+			return fmt.Errorf("background nats subscription has no response, check it in the step waiting for %s", e.Config.Name)
+		}
+	}
+	checks := []*string{e.Config.Response}
+	if e.Config.Subscribe != nil {
+		checks = append(checks, e.Config.Subscribe.Until)
+	}
+	for _, v := range checks {
+		if v == nil {
+			continue
+		}
+		c := variables.VariableRx.ReplaceAllString(*v, "2")
+		if !json.Valid([]byte(c)) {
+			return fmt.Errorf("cannot parse nats message: `%v`", *v)
+		}
+	}
+
+	return nil
+}
+
+func (e *NATS) url() string {
+	if e.Config.URL != "" {
+		return e.Vars.Apply(e.Config.URL)
+	}
+	return e.defaultURL
+}
+
+func (e *NATS) Do() error {
+	e.responseBody = nil
+	e.failure = nil
+	e.waited = nil
+	if e.modesCount() == 0 {
+		c := e.subs.Get(e.Config.Name)
+		if c == nil {
+			return fmt.Errorf("nats subscription %s is not started", e.Config.Name)
+		}
+		e.waited = c
+		return e.wait(c)
+	}
+	nc, err := e.conns.get(e.url())
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(e.Config.Publish) > 0:
+		return e.publish(nc)
+	case e.Config.Request != nil:
+		return e.request(nc)
+	}
+	c, err := e.subscribe(nc)
+	if err != nil {
+		return err
+	}
+	if e.Config.Subscribe.Background {
+		e.subs.Set(e.Config.Name, c)
+		return nil
+	}
+
+	return e.wait(c)
+}
+
+func (e *NATS) message(subject string, headers map[string]string, data string) *gonats.Msg {
+	msg := gonats.NewMsg(subject)
+	msg.Data = []byte(strings.TrimSuffix(e.Vars.Apply(data), "\n"))
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		msg.Header.Set(e.Vars.Apply(k), e.Vars.Apply(headers[k]))
+	}
+
+	return msg
+}
+
+func (e *NATS) publish(nc *gonats.Conn) error {
+	subject := e.Vars.Apply(e.Config.Subject)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	var js gonats.JetStreamContext
+	if e.Config.JetStream {
+		var err error
+		js, err = nc.JetStream()
+		if err != nil {
+			return fmt.Errorf("make jetstream context: %w", err)
+		}
+	}
+	published := []string{}
+	for _, v := range e.Config.Publish {
+		msg := e.message(subject, v.Headers, v.Data)
+		if js == nil {
+			if err := nc.PublishMsg(msg); err != nil {
+				return fmt.Errorf("publish to %s: %w", subject, err)
+			}
+			published = append(published, render(msg, "", 0))
+			continue
+		}
+		ack, err := js.PublishMsg(msg, gonats.Context(ctx))
+		if err != nil {
+			return fmt.Errorf("publish to %s: %w", subject, err)
+		}
+		published = append(published, render(msg, ack.Stream, ack.Sequence))
+	}
+	if err := nc.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("flush nats connection: %w", err)
+	}
+	res := "[" + strings.Join(published, ",") + "]"
+	e.responseBody = &res
+	if e.Report != nil {
+		e.Report.AddAttachment("published", allure.ApplicationJson, []byte(tools.JSONPrettyPrint(res)))
+	}
+
+	return nil
+}
+
+func (e *NATS) request(nc *gonats.Conn) error {
+	cfg := e.Config.Request
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	msg := e.message(e.Vars.Apply(e.Config.Subject), cfg.Headers, cfg.Data)
+	reply, err := nc.RequestMsg(msg, timeout)
+	if err != nil {
+		return fmt.Errorf("request %s: %w", msg.Subject, err)
+	}
+	res := render(reply, "", 0)
+	e.responseBody = &res
+	if e.Report != nil {
+		e.Report.AddAttachment("reply", allure.ApplicationJson, []byte(tools.JSONPrettyPrint(res)))
+	}
+
+	return nil
+}
+
+func (e *NATS) wait(c *collector.Collector) error {
+	res, failure, err := c.Wait("nats messages not received", "messages")
+	if err != nil {
+		return err
+	}
+	e.responseBody = &res
+	if e.Report != nil {
+		e.Report.AddAttachment("received", allure.ApplicationJson, []byte(tools.JSONPrettyPrint(res)))
+	}
+	e.failure = failure
+
+	return nil
+}
+
+// ResponseBody returns published messages, reply or received messages
+func (e *NATS) ResponseBody() *string {
+	return e.responseBody
+}
+
+func (e *NATS) Check() error {
+	if err := e.check(); err != nil {
+		return err
+	}
+	// waited subscription is kept until check passes, so poll retries see it
+	if e.waited != nil {
+		e.subs.Remove(e.Config.Name, e.waited)
+	}
+
+	return nil
+}
+
+func (e *NATS) check() error {
+	if e.failure != nil {
+		return e.failure
+	}
+	if e.Config.Response == nil || e.responseBody == nil {
+		return nil
+	}
+	expected := strings.TrimSuffix(e.Vars.Apply(*e.Config.Response), "\n")
+
+	return tools.CompareJSON(e.comparer, "nats messages differ", expected, *e.responseBody, e.Config.ComparisonParams)
+}
+
+// render renders message as json, json data is kept as is and other data
+// becomes json string, stream and sequence are set for jetstream messages
+func render(msg *gonats.Msg, stream string, sequence uint64) string {
+	headers := map[string]string{}
+	for k := range msg.Header {
+		headers[k] = msg.Header.Get(k)
+	}
+	data := json.RawMessage(msg.Data)
+	if !json.Valid(msg.Data) {
+		data, _ = json.Marshal(string(msg.Data))
+	}
+	b, _ := json.Marshal(struct {
+		Subject  string            `json:"subject"`
+		Headers  map[string]string `json:"headers"`
+		Data     json.RawMessage   `json:"data"`
+		Stream   string            `json:"stream,omitempty"`
+		Sequence uint64            `json:"sequence,omitempty"`
+	}{
+		Subject:  msg.Subject,
+		Headers:  headers,
+		Data:     data,
+		Stream:   stream,
+		Sequence: sequence,
+	})
+
+	return string(b)
+}
+
+// conns stores connections by url
+type conns struct {
+	mu    sync.Mutex
+	conns map[string]*gonats.Conn
+}
+
+func (c *conns) get(url string) (*gonats.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if nc, ok := c.conns[url]; ok && !nc.IsClosed() {
+		return nc, nil
+	}
+	nc, err := gonats.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("connect nats %s: %w", url, err)
+	}
+	c.conns[url] = nc
+
+	return nc, nil
+}
+
+func (c *conns) closeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for url, nc := range c.conns {
+		nc.Close()
+		delete(c.conns, url)
+	}
+}
@@ -0,0 +1,224 @@
+package nats
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	gonats "github.com/nats-io/nats.go"
+	"gopkg.in/yaml.v2"
+
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+func newServer(t *testing.T) (*gonats.Conn, *Unmarshaller) {
+	t.Helper()
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server is not ready")
+	}
+	t.Cleanup(s.Shutdown)
+	nc, err := gonats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	vv := testutil.Vars{}
+
+	return nc, NewUnmarshaller(s.ClientURL(), compare.New(contract.CompareParams{}, vv))
+}
+
+func TestNATS_Request(t *testing.T) {
+	nc, u := newServer(t)
+	_, err := nc.Subscribe("orders.get", func(msg *gonats.Msg) {
+		reply := gonats.NewMsg(msg.Reply)
+		reply.Header.Set("trace", msg.Header.Get("trace"))
+		reply.Data = []byte(`{"id": ` + string(msg.Data) + `, "status": "paid"}`)
+		_ = msg.RespondMsg(reply)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vv := testutil.Vars{"id": "42"}
+	r := testutil.Run[*NATS](t, u, `
+nats:
+  subject: orders.get
+  request:
+    headers:
+      trace: abc
+    data: "{{$id}}"
+    timeout: 2s
+  response: '{"headers": {"trace": "abc"}, "data": {"id": {{$id}}, "status": "paid"}}'
+`, vv)
+	if err := r.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	noReply := testutil.Valid[*NATS](t, u, `
+nats:
+  subject: orders.missing
+  request:
+    timeout: 200ms
+`, vv)
+	if err := noReply.Do(); err == nil {
+		t.Errorf("Do() error = nil, want no responders error")
+	}
+}
+
+func TestNATS_BackgroundSubscribe(t *testing.T) {
+	_, u := newServer(t)
+	vv := testutil.Vars{"id": "42"}
+	start := testutil.Run[*NATS](t, u, `
+nats:
+  subject: orders.*
+  name: orders
+  subscribe:
+    background: true
+    until: '{"data": {"status": "paid"}}'
+    timeout: 5s
+`, vv)
+	if start.ResponseBody() != nil {
+		t.Errorf("background step has response %s", *start.ResponseBody())
+	}
+	testutil.Run[*NATS](t, u, `
+nats:
+  subject: orders.{{$id}}
+  publish:
+    - data: '{"id": {{$id}}, "status": "created"}'
+    - data: '{"id": {{$id}}, "status": "paid"}'
+`, vv)
+	wait := testutil.Run[*NATS](t, u, `
+nats:
+  name: orders
+  response: |
+    [
+      {"subject": "orders.{{$id}}", "data": {"status": "created"}},
+      {"subject": "orders.{{$id}}", "data": {"status": "paid"}}
+    ]
+`, vv)
+	if err := wait.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+}
+
+func TestNATS_JetStream(t *testing.T) {
+	nc, u := newServer(t)
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := js.AddStream(&gonats.StreamConfig{Name: "EVENTS", Subjects: []string{"events.>"}}); err != nil {
+		t.Fatal(err)
+	}
+	vv := testutil.Vars{}
+	published := testutil.Run[*NATS](t, u, `
+nats:
+  subject: events.created
+  jetStream: true
+  publish:
+    - data: '{"n": 1}'
+    - data: plain
+  response: '[{"stream": "EVENTS", "sequence": 1}, {"stream": "EVENTS", "sequence": 2}]'
+`, vv)
+	if err := published.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	all := testutil.Run[*NATS](t, u, `
+nats:
+  subject: events.>
+  jetStream: true
+  subscribe:
+    count: 2
+    timeout: 5s
+  response: '[{"data": {"n": 1}, "sequence": 1}, {"data": "plain", "sequence": 2}]'
+`, vv)
+	if err := all.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	fromNow := testutil.Run[*NATS](t, u, `
+nats:
+  subject: events.>
+  jetStream: true
+  subscribe:
+    fromNow: true
+    count: 1
+    timeout: 200ms
+`, vv)
+	var testErr *contract.TestError
+	if err := fromNow.Check(); !errors.As(err, &testErr) {
+		t.Errorf("Check() error = %v, want test error", err)
+	}
+}
+
+func TestNATS_IsValid(t *testing.T) {
+	_, u := newServer(t)
+	tests := []struct {
+		name string
+		step string
+	}{
+		{
+			name: "publish and subscribe",
+			step: "nats:\n  subject: a\n  publish: [{data: x}]\n  subscribe: {count: 1}\n",
+		},
+		{
+			name: "empty subject",
+			step: "nats:\n  publish: [{data: x}]\n",
+		},
+		{
+			name: "background without name",
+			step: "nats:\n  subject: a\n  subscribe: {background: true}\n",
+		},
+		{
+			name: "jetstream request",
+			step: "nats:\n  subject: a\n  jetStream: true\n  request: {data: x}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := u.Build(func(v any) error {
+				return yaml.Unmarshal([]byte(tt.step), v)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmd.SetVars(testutil.Vars{})
+			if err := cmd.IsValid(); err == nil {
+				t.Errorf("IsValid() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestUnmarshaller_Stop(t *testing.T) {
+	_, u := newServer(t)
+	testutil.Run[*NATS](t, u, "nats:\n  subject: orders.1\n  publish:\n    - data: '{}'\n", testutil.Vars{})
+	opened := []*gonats.Conn{}
+	for _, nc := range u.conns.conns {
+		opened = append(opened, nc)
+	}
+	if len(opened) != 1 {
+		t.Fatalf("expected one connection, got %d", len(opened))
+	}
+	if err := u.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if len(u.conns.conns) != 0 || !opened[0].IsClosed() {
+		t.Errorf("connection is not closed, status %v", opened[0].Status())
+	}
+}
@@ -0,0 +1,95 @@
+package nats
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	gonats "github.com/nats-io/nats.go"
+
+	"github.com/ixpectus/declarate/internal/collector"
+)
+
+// subscribe starts subscriber, subscription is registered on server before
+// return, so messages published by the next steps are not missed
+func (e *NATS) subscribe(nc *gonats.Conn) (*collector.Collector, error) {
+	cfg := e.Config.Subscribe
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	subject := e.Vars.Apply(e.Config.Subject)
+	var (
+		sub *gonats.Subscription
+		err error
+	)
+	if e.Config.JetStream {
+		js, jsErr := nc.JetStream()
+		if jsErr != nil {
+			return nil, fmt.Errorf("make jetstream context: %w", jsErr)
+		}
+		deliver := gonats.DeliverAll()
+		if cfg.FromNow {
+			deliver = gonats.DeliverNew()
+		}
+		sub, err = js.SubscribeSync(subject, gonats.OrderedConsumer(), deliver)
+	} else {
+		sub, err = nc.SubscribeSync(subject)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("subscribe %s: %w", subject, err)
+	}
+	if err := nc.Flush(); err != nil {
+		_ = sub.Unsubscribe()
+		return nil, fmt.Errorf("flush nats connection: %w", err)
+	}
+	stop := collector.Config{
+		Count:    cfg.Count,
+		Timeout:  timeout,
+		Comparer: e.comparer,
+		Params:   e.Config.ComparisonParams,
+	}
+	if cfg.Until != nil {
+		stop.Until = strings.TrimSuffix(e.Vars.Apply(*cfg.Until), "\n")
+	}
+	c := collector.New(stop)
+	go receive(sub, e.Config.JetStream, c, timeout)
+
+	return c, nil
+}
+
+// receive reads subscription until stop condition or timeout
+func receive(sub *gonats.Subscription, jetStream bool, c *collector.Collector, timeout time.Duration) {
+	defer sub.Unsubscribe()
+	deadline := time.Now().Add(timeout)
+	for {
+		left := time.Until(deadline)
+		if left <= 0 {
+			c.Close(nil, true)
+			return
+		}
+		msg, err := sub.NextMsg(left)
+		if errors.Is(err, gonats.ErrTimeout) {
+			c.Close(nil, true)
+			return
+		}
+		if err != nil {
+			c.Close(fmt.Errorf("receive %s: %w", sub.Subject, err), false)
+			return
+		}
+		var (
+			stream   string
+			sequence uint64
+		)
+		if jetStream {
+			if meta, err := msg.Metadata(); err == nil {
+				stream, sequence = meta.Stream, meta.Sequence.Stream
+			}
+		}
+		if c.Add(render(msg, stream, sequence)) {
+			c.Close(nil, false)
+			return
+		}
+	}
+}
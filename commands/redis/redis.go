@@ -0,0 +1,351 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dailymotion/allure-go"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/tools"
+	"github.com/ixpectus/declarate/variables"
+)
+
+const (
+	defaultConn    = "default"
+	defaultTimeout = 10 * time.Second
+	scanCount      = 100
+)
+
+type Redis struct {
+	Config       *Config
+	Vars         contract.Vars
+	Report       contract.ReportAttachement
+	comparer     contract.Comparer
+	clients      *clients
+	responseBody *string
+	repro        string
+}
+
+type Unmarshaller struct {
+	comparer contract.Comparer
+	clients  *clients
+}
+
+// NewUnmarshaller makes redis command builder, conns are redis urls or
+// addresses by connection name, `default` is used when step has no conn
+func NewUnmarshaller(conns map[string]string, comparer contract.Comparer) *Unmarshaller {
+	return &Unmarshaller{
+		comparer: comparer,
+		clients: &clients{
+			conns:   conns,
+			clients: map[string]*goredis.Client{},
+		},
+	}
+}
+
+type Check struct {
+	Redis *Config `yaml:"redis,omitempty"`
+}
+
+type Config struct {
+	// Conn is connection name
+	Conn string `json:"conn" yaml:"conn"`
+	// Flush deletes keys matching patterns before commands
+	Flush []string `json:"flush" yaml:"flush"`
+	// Command reply is the step response
+	Command *Command `json:"command" yaml:"command"`
+	// Commands replies are the step response
+	Commands         []Command              `json:"commands" yaml:"commands"`
+	Response         *string                `json:"response" yaml:"response"`
+	ComparisonParams contract.CompareParams `json:"comparisonParams" yaml:"comparisonParams"`
+}
+
+// Command is a list of arguments or a string split like shell arguments
+type Command []string
+
+func (c *Command) UnmarshalYAML(unmarshal func(any) error) error {
+	var args []string
+	if err := unmarshal(&args); err == nil {
+		*c = args
+		return nil
+	}
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return fmt.Errorf("redis command must be a string or a list of arguments: %w", err)
+	}
+	args, err := tools.ShellArgs(s)
+	if err != nil {
+		return fmt.Errorf("parse redis command: %w", err)
+	}
+	*c = args
+
+	return nil
+}
+
+// Stop closes clients shared between steps
+func (u *Unmarshaller) Stop() error {
+	return u.clients.closeAll()
+}
+
+func (u *Unmarshaller) Build(unmarshal func(any) error) (contract.Doer, error) {
+	cfg := &Check{}
+	if err := unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("unmarshall redis: %w", err)
+	}
+	if cfg.Redis == nil {
+		return nil, nil
+	}
+
+	return &Redis{
+		Config:   cfg.Redis,
+		comparer: u.comparer,
+		clients:  u.clients,
+	}, nil
+}
+
+func (e *Redis) SetVars(vv contract.Vars) {
+	e.Vars = vv
+}
+
+func (e *Redis) SetReport(r contract.ReportAttachement) {
+	e.Report = r
+}
+
+func (e *Redis) GetConfig() any {
+	return e.Config
+}
+
+func (e *Redis) IsValid() error {
+	if e.Config.Command != nil && len(e.Config.Commands) > 0 {
+		return fmt.Errorf("impossible to fill command and commands simultaneously, choose one of")
+	}
+	if e.Config.Command == nil && len(e.Config.Commands) == 0 && len(e.Config.Flush) == 0 {
+		return fmt.Errorf("redis step requires command, commands or flush")
+	}
+	for _, v := range e.commands() {
+		if len(v) == 0 {
+			return fmt.Errorf("redis command is empty")
+		}
+	}
+	if _, ok := e.clients.conns[e.connName()]; !ok {
+		return fmt.Errorf("unknown redis connection `%s`", e.connName())
+	}
+	if e.Config.Response != nil {
+		c := variables.VariableRx.ReplaceAllString(*e.Config.Response, "2")
+		if !json.Valid([]byte(c)) {
+			return fmt.Errorf("cannot parse redis response: `%v`", *e.Config.Response)
+		}
+	}
+
+	return nil
+}
+
+func (e *Redis) connName() string {
+	if e.Config.Conn == "" {
+		return defaultConn
+	}
+	return e.Config.Conn
+}
+
+func (e *Redis) commands() []Command {
+	if e.Config.Command != nil {
+		return []Command{*e.Config.Command}
+	}
+	return e.Config.Commands
+}
+
+func (e *Redis) Do() error {
+	e.responseBody = nil
+	client, connURL, err := e.clients.get(e.connName())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	repro := []string{}
+	for _, pattern := range e.Config.Flush {
+		pattern = e.Vars.Apply(pattern)
+		repro = append(repro, fmt.Sprintf(
+			"redis-cli -u %s --scan --pattern %s | xargs -r redis-cli -u %s DEL",
+			tools.ShellQuote(connURL), tools.ShellQuote(pattern), tools.ShellQuote(connURL),
+		))
+		if err := flush(ctx, client, pattern); err != nil {
+			return err
+		}
+	}
+	replies := []string{}
+	for _, cmd := range e.commands() {
+		args := make([]any, 0, len(cmd))
+		quoted := []string{"redis-cli", "-u", tools.ShellQuote(connURL)}
+		for _, v := range cmd {
+			v = e.Vars.Apply(v)
+			args = append(args, v)
+			quoted = append(quoted, tools.ShellQuote(v))
+		}
+		repro = append(repro, strings.Join(quoted, " "))
+		reply, err := client.Do(ctx, args...).Result()
+		if err != nil && !errors.Is(err, goredis.Nil) {
+			return fmt.Errorf("redis %s: %w", strings.ToUpper(fmt.Sprint(args[0])), err)
+		}
+		b, err := json.Marshal(toJSON(reply))
+		if err != nil {
+			return fmt.Errorf("marshall redis reply: %w", err)
+		}
+		replies = append(replies, string(b))
+	}
+	e.repro = strings.Join(repro, "\n")
+	if e.Report != nil {
+		e.Report.AddAttachment("commands", allure.TextPlain, []byte(e.repro))
+	}
+	if len(replies) == 0 {
+		return nil
+	}
+	res := "[" + strings.Join(replies, ",") + "]"
+	if e.Config.Command != nil {
+		res = replies[0]
+	}
+	e.responseBody = &res
+	if e.Report != nil {
+		e.Report.AddAttachment("response", allure.ApplicationJson, []byte(tools.JSONPrettyPrint(res)))
+	}
+
+	return nil
+}
+
+// flush deletes keys matching pattern
+func flush(ctx context.Context, client *goredis.Client, pattern string) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, scanCount).Result()
+		if err != nil {
+			return fmt.Errorf("scan %s: %w", pattern, err)
+		}
+		if len(keys) > 0 {
+			if err := client.Del(ctx, keys...).Err(); err != nil {
+				return fmt.Errorf("delete %s: %w", pattern, err)
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// toJSON converts reply to json compatible value, strings containing json
+// objects or arrays are kept as json
+func toJSON(v any) any {
+	switch vv := v.(type) {
+	case string:
+		trimmed := strings.TrimSpace(vv)
+		if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+			return json.RawMessage(trimmed)
+		}
+		return vv
+	case []any:
+		res := make([]any, len(vv))
+		for i, v := range vv {
+			res[i] = toJSON(v)
+		}
+		return res
+	case map[any]any:
+		res := make(map[string]any, len(vv))
+		for k, v := range vv {
+			res[fmt.Sprint(k)] = toJSON(v)
+		}
+		return res
+	case map[string]any:
+		res := make(map[string]any, len(vv))
+		for k, v := range vv {
+			res[k] = toJSON(v)
+		}
+		return res
+	}
+
+	return v
+}
+
+func (e *Redis) Repro() string {
+	return e.repro
+}
+
+func (e *Redis) ResponseBody() *string {
+	return e.responseBody
+}
+
+func (e *Redis) Check() error {
+	if e.Config.Response == nil || e.responseBody == nil {
+		return nil
+	}
+	expected := strings.TrimSuffix(e.Vars.Apply(*e.Config.Response), "\n")
+
+	return tools.CompareJSON(e.comparer, "redis response differs", expected, *e.responseBody, e.Config.ComparisonParams)
+}
+
+// clients stores clients by connection name
+type clients struct {
+	mu      sync.Mutex
+	conns   map[string]string
+	clients map[string]*goredis.Client
+}
+
+// get returns client of connection and its url with masked password
+func (c *clients) get(name string) (*goredis.Client, string, error) {
+	connURL, ok := c.conns[name]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown redis connection `%s`", name)
+	}
+	if !strings.Contains(connURL, "://") {
+		connURL = "redis://" + connURL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.clients[name]; ok {
+		return client, maskURL(connURL), nil
+	}
+	opts, err := goredis.ParseURL(connURL)
+	if err != nil {
+		// url error contains url with password
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, "", fmt.Errorf("parse redis url of %s connection: %w", name, err)
+	}
+	client := goredis.NewClient(opts)
+	c.clients[name] = client
+
+	return client, maskURL(connURL), nil
+}
+
+func (c *clients) closeAll() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for name, client := range c.clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close redis connection %s: %w", name, err))
+		}
+		delete(c.clients, name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// maskURL masks password of redis url, url is shown in report and repro
+func maskURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+
+	return u.Redacted()
+}
@@ -0,0 +1,421 @@
+package request
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	maskedSecret = "***"
+	// tokenExpiryMargin is subtracted from token lifetime, so token is not
+	// expired on the way to the server
+	tokenExpiryMargin = 10 * time.Second
+)
+
+// now is replaced in tests
+var now = time.Now
+
+// AuthConfig describes request authentication, only one mode should be set,
+// step auth replaces default one completely
+type AuthConfig struct {
+	// None disables default auth for the step
+	None   bool              `json:"none" yaml:"none"`
+	Basic  *BasicAuthConfig  `json:"basic" yaml:"basic"`
+	Bearer string            `json:"bearer" yaml:"bearer"`
+	OAuth2 *OAuth2AuthConfig `json:"oauth2" yaml:"oauth2"`
+	HMAC   *HMACAuthConfig   `json:"hmac" yaml:"hmac"`
+	SigV4  *SigV4AuthConfig  `json:"sigv4" yaml:"sigv4"`
+}
+
+type BasicAuthConfig struct {
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+// OAuth2AuthConfig describes client credentials grant, token is cached until
+// expiry
+type OAuth2AuthConfig struct {
+	TokenURL     string   `json:"tokenUrl" yaml:"tokenUrl"`
+	ClientID     string   `json:"clientId" yaml:"clientId"`
+	ClientSecret string   `json:"clientSecret" yaml:"clientSecret"`
+	Scopes       []string `json:"scopes" yaml:"scopes"`
+	// CredentialsInBody sends client credentials in the form instead of
+	// basic auth header
+	CredentialsInBody bool `json:"credentialsInBody" yaml:"credentialsInBody"`
+}
+
+// HMACAuthConfig signs `METHOD\npath?query\ntimestamp\nbody` string with the
+// key, signature is sent in Header, unix timestamp in TimestampHeader
+type HMACAuthConfig struct {
+	Key             string `json:"key" yaml:"key"`
+	Header          string `json:"header" yaml:"header"`
+	TimestampHeader string `json:"timestampHeader" yaml:"timestampHeader"`
+	// Algorithm is one of sha1, sha256, sha512, sha256 by default
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+	// Encoding is one of hex, base64, hex by default
+	Encoding string `json:"encoding" yaml:"encoding"`
+}
+
+// SigV4AuthConfig signs request with AWS signature version 4
+type SigV4AuthConfig struct {
+	AccessKey    string `json:"accessKey" yaml:"accessKey"`
+	SecretKey    string `json:"secretKey" yaml:"secretKey"`
+	SessionToken string `json:"sessionToken" yaml:"sessionToken"`
+	Region       string `json:"region" yaml:"region"`
+	Service      string `json:"service" yaml:"service"`
+}
+
+type oauth2Token struct {
+	value   string
+	expires time.Time
+}
+
+// tokens caches oauth2 tokens by token url, client and scopes, token of a
+// key is requested once at a time without blocking other keys
+type tokens struct {
+	mu     sync.Mutex
+	tokens map[string]oauth2Token
+	group  singleflight.Group
+}
+
+func newTokens() *tokens {
+	return &tokens{
+		tokens: map[string]oauth2Token{},
+	}
+}
+
+func (t *tokens) get(key string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if v, ok := t.tokens[key]; ok && now().Before(v.expires) {
+		return v.value, true
+	}
+
+	return "", false
+}
+
+func (t *tokens) set(key string, v oauth2Token) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tokens[key] = v
+}
+
+func (e *Request) authConfig() *AuthConfig {
+	if e.Config.Auth != nil {
+		if e.Config.Auth.None {
+			return nil
+		}
+		return e.Config.Auth
+	}
+	if e.service != nil && e.service.Auth != nil {
+		return e.service.Auth
+	}
+
+	return e.defaultConfig.Auth
+}
+
+// authorize adds authentication to the final request, secrets are remembered
+// for masking in attachments
+func (e *Request) authorize(req *http.Request, body []byte) error {
+	auth := e.authConfig()
+	if auth == nil {
+		return nil
+	}
+	switch {
+	case auth.Basic != nil:
+		username := e.Vars.Apply(auth.Basic.Username)
+		password := e.Vars.Apply(auth.Basic.Password)
+		req.SetBasicAuth(username, password)
+		e.addSecrets(password, strings.TrimPrefix(req.Header.Get("Authorization"), "Basic "))
+	case auth.Bearer != "":
+		token := e.Vars.Apply(auth.Bearer)
+		req.Header.Set("Authorization", "Bearer "+token)
+		e.addSecrets(token)
+	case auth.OAuth2 != nil:
+		token, err := e.oauth2Token(auth.OAuth2)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		e.addSecrets(token)
+	case auth.HMAC != nil:
+		return e.signHMAC(req, body, auth.HMAC)
+	case auth.SigV4 != nil:
+		return e.signSigV4(req, body, auth.SigV4)
+	}
+
+	return nil
+}
+
+func (e *Request) addSecrets(secrets ...string) {
+	for _, v := range secrets {
+		if v != "" {
+			e.secrets = append(e.secrets, v)
+		}
+	}
+}
+
+// mask replaces secrets used for authentication
+func (e *Request) mask(s string) string {
+	for _, v := range e.secrets {
+		s = strings.ReplaceAll(s, v, maskedSecret)
+	}
+
+	return s
+}
+
+func (e *Request) oauth2Token(cfg *OAuth2AuthConfig) (string, error) {
+	tokenURL := e.Vars.Apply(cfg.TokenURL)
+	clientID := e.Vars.Apply(cfg.ClientID)
+	clientSecret := e.Vars.Apply(cfg.ClientSecret)
+	e.addSecrets(clientSecret)
+	key := strings.Join([]string{tokenURL, clientID, strings.Join(cfg.Scopes, " ")}, "\n")
+	if t, ok := e.tokens.get(key); ok {
+		return t, nil
+	}
+	t, err, _ := e.tokens.group.Do(key, func() (any, error) {
+		// token may be received while waiting for the group
+		if t, ok := e.tokens.get(key); ok {
+			return t, nil
+		}
+		t, err := e.requestOAuth2Token(cfg, tokenURL, clientID, clientSecret)
+		if err != nil {
+			return "", err
+		}
+		e.tokens.set(key, t)
+
+		return t.value, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return t.(string), nil
+}
+
+// requestOAuth2Token requests token with client credentials grant
+func (e *Request) requestOAuth2Token(cfg *OAuth2AuthConfig, tokenURL, clientID, clientSecret string) (oauth2Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+	if cfg.CredentialsInBody {
+		form.Set("client_id", clientID)
+		form.Set("client_secret", clientSecret)
+	}
+	req, err := http.NewRequest(http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return oauth2Token{}, fmt.Errorf("make token request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentTypeForm)
+	req.Header.Set("Accept", contentTypeJSON)
+	if !cfg.CredentialsInBody {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+	client, err := e.client()
+	if err != nil {
+		return oauth2Token{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return oauth2Token{}, fmt.Errorf("token request: %w", err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return oauth2Token{}, fmt.Errorf("read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return oauth2Token{}, fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, e.mask(string(data)))
+	}
+	var res struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return oauth2Token{}, fmt.Errorf("unmarshall token response: %w", err)
+	}
+	if res.AccessToken == "" {
+		return oauth2Token{}, fmt.Errorf("token response without access_token")
+	}
+	t := oauth2Token{value: res.AccessToken}
+	if res.ExpiresIn > 0 {
+		t.expires = now().Add(time.Duration(res.ExpiresIn)*time.Second - tokenExpiryMargin)
+	} else {
+		// token without expiration is valid for the whole run
+		t.expires = now().Add(24 * time.Hour)
+	}
+
+	return t, nil
+}
+
+func (e *Request) signHMAC(req *http.Request, body []byte, cfg *HMACAuthConfig) error {
+	key := e.Vars.Apply(cfg.Key)
+	e.addSecrets(key)
+	var h func() hash.Hash
+	switch cfg.Algorithm {
+	case "", "sha256":
+		h = sha256.New
+	case "sha512":
+		h = sha512.New
+	case "sha1":
+		h = sha1.New
+	default:
+		return fmt.Errorf("unknown hmac algorithm `%s`", cfg.Algorithm)
+	}
+	header := cfg.Header
+	if header == "" {
+		header = "X-Signature"
+	}
+	timestampHeader := cfg.TimestampHeader
+	if timestampHeader == "" {
+		timestampHeader = "X-Timestamp"
+	}
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+	mac := hmac.New(h, []byte(key))
+	mac.Write([]byte(strings.Join([]string{
+		req.Method,
+		req.URL.RequestURI(),
+		timestamp,
+		string(body),
+	}, "\n")))
+	var signature string
+	switch cfg.Encoding {
+	case "", "hex":
+		signature = hex.EncodeToString(mac.Sum(nil))
+	case "base64":
+		signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	default:
+		return fmt.Errorf("unknown hmac encoding `%s`", cfg.Encoding)
+	}
+	req.Header.Set(timestampHeader, timestamp)
+	req.Header.Set(header, signature)
+
+	return nil
+}
+
+func (e *Request) signSigV4(req *http.Request, body []byte, cfg *SigV4AuthConfig) error {
+	accessKey := e.Vars.Apply(cfg.AccessKey)
+	secretKey := e.Vars.Apply(cfg.SecretKey)
+	sessionToken := e.Vars.Apply(cfg.SessionToken)
+	region := e.Vars.Apply(cfg.Region)
+	service := e.Vars.Apply(cfg.Service)
+	if accessKey == "" || secretKey == "" || region == "" || service == "" {
+		return fmt.Errorf("sigv4 auth requires accessKey, secretKey, region and service")
+	}
+	e.addSecrets(secretKey, sessionToken)
+	t := now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+	if service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if k == "content-type" || strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.Join(v, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, k := range names {
+		canonicalHeaders += k + ":" + strings.Join(strings.Fields(headers[k]), " ") + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+	key := []byte("AWS4" + secretKey)
+	for _, v := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, v)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature,
+	))
+
+	return nil
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	res := []string{}
+	for _, k := range keys {
+		vv := append([]string{}, values[k]...)
+		sort.Strings(vv)
+		for _, v := range vv {
+			res = append(res, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+
+	return strings.Join(res, "&")
+}
+
+// awsEscape escapes everything except unreserved characters of RFC 3986
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
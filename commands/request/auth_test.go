@@ -0,0 +1,223 @@
+package request
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+func authServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`"` + r.Header.Get("Authorization") + `"`))
+	}))
+}
+
+func TestRequest_AuthBasicAndBearer(t *testing.T) {
+	srv := authServer()
+	defer srv.Close()
+	u := NewUnmarshaller(srv.URL, nil, OptionDefaultRequestConfig(DefaultConfig{
+		Auth: &AuthConfig{Bearer: "{{$token}}"},
+	}))
+	vv := testutil.Vars{"token": "secret-token", "password": "qwerty"}
+
+	tests := []struct {
+		name     string
+		auth     *AuthConfig
+		expected string
+		secret   string
+	}{
+		{name: "default bearer", expected: "Bearer secret-token", secret: "secret-token"},
+		{
+			name:     "basic",
+			auth:     &AuthConfig{Basic: &BasicAuthConfig{Username: "admin", Password: "{{$password}}"}},
+			expected: "Basic YWRtaW46cXdlcnR5",
+			secret:   "YWRtaW46cXdlcnR5",
+		},
+		{name: "none", auth: &AuthConfig{None: true}, expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRequest(t, u, "a.yaml", &RequestConfig{Method: "GET", RequestURL: "/", Auth: tt.auth}, vv)
+			report := &testutil.Report{}
+			r.SetReport(report)
+			if err := r.Do(); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if got := gjson.Get(*r.ResponseBody(), "body").String(); got != tt.expected {
+				t.Errorf("expected authorization %q, got %q", tt.expected, got)
+			}
+			if tt.secret != "" && (strings.Contains(report.Attachment("request"), tt.secret) || !strings.Contains(report.Attachment("request"), maskedSecret)) {
+				t.Errorf("expected masked secret in attachment, got %s", report.Attachment("request"))
+			}
+			if tt.secret != "" && (strings.Contains(r.Repro(), tt.secret) || !strings.Contains(r.Repro(), maskedSecret)) {
+				t.Errorf("expected masked secret in repro, got %s", r.Repro())
+			}
+		})
+	}
+}
+
+func TestRequest_AuthOAuth2(t *testing.T) {
+	tokenCalls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			tokenCalls++
+			id, secret, _ := r.BasicAuth()
+			r.ParseForm()
+			if id != "client" || secret != "s3cr3t" || r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("scope") != "pets:read" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"access_token": "token` + string(rune('0'+tokenCalls)) + `", "expires_in": 60}`))
+			return
+		}
+		w.Write([]byte(`"` + r.Header.Get("Authorization") + `"`))
+	}))
+	defer srv.Close()
+	current := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+	u := NewUnmarshaller(srv.URL, nil, OptionDefaultRequestConfig(DefaultConfig{
+		Auth: &AuthConfig{OAuth2: &OAuth2AuthConfig{
+			TokenURL:     srv.URL + "/token",
+			ClientID:     "client",
+			ClientSecret: "{{$secret}}",
+			Scopes:       []string{"pets:read"},
+		}},
+	}))
+	vv := testutil.Vars{"secret": "s3cr3t"}
+
+	for i, expected := range []string{"Bearer token1", "Bearer token1", "Bearer token2"} {
+		if i == 2 {
+			current = current.Add(time.Minute)
+		}
+		r := newTestRequest(t, u, "a.yaml", &RequestConfig{Method: "GET", RequestURL: "/"}, vv)
+		if err := r.Do(); err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		if got := gjson.Get(*r.ResponseBody(), "body").String(); got != expected {
+			t.Errorf("request %d: expected authorization %q, got %q", i, expected, got)
+		}
+	}
+	if tokenCalls != 2 {
+		t.Errorf("expected cached token, got %d token requests", tokenCalls)
+	}
+}
+
+func TestRequest_AuthOAuth2Concurrent(t *testing.T) {
+	var slowCalls int32
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			id, _, _ := r.BasicAuth()
+			if id == "slow" {
+				atomic.AddInt32(&slowCalls, 1)
+				started <- struct{}{}
+				<-release
+			}
+			w.Write([]byte(`{"access_token": "` + id + `-token", "expires_in": 60}`))
+			return
+		}
+		w.Write([]byte(`"` + r.Header.Get("Authorization") + `"`))
+	}))
+	defer srv.Close()
+	u := NewUnmarshaller(srv.URL, nil)
+	auth := func(clientID string) *AuthConfig {
+		return &AuthConfig{OAuth2: &OAuth2AuthConfig{TokenURL: srv.URL + "/token", ClientID: clientID}}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		r := newTestRequest(t, u, "a.yaml", &RequestConfig{Method: "GET", RequestURL: "/", Auth: auth("slow")}, testutil.Vars{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.Do(); err != nil {
+				t.Errorf("Do() error = %v", err)
+			}
+		}()
+	}
+	<-started
+
+	// token of other client is not blocked by pending token request
+	done := make(chan error, 1)
+	fast := newTestRequest(t, u, "a.yaml", &RequestConfig{Method: "GET", RequestURL: "/", Auth: auth("fast")}, testutil.Vars{})
+	go func() { done <- fast.Do() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Do() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("token request of other client is blocked")
+	}
+	close(release)
+	wg.Wait()
+	if got := gjson.Get(*fast.ResponseBody(), "body").String(); got != "Bearer fast-token" {
+		t.Errorf("expected authorization %q, got %q", "Bearer fast-token", got)
+	}
+	if slowCalls != 1 {
+		t.Errorf("expected one token request for concurrent steps, got %d", slowCalls)
+	}
+}
+
+func TestRequest_AuthHMAC(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("key"))
+		mac.Write([]byte("POST\n/pets?a=1\n" + r.Header.Get("X-Timestamp") + "\n" + string(body)))
+		if r.Header.Get("X-Signature") != hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+	u := NewUnmarshaller(srv.URL, nil)
+
+	r := newTestRequest(t, u, "a.yaml", &RequestConfig{
+		Method:      "POST",
+		RequestURL:  "/pets",
+		QueryParams: "?a=1",
+		RequestTmpl: `{"name": "Rex"}`,
+		Auth:        &AuthConfig{HMAC: &HMACAuthConfig{Key: "key"}},
+	}, testutil.Vars{})
+	if err := r.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if status := gjson.Get(*r.ResponseBody(), "status").Int(); status != http.StatusOK {
+		t.Errorf("expected valid signature, got status %d", status)
+	}
+}
+
+// TestRequest_SignSigV4 checks get-vanilla case of aws signature v4 test suite
+func TestRequest_SignSigV4(t *testing.T) {
+	now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	r := &Request{Vars: testutil.Vars{}}
+	err := r.signSigV4(req, nil, &SigV4AuthConfig{
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:    "us-east-1",
+		Service:   "service",
+	})
+	if err != nil {
+		t.Fatalf("signSigV4() error = %v", err)
+	}
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != expected {
+		t.Errorf("unexpected authorization\n got %s\nwant %s", got, expected)
+	}
+}
@@ -0,0 +1,131 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	contentTypeJSON = "application/json"
+	contentTypeForm = "application/x-www-form-urlencoded"
+)
+
+// MultipartPart is a part of multipart body, either value or file content
+type MultipartPart struct {
+	Name        string `json:"name" yaml:"name"`
+	Value       string `json:"value" yaml:"value"`
+	File        string `json:"file" yaml:"file"`
+	FileName    string `json:"filename" yaml:"filename"`
+	ContentType string `json:"contentType" yaml:"contentType"`
+}
+
+func (e *Request) bodyModesCount() int {
+	res := 0
+	for _, v := range []bool{
+		e.Config.RequestTmpl != "",
+		len(e.Config.Form) > 0,
+		len(e.Config.Multipart) > 0,
+		e.Config.BodyFile != "",
+	} {
+		if v {
+			res++
+		}
+	}
+
+	return res
+}
+
+// body encodes request body and returns it with content type,
+// empty content type means default one
+func (e *Request) body() ([]byte, string, error) {
+	switch {
+	case len(e.Config.Form) > 0:
+		values := url.Values{}
+		keys := make([]string, 0, len(e.Config.Form))
+		for k := range e.Config.Form {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			values.Add(e.Vars.Apply(k), e.Vars.Apply(e.Config.Form[k]))
+		}
+
+		return []byte(values.Encode()), contentTypeForm, nil
+	case len(e.Config.Multipart) > 0:
+		return e.multipartBody()
+	case e.Config.BodyFile != "":
+		data, err := os.ReadFile(e.filePath(e.Vars.Apply(e.Config.BodyFile)))
+		if err != nil {
+			return nil, "", fmt.Errorf("read body file: %w", err)
+		}
+
+		return []byte(e.Vars.Apply(string(data))), "", nil
+	}
+
+	return []byte(e.Config.RequestTmpl), "", nil
+}
+
+func (e *Request) multipartBody() ([]byte, string, error) {
+	b := &bytes.Buffer{}
+	w := multipart.NewWriter(b)
+	for _, v := range e.Config.Multipart {
+		name := e.Vars.Apply(v.Name)
+		h := textproto.MIMEHeader{}
+		contentType := e.Vars.Apply(v.ContentType)
+		if v.File == "" {
+			h.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q`, name))
+			if contentType != "" {
+				h.Set("Content-Type", contentType)
+			}
+			part, err := w.CreatePart(h)
+			if err != nil {
+				return nil, "", fmt.Errorf("create multipart part %s: %w", name, err)
+			}
+			if _, err := part.Write([]byte(e.Vars.Apply(v.Value))); err != nil {
+				return nil, "", fmt.Errorf("write multipart part %s: %w", name, err)
+			}
+			continue
+		}
+		path := e.filePath(e.Vars.Apply(v.File))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("read multipart file: %w", err)
+		}
+		fileName := e.Vars.Apply(v.FileName)
+		if fileName == "" {
+			fileName = filepath.Base(path)
+		}
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, name, fileName))
+		h.Set("Content-Type", contentType)
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return nil, "", fmt.Errorf("create multipart part %s: %w", name, err)
+		}
+		if _, err := part.Write(data); err != nil {
+			return nil, "", fmt.Errorf("write multipart part %s: %w", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", fmt.Errorf("close multipart body: %w", err)
+	}
+
+	return b.Bytes(), w.FormDataContentType(), nil
+}
+
+// filePath resolves path relative to the test file
+func (e *Request) filePath(path string) string {
+	if filepath.IsAbs(path) || e.fileName == "" {
+		return path
+	}
+
+	return filepath.Join(filepath.Dir(e.fileName), path)
+}
@@ -0,0 +1,121 @@
+package request
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+func echoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := map[string]string{"contentType": r.Header.Get("Content-Type")}
+		if strings.HasPrefix(res["contentType"], "multipart/") {
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			res["title"] = r.FormValue("title")
+			f, h, err := r.FormFile("avatar")
+			if err == nil {
+				data, _ := io.ReadAll(f)
+				res["avatar"] = h.Filename + ":" + h.Header.Get("Content-Type") + ":" + string(data)
+			}
+		} else {
+			data, _ := io.ReadAll(r.Body)
+			res["body"] = string(data)
+		}
+		b, _ := json.Marshal(res)
+		w.Write(b)
+	}))
+}
+
+func TestRequest_BodyModes(t *testing.T) {
+	srv := echoServer()
+	defer srv.Close()
+	dir := t.TempDir()
+	testFile := filepath.Join(dir, "pets.yaml")
+	if err := os.MkdirAll(filepath.Join(dir, "files"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "files", "avatar.txt"), []byte("{{$name}} avatar"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "files", "pet.json"), []byte(`{"name": "{{$name}}"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	u := NewUnmarshaller(srv.URL, nil)
+	vv := testutil.Vars{"name": "Rex"}
+
+	tests := []struct {
+		name        string
+		cfg         *RequestConfig
+		contentType string
+		expected    map[string]string
+	}{
+		{
+			name:        "form",
+			cfg:         &RequestConfig{Form: map[string]string{"name": "{{$name}}", "kind": "dog & cat"}},
+			contentType: contentTypeForm,
+			expected:    map[string]string{"body": "kind=dog+%26+cat&name=Rex"},
+		},
+		{
+			name: "multipart",
+			cfg: &RequestConfig{Multipart: []MultipartPart{
+				{Name: "title", Value: "{{$name}}"},
+				{Name: "avatar", File: "files/avatar.txt", ContentType: "text/plain"},
+			}},
+			contentType: "multipart/form-data",
+			expected:    map[string]string{"title": "Rex", "avatar": "avatar.txt:text/plain:{{$name}} avatar"},
+		},
+		{
+			name:        "body file",
+			cfg:         &RequestConfig{BodyFile: "./files/pet.json"},
+			contentType: contentTypeJSON,
+			expected:    map[string]string{"body": `{"name": "Rex"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Method = "POST"
+			tt.cfg.RequestURL = "/pets"
+			r := newTestRequest(t, u, testFile, tt.cfg, vv)
+			if err := r.IsValid(); err != nil {
+				t.Fatalf("IsValid() error = %v", err)
+			}
+			if err := r.Do(); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			body := gjson.Get(*r.ResponseBody(), "body")
+			if got := body.Get("contentType").String(); !strings.HasPrefix(got, tt.contentType) {
+				t.Errorf("expected content type %s, got %s", tt.contentType, got)
+			}
+			for k, v := range tt.expected {
+				if got := body.Get(k).String(); got != v {
+					t.Errorf("expected %s %q, got %q", k, v, got)
+				}
+			}
+			if tt.name == "form" && !strings.Contains(r.Repro(), "kind=dog+%26+cat&name=Rex") {
+				t.Errorf("expected encoded body in curl, got %s", r.Repro())
+			}
+		})
+	}
+}
+
+func TestRequest_BodyModesExclusive(t *testing.T) {
+	r := &Request{Config: &RequestConfig{
+		RequestTmpl: `{}`,
+		Form:        map[string]string{"a": "b"},
+	}}
+	if err := r.IsValid(); err == nil {
+		t.Errorf("expected error for several body modes")
+	}
+}
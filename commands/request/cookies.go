@@ -0,0 +1,74 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"sync"
+
+	"github.com/dailymotion/allure-go"
+	"github.com/ixpectus/declarate/tools"
+)
+
+// cookieJars stores cookie jars of named sessions and test files
+type cookieJars struct {
+	mu   sync.Mutex
+	jars map[string]http.CookieJar
+}
+
+func newCookieJars() *cookieJars {
+	return &cookieJars{
+		jars: map[string]http.CookieJar{},
+	}
+}
+
+func (c *cookieJars) get(key string) http.CookieJar {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	jar, ok := c.jars[key]
+	if !ok {
+		// cookiejar.New returns error only for invalid options
+		jar, _ = cookiejar.New(nil)
+		c.jars[key] = jar
+	}
+
+	return jar
+}
+
+// jar returns cookie jar of named session, or jar of the test file when
+// cookie jar is enabled by default config, nil otherwise
+func (e *Request) jar() http.CookieJar {
+	if e.jars == nil {
+		return nil
+	}
+	if session := e.Vars.Apply(e.Config.Session); session != "" {
+		return e.jars.get("session:" + session)
+	}
+	if e.defaultConfig.CookieJar {
+		return e.jars.get("file:" + e.fileName)
+	}
+
+	return nil
+}
+
+func (e *Request) setCookieVariables(cookies map[string]string) error {
+	if len(e.Config.CookieVariables) == 0 {
+		return nil
+	}
+	res := map[string]string{}
+	for k, name := range e.Config.CookieVariables {
+		v, ok := cookies[name]
+		if !ok {
+			return fmt.Errorf("cookie %s for variable %s not found in response", name, k)
+		}
+		if err := e.Vars.Set(k, v); err != nil {
+			return fmt.Errorf("set variable %s from cookie: %w", k, err)
+		}
+		res[k] = v
+	}
+	if e.report != nil {
+		e.report.AddAttachment("variables from cookies", allure.TextPlain, []byte(tools.FormatVariables(res)))
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package request
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/ixpectus/declarate/contract"
+)
+
+const (
+	errorTypeTimeout = "timeout"
+	errorTypeRefused = "refused"
+	errorTypeReset   = "reset"
+	errorTypeTLS     = "tls"
+	errorTypeDNS     = "dns"
+	errorTypeUnknown = "unknown"
+)
+
+// ExpectErrorConfig describes expected transport error, empty type matches
+// any error, message is a regexp
+type ExpectErrorConfig struct {
+	Type    string `json:"type" yaml:"type"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// isValid checks that type is empty or one of known types
+func (c *ExpectErrorConfig) isValid() error {
+	switch c.Type {
+	case "", errorTypeTimeout, errorTypeRefused, errorTypeReset, errorTypeTLS, errorTypeDNS:
+		return nil
+	}
+
+	return fmt.Errorf(
+		"unknown expectError type `%s`, expected one of %s, %s, %s, %s, %s",
+		c.Type, errorTypeTimeout, errorTypeRefused, errorTypeReset, errorTypeTLS, errorTypeDNS,
+	)
+}
+
+// transportError is an error of sending request or reading response
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// errorType classifies transport error, dns is checked first because dns
+// errors can be timeouts too
+func errorType(err error) string {
+	var (
+		dnsErr       *net.DNSError
+		netErr       net.Error
+		unknownAuth  x509.UnknownAuthorityError
+		invalidCert  x509.CertificateInvalidError
+		hostnameErr  x509.HostnameError
+		recordErr    tls.RecordHeaderError
+		verification *tls.CertificateVerificationError
+	)
+	switch {
+	case errors.As(err, &dnsErr):
+		return errorTypeDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return errorTypeRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return errorTypeReset
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return errorTypeTimeout
+	case errors.As(err, &unknownAuth), errors.As(err, &invalidCert), errors.As(err, &hostnameErr),
+		errors.As(err, &recordErr), errors.As(err, &verification), strings.Contains(err.Error(), "tls: "):
+		return errorTypeTLS
+	}
+
+	return errorTypeUnknown
+}
+
+// expectedError keeps transport error when it is expected, so Check can
+// compare it, other errors are returned as is
+func (e *Request) expectedError(err error) error {
+	var tErr *transportError
+	if e.Config.ExpectError == nil || !errors.As(err, &tErr) {
+		return err
+	}
+	e.transportErr = tErr.err
+	e.responseBody = nil
+
+	return nil
+}
+
+func (e *Request) checkExpectedError() error {
+	expected := e.Config.ExpectError
+	if e.transportErr == nil {
+		actual := "no error"
+		if e.responseBody != nil {
+			actual = "status " + gjson.Get(*e.responseBody, "status").String()
+		}
+		return &contract.TestError{
+			Title:         "expected transport error",
+			Expected:      expectedErrorString(expected),
+			Actual:        actual,
+			OriginalError: fmt.Errorf("expected transport error, got %s", actual),
+		}
+	}
+	got := errorType(e.transportErr)
+	message := e.transportErr.Error()
+	var errs []string
+	if expected.Type != "" && expected.Type != got {
+		errs = append(errs, fmt.Sprintf("error type differs, expected %s, got %s", expected.Type, got))
+	}
+	if expected.Message != "" {
+		rx, err := regexp.Compile(e.Vars.Apply(expected.Message))
+		if err != nil {
+			return fmt.Errorf("compile expected error message: %w", err)
+		}
+		if !rx.MatchString(message) {
+			errs = append(errs, fmt.Sprintf("error message `%s` does not match `%s`", message, rx.String()))
+		}
+	}
+	if len(errs) > 0 {
+		msg := strings.Join(errs, "\n")
+		return &contract.TestError{
+			Title:         "transport error differs",
+			Expected:      expectedErrorString(expected),
+			Actual:        got + ": " + message,
+			Message:       msg,
+			OriginalError: fmt.Errorf("transport error differs: %v", msg),
+		}
+	}
+
+	return nil
+}
+
+func expectedErrorString(expected *ExpectErrorConfig) string {
+	res := expected.Type
+	if res == "" {
+		res = "any"
+	}
+	if expected.Message != "" {
+		res += ": " + expected.Message
+	}
+
+	return res
+}
@@ -0,0 +1,126 @@
+package request
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+func TestRequest_ExpectError(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer secure.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "http://" + l.Addr().String()
+	l.Close()
+	insecure := true
+
+	tests := []struct {
+		name      string
+		host      string
+		transport *TransportConfig
+		expect    ExpectErrorConfig
+		wantErr   bool
+	}{
+		{
+			name:      "timeout",
+			host:      slow.URL,
+			transport: &TransportConfig{Timeout: 50 * time.Millisecond},
+			expect:    ExpectErrorConfig{Type: errorTypeTimeout, Message: "Timeout"},
+		},
+		{
+			name:   "refused",
+			host:   closed,
+			expect: ExpectErrorConfig{Type: errorTypeRefused},
+		},
+		{
+			name:   "tls",
+			host:   secure.URL,
+			expect: ExpectErrorConfig{Type: errorTypeTLS},
+		},
+		{
+			name:    "type differs",
+			host:    closed,
+			expect:  ExpectErrorConfig{Type: errorTypeTLS},
+			wantErr: true,
+		},
+		{
+			name:    "message differs",
+			host:    closed,
+			expect:  ExpectErrorConfig{Message: "no such host"},
+			wantErr: true,
+		},
+		{
+			name:      "unexpected success",
+			host:      secure.URL,
+			transport: &TransportConfig{InsecureSkipVerify: &insecure},
+			expect:    ExpectErrorConfig{},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect := tt.expect
+			r := newTestRequest(t, NewUnmarshaller(tt.host, nil), "a.yaml", &RequestConfig{
+				Method:      "GET",
+				RequestURL:  "/",
+				Transport:   tt.transport,
+				ExpectError: &expect,
+			}, testutil.Vars{})
+			if err := r.Do(); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			err := r.Check()
+			var testErr *contract.TestError
+			if tt.wantErr && !errors.As(err, &testErr) {
+				t.Errorf("Check() error = %v, want test error", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Check() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRequest_UnexpectedTransportError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := "http://" + l.Addr().String()
+	l.Close()
+	r := newTestRequest(t, NewUnmarshaller(host, nil), "a.yaml", &RequestConfig{
+		Method:     "GET",
+		RequestURL: "/",
+	}, testutil.Vars{})
+	if err := r.Do(); err == nil {
+		t.Errorf("expected transport error without expectError")
+	}
+}
+
+func TestRequest_ExpectErrorIsValid(t *testing.T) {
+	for _, tp := range []string{"", "timeout", "refused", "reset", "tls", "dns"} {
+		r := &Request{Config: &RequestConfig{ExpectError: &ExpectErrorConfig{Type: tp}}}
+		if err := r.IsValid(); err != nil {
+			t.Errorf("IsValid() for type %q error = %v", tp, err)
+		}
+	}
+	for _, tp := range []string{"unknown", "Timeout", "refuse"} {
+		r := &Request{Config: &RequestConfig{ExpectError: &ExpectErrorConfig{Type: tp}}}
+		if err := r.IsValid(); err == nil {
+			t.Errorf("expected error for type %q", tp)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/ixpectus/declarate/contract"
+)
+
+// absent is a matcher for headers and cookies which must not be in response
+const absent = "$absent"
+
+func (e *Request) checkHeaders() error {
+	if e.responseHeader == nil {
+		return nil
+	}
+	if err := e.checkValues(
+		"response headers differ",
+		e.Config.ResponseHeaders,
+		func(k string) (string, bool) {
+			v, ok := e.responseHeader[http.CanonicalHeaderKey(k)]
+			return strings.Join(v, ", "), ok
+		},
+	); err != nil {
+		return err
+	}
+
+	return e.checkValues(
+		"response cookies differ",
+		e.Config.ResponseCookies,
+		func(k string) (string, bool) {
+			v, ok := e.responseCookies[k]
+			return v, ok
+		},
+	)
+}
+
+// checkValues compares expected values with the actual ones using comparer
+// matchers, `$absent` checks that value is missing
+func (e *Request) checkValues(
+	title string,
+	expected map[string]string,
+	actual func(k string) (string, bool),
+) error {
+	if len(expected) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(expected))
+	for k := range expected {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var (
+		errs           []string
+		expectedValues []string
+		actualValues   []string
+	)
+	for _, k := range keys {
+		exp := e.Vars.Apply(expected[k])
+		got, ok := actual(k)
+		expectedValues = append(expectedValues, k+": "+exp)
+		if ok {
+			actualValues = append(actualValues, k+": "+got)
+		} else {
+			actualValues = append(actualValues, k+": "+absent)
+		}
+		if exp == absent {
+			if ok {
+				errs = append(errs, fmt.Sprintf("%s expected to be absent, got `%s`", k, got))
+			}
+			continue
+		}
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s not found", k))
+			continue
+		}
+		for _, err := range e.comparer.Compare(exp, got, e.Config.ComparisonParams) {
+			errs = append(errs, fmt.Sprintf("%s: %s", k, err.Error()))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	msg := strings.Join(errs, "\n")
+
+	return &contract.TestError{
+		Title:         title,
+		Expected:      strings.Join(expectedValues, "\n"),
+		Actual:        strings.Join(actualValues, "\n"),
+		Message:       msg,
+		OriginalError: fmt.Errorf("%s: %v", title, msg),
+	}
+}
@@ -0,0 +1,79 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/eval"
+	"github.com/ixpectus/declarate/internal/testutil"
+	"github.com/ixpectus/declarate/variables"
+)
+
+func TestRequest_CheckHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("X-Request-Id", "42")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	vv := variables.New(eval.NewEval(nil), nil, false)
+	u := NewUnmarshaller(srv.URL, compare.New(contract.CompareParams{}, vv))
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		cookies map[string]string
+		errMsg  string
+	}{
+		{
+			name: "matched",
+			headers: map[string]string{
+				"content-type": "$matchRegexp(^application/json)",
+				"X-Request-Id": "$gt(10)",
+				"X-Debug":      absent,
+			},
+			cookies: map[string]string{"session": "abc", "theme": absent},
+		},
+		{
+			name:    "differs",
+			headers: map[string]string{"X-Request-Id": "43"},
+			errMsg:  "X-Request-Id",
+		},
+		{
+			name:    "not absent",
+			headers: map[string]string{"X-Request-Id": absent},
+			errMsg:  "expected to be absent",
+		},
+		{
+			name:    "missing cookie",
+			cookies: map[string]string{"theme": "$any"},
+			errMsg:  "theme not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRequest(t, u, "a.yaml", &RequestConfig{
+				Method:          "GET",
+				RequestURL:      "/",
+				ResponseHeaders: tt.headers,
+				ResponseCookies: tt.cookies,
+			}, testutil.Vars{})
+			r.mode = modeLight
+			if err := r.Do(); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			err := r.Check()
+			if tt.errMsg == "" && err != nil {
+				t.Errorf("Check() error = %v", err)
+			}
+			if tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)) {
+				t.Errorf("expected error with %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dailymotion/allure-go"
+	"github.com/tidwall/gjson"
+	"moul.io/http2curl"
+
+	"github.com/ixpectus/declarate/tools"
+)
+
+const (
+	defaultPageLimit   = 10
+	defaultCursorParam = "cursor"
+)
+
+// PaginateConfig describes how next page is found, one of Cursor, NextURL
+// and Link is required
+type PaginateConfig struct {
+	// Cursor is json path of next cursor in page body, cursor is sent in
+	// query parameter Param
+	Cursor string `json:"cursor" yaml:"cursor"`
+	Param  string `json:"param" yaml:"param"`
+	// NextURL is json path of next page url in page body
+	NextURL string `json:"nextUrl" yaml:"nextUrl"`
+	// Link follows url from Link header with rel=next
+	Link bool `json:"link" yaml:"link"`
+	// Items is json path of items array in page body, items of all pages
+	// are concatenated, whole page bodies are collected when empty
+	Items string `json:"items" yaml:"items"`
+	// Limit is max pages count
+	Limit int `json:"limit" yaml:"limit"`
+}
+
+func (c PaginateConfig) modesCount() int {
+	count := 0
+	for _, v := range []bool{c.Cursor != "", c.NextURL != "", c.Link} {
+		if v {
+			count++
+		}
+	}
+
+	return count
+}
+
+// paginate follows next pages and joins them, status and headers are taken
+// from the last page, timings are summed, paging stops on the first non
+// 2xx response which is returned as is
+func (e *Request) paginate(
+	client *http.Client,
+	config RequestConfig,
+	reqBody []byte,
+	contentType string,
+	first *page,
+) (*page, error) {
+	cfg := e.Config.Paginate
+	limit := cfg.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	items := []string{}
+	cookies := map[string]string{}
+	phases := map[string]float64{}
+	p := first
+	curl := e.curl
+	for n := 1; ; n++ {
+		e.reportPage(n, curl, p)
+		if p.resp.StatusCode < 200 || p.resp.StatusCode > 299 {
+			return p, nil
+		}
+		pageItems, err := cfg.items(p.body, p.resp.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", n, err)
+		}
+		items = append(items, pageItems...)
+		for k, v := range p.cookies {
+			cookies[k] = v
+		}
+		for k, v := range p.phases {
+			phases[k] += v
+		}
+		if n >= limit {
+			break
+		}
+		next, err := cfg.next(p)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", n, err)
+		}
+		if next == nil {
+			break
+		}
+		pageConfig := config
+		pageConfig.RequestURL = next.String()
+		pageConfig.QueryParams = ""
+		req, err := newCommonRequest("", pageConfig, reqBody, contentType)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.authorize(req, reqBody); err != nil {
+			return nil, fmt.Errorf("authorize request: %w", err)
+		}
+		curl = ""
+		if curlReq, _ := http2curl.GetCurlCommand(req); curlReq != nil {
+			curl = curlReq.String()
+		}
+		p, err = e.send(client, req)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", n+1, err)
+		}
+	}
+	res := *p
+	res.body = []byte("[" + strings.Join(items, ",") + "]")
+	res.cookies = cookies
+	res.phases = phases
+	res.start = first.start
+
+	return &res, nil
+}
+
+// items returns raw json items of the page
+func (c PaginateConfig) items(body []byte, contentType string) ([]string, error) {
+	if c.Items == "" {
+		raw, _ := encodeBody(body, contentType)
+		return []string{string(raw)}, nil
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("body is not json, items `%s` not found", c.Items)
+	}
+	res := gjson.GetBytes(body, c.Items)
+	if !res.IsArray() {
+		return nil, fmt.Errorf("items `%s` is not an array", c.Items)
+	}
+	items := []string{}
+	for _, v := range res.Array() {
+		items = append(items, v.Raw)
+	}
+
+	return items, nil
+}
+
+// next returns next page url resolved against the current one, nil means
+// there are no more pages
+func (c PaginateConfig) next(p *page) (*url.URL, error) {
+	current := p.resp.Request.URL
+	var next string
+	switch {
+	case c.Link:
+		next = linkNext(p.resp.Header.Values("Link"))
+	case c.NextURL != "":
+		next = gjson.GetBytes(p.body, c.NextURL).String()
+	case c.Cursor != "":
+		cursor := gjson.GetBytes(p.body, c.Cursor).String()
+		if cursor == "" {
+			return nil, nil
+		}
+		param := c.Param
+		if param == "" {
+			param = defaultCursorParam
+		}
+		u := *current
+		query := u.Query()
+		query.Set(param, cursor)
+		u.RawQuery = query.Encode()
+		return &u, nil
+	}
+	if next == "" {
+		return nil, nil
+	}
+	u, err := current.Parse(next)
+	if err != nil {
+		return nil, fmt.Errorf("parse next page url %s: %w", next, err)
+	}
+
+	return u, nil
+}
+
+// linkNext finds rel=next url in Link headers
+func linkNext(links []string) string {
+	for _, header := range links {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(k, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(v, `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+func (e *Request) reportPage(n int, curl string, p *page) {
+	if e.report == nil {
+		return
+	}
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "%s\n\n%d\n", e.mask(curl), p.resp.StatusCode)
+	b.WriteString(tools.JSONPrettyPrint(string(p.body)))
+	e.report.AddAttachment(fmt.Sprintf("page %d", n), allure.TextPlain, b.Bytes())
+}
@@ -0,0 +1,117 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+// pagesServer serves three pages of two items by cursor or by Link header
+func pagesServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		if r.URL.Path == "/links" {
+			n, _ = strconv.Atoi(r.URL.Query().Get("page"))
+			if n < 2 {
+				w.Header().Set("Link", fmt.Sprintf(`</links?page=%d>; rel="next", </links?page=0>; rel="first"`, n+1))
+			}
+		}
+		next := `null`
+		if n < 2 {
+			next = fmt.Sprintf(`"%d"`, n+1)
+		}
+		fmt.Fprintf(w, `{"data":[{"id":%d},{"id":%d}],"meta":{"next":%s}}`, n*2, n*2+1, next)
+	}))
+}
+
+func TestRequest_Paginate(t *testing.T) {
+	srv := pagesServer()
+	defer srv.Close()
+	u := NewUnmarshaller(srv.URL, nil)
+
+	tests := []struct {
+		name     string
+		path     string
+		paginate *PaginateConfig
+		want     string
+		pages    int
+	}{
+		{
+			name:     "cursor",
+			path:     "/items",
+			paginate: &PaginateConfig{Cursor: "meta.next", Items: "data"},
+			want:     `[0,1,2,3,4,5]`,
+			pages:    3,
+		},
+		{
+			name:     "link",
+			path:     "/links",
+			paginate: &PaginateConfig{Link: true, Items: "data"},
+			want:     `[0,1,2,3,4,5]`,
+			pages:    3,
+		},
+		{
+			name:     "limit",
+			path:     "/items",
+			paginate: &PaginateConfig{Cursor: "meta.next", Items: "data", Limit: 2},
+			want:     `[0,1,2,3]`,
+			pages:    2,
+		},
+		{
+			name:     "whole pages",
+			path:     "/items",
+			paginate: &PaginateConfig{Cursor: "meta.next"},
+			want:     `[[0,1],[2,3],[4,5]]`,
+			pages:    3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRequest(t, u, "a.yaml", &RequestConfig{
+				Method:     "GET",
+				RequestURL: tt.path,
+				Paginate:   tt.paginate,
+			}, testutil.Vars{})
+			r.mode = modeLight
+			if err := r.IsValid(); err != nil {
+				t.Fatalf("IsValid() error = %v", err)
+			}
+			report := &testutil.Report{}
+			r.SetReport(report)
+			if err := r.Do(); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			body := gjson.Parse(*r.ResponseBody())
+			ids := body.Get("#.id").Raw
+			if tt.paginate.Items == "" {
+				ids = body.Get("#.data.#.id").Raw
+			}
+			if ids != tt.want {
+				t.Errorf("ids = %s, want %s", ids, tt.want)
+			}
+			for n := 1; n <= tt.pages+1; n++ {
+				ok := report.Attachment(fmt.Sprintf("page %d", n)) != ""
+				if ok != (n <= tt.pages) {
+					t.Errorf("page %d attachment exists %v", n, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestRequest_PaginateInvalid(t *testing.T) {
+	r := &Request{Config: &RequestConfig{
+		Method:     "GET",
+		RequestURL: "/items",
+		Paginate:   &PaginateConfig{Cursor: "next", Link: true},
+	}}
+	if err := r.IsValid(); err == nil {
+		t.Errorf("expected error for several paginate modes")
+	}
+}
@@ -2,10 +2,12 @@ package request
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"time"
 
@@ -30,14 +32,30 @@ type Request struct {
 	Vars          contract.Vars
 	Host          string
 	responseBody  *string
+	curl          string
 	comparer      contract.Comparer
 	report        contract.ReportAttachement
+	jars          *cookieJars
+	clients       *clients
+	tokens        *tokens
+	secrets       []string
+	fileName      string
+	service       *ServiceConfig
+	// response headers and cookies are kept for assertions
+	responseHeader  http.Header
+	responseCookies map[string]string
+	// transportErr is kept when transport error is expected
+	transportErr error
 }
 
 type Unmarshaller struct {
 	host          string
 	comparer      contract.Comparer
 	defaultConfig DefaultConfig
+	jars          *cookieJars
+	clients       *clients
+	tokens        *tokens
+	services      map[string]ServiceConfig
 }
 
 type Option func(*Unmarshaller)
@@ -57,6 +75,9 @@ func NewUnmarshaller(
 		host:          host,
 		comparer:      comparer,
 		defaultConfig: DefaultConfig{},
+		jars:          newCookieJars(),
+		clients:       newClients(),
+		tokens:        newTokens(),
 	}
 	for _, v := range opts {
 		v(u)
@@ -77,17 +98,31 @@ func (u *Unmarshaller) Build(unmarshal func(any) error) (contract.Doer, error) {
 	if cfg.FullResponse != nil {
 		mode = modeFull
 	}
+	host := u.host
+	var service *ServiceConfig
+	if s, ok := u.services[cfg.Service]; ok && cfg.Service != "" {
+		service = &s
+		host = s.Host
+	}
 	return &Request{
 		Config:        cfg,
-		Host:          u.host,
+		Host:          host,
+		service:       service,
 		comparer:      u.comparer,
 		mode:          mode,
 		defaultConfig: u.defaultConfig,
+		jars:          u.jars,
+		clients:       u.clients,
+		tokens:        u.tokens,
 	}, nil
 }
 
 type DefaultConfig struct {
 	HeadersVal map[string]string `json:"headers" yaml:"headers"`
+	// CookieJar enables cookie jar shared by all request steps of a file
+	CookieJar bool            `json:"cookieJar" yaml:"cookieJar"`
+	Transport TransportConfig `json:"transport" yaml:"transport"`
+	Auth      *AuthConfig     `json:"auth" yaml:"auth"`
 }
 type RequestConfig struct {
 	Method           string                 `json:"method" yaml:"method"`
@@ -98,6 +133,22 @@ type RequestConfig struct {
 	HeadersVal       map[string]string      `json:"headers" yaml:"headers"`
 	QueryParams      string                 `json:"query" yaml:"query"`
 	CookiesVal       map[string]string      `json:"cookies" yaml:"cookies"`
+	Session          string                 `json:"session" yaml:"session"`
+	CookieVariables  map[string]string      `json:"cookieVariables" yaml:"cookieVariables"`
+	TimingVariables  map[string]string      `json:"timingVariables" yaml:"timingVariables"`
+	Paginate         *PaginateConfig        `json:"paginate" yaml:"paginate"`
+	ExpectError      *ExpectErrorConfig     `json:"expectError" yaml:"expectError"`
+	Transport        *TransportConfig       `json:"transport" yaml:"transport"`
+	Form             map[string]string      `json:"form" yaml:"form"`
+	Multipart        []MultipartPart        `json:"multipart" yaml:"multipart"`
+	BodyFile         string                 `json:"body_file" yaml:"body_file"`
+	Service          string                 `json:"service" yaml:"service"`
+	Auth             *AuthConfig            `json:"auth" yaml:"auth"`
+	ResponseText     *string                `json:"responseText" yaml:"responseText"`
+	ResponseContains []string               `json:"responseContains" yaml:"responseContains"`
+	ResponseRegexp   string                 `json:"responseRegexp" yaml:"responseRegexp"`
+	ResponseHeaders  map[string]string      `json:"responseHeaders" yaml:"responseHeaders"`
+	ResponseCookies  map[string]string      `json:"responseCookies" yaml:"responseCookies"`
 	ComparisonParams contract.CompareParams `json:"comparisonParams" yaml:"comparisonParams"`
 	RequestURL       string                 `json:"path" yaml:"path"`
 }
@@ -110,6 +161,10 @@ func (e *Request) SetReport(r contract.ReportAttachement) {
 	e.report = r
 }
 
+func (e *Request) SetFileName(fileName string) {
+	e.fileName = fileName
+}
+
 func (e *Request) GetConfig() any {
 	return e.Config
 }
@@ -126,6 +181,23 @@ func (e *Request) applyHeadersVal(headers map[string]string) map[string]string {
 }
 
 func (e *Request) IsValid() error {
+	if e.Config.Service != "" && e.service == nil {
+		return fmt.Errorf("unknown service `%s`", e.Config.Service)
+	}
+	if e.Config.Response != nil && e.Config.ResponseText != nil {
+		return fmt.Errorf("impossible to fill response and responseText simultaneously, choose one of")
+	}
+	if e.Config.Paginate != nil && e.Config.Paginate.modesCount() != 1 {
+		return fmt.Errorf("paginate requires one of cursor, nextUrl and link")
+	}
+	if e.Config.ExpectError != nil {
+		if err := e.Config.ExpectError.isValid(); err != nil {
+			return err
+		}
+	}
+	if e.bodyModesCount() > 1 {
+		return fmt.Errorf("impossible to fill request, form, multipart and body_file simultaneously, choose one of")
+	}
 	if (e.Config.Response != nil || e.Config.ResponseStatus != nil) && e.Config.FullResponse != nil {
 		return fmt.Errorf("impossible to fill response and fullResponse simultaneously, choose one of")
 	}
@@ -149,12 +221,14 @@ func (e *Request) IsValid() error {
 }
 
 func (e *Request) Do() error {
+	return e.withService(e.expectedError(e.do()))
+}
+
+func (e *Request) do() error {
 	if e.Config.Method == "" {
 		return nil
 	}
-	e.Config.QueryParams = e.Vars.Apply(e.Config.QueryParams)
-	e.Config.RequestTmpl = e.Vars.Apply(e.Config.RequestTmpl)
-
+	e.transportErr = nil
 	if e.Config.Response != nil {
 		s := e.Vars.Apply(*e.Config.Response)
 		s = strings.TrimSuffix(s, "\n")
@@ -170,55 +244,186 @@ func (e *Request) Do() error {
 		s = strings.TrimSuffix(s, "\n")
 		e.Config.ResponseStatus = &s
 	}
+	out, err := e.prepare()
+	if err != nil {
+		return err
+	}
+	client, config, reqBody, contentType := out.client, out.config, out.body, out.contentType
+	p, err := e.send(client, out.req)
+	if err != nil {
+		return err
+	}
+	if e.Config.Paginate != nil {
+		p, err = e.paginate(client, config, reqBody, contentType, p)
+		if err != nil {
+			return err
+		}
+	}
+	reqStart, reqFinish, body, phases := p.start, p.finish, p.body, p.phases
+	reqDuration := reqFinish.Sub(reqStart)
+	resp := p.resp
+	cookies := p.cookies
+	e.responseHeader = resp.Header
+	e.responseCookies = cookies
+	env := envelope{
+		Status:  resp.StatusCode,
+		Header:  resp.Header,
+		Cookies: cookies,
+		Timings: phases,
+	}
+	env.Body, env.BodyType = encodeBody(body, resp.Header.Get("Content-Type"))
+	r := env.String()
+	if err := e.setCookieVariables(cookies); err != nil {
+		return err
+	}
+	if err := e.setTimingVariables(phases); err != nil {
+		return err
+	}
+	e.reportTimings(phases)
+
+	if e.report != nil {
+		e.report.AddAttachment("response", allure.ApplicationJson, []byte(tools.JSONPrettyPrint(r)))
+		meta := map[string]string{
+			"start":    reqStart.Format(time.RFC3339Nano),
+			"finish":   reqFinish.Format(time.RFC3339Nano),
+			"duration": reqDuration.Round(time.Millisecond).String(),
+		}
+		if e.Config.Service != "" {
+			meta["service"] = e.Config.Service
+		}
+		e.report.AddAttachment("meta", allure.TextPlain, []byte(tools.FormatVariables(meta)))
+	}
+	e.responseBody = tools.To(r)
+
+	return nil
+}
+
+// outgoing is prepared request with the client sending it
+type outgoing struct {
+	req         *http.Request
+	client      *http.Client
+	config      RequestConfig
+	body        []byte
+	contentType string
+}
+
+// prepare resolves variables, headers of defaults and service, body, auth
+// and transport of the step
+func (e *Request) prepare() (*outgoing, error) {
+	e.Config.QueryParams = e.Vars.Apply(e.Config.QueryParams)
+	e.Config.RequestTmpl = e.Vars.Apply(e.Config.RequestTmpl)
 	e.Config.RequestURL = e.Vars.Apply(e.Config.RequestURL)
 
 	defaultHeaders := e.applyHeadersVal(e.defaultConfig.HeadersVal)
 	if defaultHeaders == nil {
 		defaultHeaders = map[string]string{}
 	}
+	if e.service != nil {
+		for k, v := range e.applyHeadersVal(e.service.HeadersVal) {
+			defaultHeaders[k] = v
+		}
+	}
 	headers := e.applyHeadersVal(e.Config.HeadersVal)
 	for k, v := range headers {
 		defaultHeaders[k] = v
 	}
 	config := *e.Config
 	config.HeadersVal = defaultHeaders
-	req, err := newCommonRequest(e.Host, config)
+	config.CookiesVal = e.applyHeadersVal(e.Config.CookiesVal)
+	reqBody, contentType, err := e.body()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	req, err := newCommonRequest(e.Host, config, reqBody, contentType)
+	if err != nil {
+		return nil, err
+	}
+	e.secrets = nil
+	if err := e.authorize(req, reqBody); err != nil {
+		return nil, fmt.Errorf("authorize request: %w", err)
+	}
+	client, err := e.client()
+	if err != nil {
+		return nil, err
 	}
-	client := &http.Client{}
 	curlReq, _ := http2curl.GetCurlCommand(req)
-	reqStart := time.Now()
-	e.report.AddAttachment("request", allure.TextPlain, []byte(curlReq.String()))
+	if curlReq != nil {
+		e.curl = curlReq.String()
+	}
+	e.report.AddAttachment("request", allure.TextPlain, []byte(e.mask(e.curl)))
+
+	return &outgoing{
+		req:         req,
+		client:      client,
+		config:      config,
+		body:        reqBody,
+		contentType: contentType,
+	}, nil
+}
+
+// Stream sends request of the step and returns response with unread body,
+// it is used by commands reading streams over transport, services and auth
+// of request steps
+func (e *Request) Stream(ctx context.Context) (*http.Response, error) {
+	out, err := e.prepare()
+	if err != nil {
+		return nil, e.withService(err)
+	}
+	resp, err := out.client.Do(out.req.WithContext(ctx))
+	if err != nil {
+		return nil, e.withService(err)
+	}
+
+	return resp, nil
+}
+
+// page is a single response with its body already read
+type page struct {
+	resp    *http.Response
+	body    []byte
+	cookies map[string]string
+	phases  map[string]float64
+	start   time.Time
+	finish  time.Time
+}
+
+func (e *Request) send(client *http.Client, req *http.Request) (*page, error) {
+	start := time.Now()
+	trace := &timings{start: start}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.trace()))
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, &transportError{err: err}
 	}
-	reqFinish := time.Now()
-	reqDuration := reqFinish.Sub(reqStart)
+	finish := time.Now()
 	body, err := io.ReadAll(resp.Body)
 	_ = resp.Body.Close()
 	if err != nil {
-		return err
+		return nil, &transportError{err: err}
 	}
-	s := string(body)
-	ss, _ := json.Marshal(resp.Header)
-	r := fmt.Sprintf(`{"body":%v, "status":%v, "header": %s}`, s, resp.StatusCode, ss)
-	if s == "" {
-		r = fmt.Sprintf(`{"body":"", "status":%v, "header": %s}`, resp.StatusCode, ss)
+	trace.set(&trace.done)
+	body, err = decompress(body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, err
 	}
-
-	if e.report != nil {
-		e.report.AddAttachment("response", allure.ApplicationJson, []byte(tools.JSONPrettyPrint(r)))
-		e.report.AddAttachment("meta", allure.TextPlain, []byte(tools.FormatVariables(map[string]string{
-			"start":    reqStart.Format(time.RFC3339Nano),
-			"finish":   reqFinish.Format(time.RFC3339Nano),
-			"duration": reqDuration.Round(time.Millisecond).String(),
-		})))
+	cookies := map[string]string{}
+	for _, v := range resp.Cookies() {
+		cookies[v.Name] = v.Value
 	}
-	e.responseBody = tools.To(r)
 
-	return nil
+	return &page{
+		resp:    resp,
+		body:    body,
+		cookies: cookies,
+		phases:  trace.phases(),
+		start:   start,
+		finish:  finish,
+	}, nil
+}
+
+// Repro returns curl of the request with auth secrets masked
+func (e *Request) Repro() string {
+	return e.mask(e.curl)
 }
 
 func (e *Request) ResponseBody() *string {
@@ -233,12 +438,32 @@ func (e *Request) ResponseBody() *string {
 	return e.responseBody
 }
 
+// Envelope returns full response with status, headers and body, it is
+// used by commands sending requests through request command
+func (e *Request) Envelope() *string {
+	return e.responseBody
+}
+
 func (e *Request) Check() error {
+	return e.withService(e.check())
+}
+
+func (e *Request) check() error {
+	if e.Config.ExpectError != nil {
+		return e.checkExpectedError()
+	}
+	check := e.checkLight
 	if e.mode == modeFull {
-		return e.checkFull()
+		check = e.checkFull
+	}
+	if err := check(); err != nil {
+		return err
+	}
+	if err := e.checkText(); err != nil {
+		return err
 	}
 
-	return e.checkLight()
+	return e.checkHeaders()
 }
 
 func (e *Request) checkLight() error {
@@ -391,18 +616,27 @@ func request(r RequestConfig, b *bytes.Buffer, host string) (*http.Request, erro
 			req.Header.Add(k, v)
 		}
 	}
+	for k, v := range r.CookiesVal {
+		req.AddCookie(&http.Cookie{Name: k, Value: v})
+	}
 	return req, nil
 }
 
-func newCommonRequest(host string, r RequestConfig) (*http.Request, error) {
-	body := []byte(r.RequestTmpl)
+func newCommonRequest(host string, r RequestConfig, body []byte, contentType string) (*http.Request, error) {
 	req, err := request(r, bytes.NewBuffer(body), host)
 	if err != nil {
 		return nil, err
 	}
 
-	if req.Header.Get("Content-Type") == "" {
-		req.Header.Set("Content-Type", "application/json")
+	switch {
+	case strings.HasPrefix(contentType, "multipart/"):
+		// boundary is generated, so content type can't be set in config
+		req.Header.Set("Content-Type", contentType)
+	case req.Header.Get("Content-Type") != "":
+	case contentType != "":
+		req.Header.Set("Content-Type", contentType)
+	default:
+		req.Header.Set("Content-Type", contentTypeJSON)
 	}
 
 	return req, nil
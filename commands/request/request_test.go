@@ -0,0 +1,129 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+func newTestRequest(t *testing.T, u *Unmarshaller, fileName string, cfg *RequestConfig, vv testutil.Vars) *Request {
+	t.Helper()
+	r := &Request{
+		Config:        cfg,
+		Host:          u.host,
+		comparer:      u.comparer,
+		mode:          modeFull,
+		defaultConfig: u.defaultConfig,
+		jars:          u.jars,
+		clients:       u.clients,
+		tokens:        u.tokens,
+	}
+	r.SetVars(vv)
+	r.SetReport(&testutil.Report{})
+	r.SetFileName(fileName)
+
+	return r
+}
+
+func cookieServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			w.Write([]byte(`{}`))
+			return
+		}
+		names := []string{}
+		for _, v := range r.Cookies() {
+			names = append(names, v.Name+"="+v.Value)
+		}
+		w.Write([]byte(`"` + strings.Join(names, ";") + `"`))
+	}))
+}
+
+func TestRequest_Cookies(t *testing.T) {
+	srv := cookieServer()
+	defer srv.Close()
+	u := NewUnmarshaller(srv.URL, nil)
+	vv := testutil.Vars{"theme": "dark"}
+
+	r := newTestRequest(t, u, "a.yaml", &RequestConfig{
+		Method:     "GET",
+		RequestURL: "/me",
+		CookiesVal: map[string]string{"theme": "{{$theme}}"},
+	}, vv)
+	if err := r.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if got := gjson.Get(*r.ResponseBody(), "body").String(); got != "theme=dark" {
+		t.Errorf("expected cookie from config, got %q", got)
+	}
+}
+
+func TestRequest_CookieJar(t *testing.T) {
+	srv := cookieServer()
+	defer srv.Close()
+	u := NewUnmarshaller(srv.URL, nil, OptionDefaultRequestConfig(DefaultConfig{CookieJar: true}))
+	vv := testutil.Vars{}
+
+	login := newTestRequest(t, u, "a.yaml", &RequestConfig{
+		Method:          "POST",
+		RequestURL:      "/login",
+		CookieVariables: map[string]string{"sid": "session"},
+	}, vv)
+	if err := login.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if vv["sid"] != "abc" {
+		t.Errorf("expected variable from cookie, got %v", vv)
+	}
+	if got := gjson.Get(*login.ResponseBody(), "cookies.session").String(); got != "abc" {
+		t.Errorf("expected response cookies, got %v", *login.ResponseBody())
+	}
+
+	sameFile := newTestRequest(t, u, "a.yaml", &RequestConfig{Method: "GET", RequestURL: "/me"}, vv)
+	if err := sameFile.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if got := gjson.Get(*sameFile.ResponseBody(), "body").String(); got != "session=abc" {
+		t.Errorf("expected cookie from file jar, got %q", got)
+	}
+
+	otherFile := newTestRequest(t, u, "b.yaml", &RequestConfig{Method: "GET", RequestURL: "/me"}, vv)
+	if err := otherFile.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if got := gjson.Get(*otherFile.ResponseBody(), "body").String(); got != "" {
+		t.Errorf("expected no cookies in other file, got %q", got)
+	}
+}
+
+func TestRequest_NamedSession(t *testing.T) {
+	srv := cookieServer()
+	defer srv.Close()
+	u := NewUnmarshaller(srv.URL, nil)
+	vv := testutil.Vars{}
+
+	login := newTestRequest(t, u, "a.yaml", &RequestConfig{Method: "POST", RequestURL: "/login", Session: "admin"}, vv)
+	if err := login.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	me := newTestRequest(t, u, "b.yaml", &RequestConfig{Method: "GET", RequestURL: "/me", Session: "admin"}, vv)
+	if err := me.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if got := gjson.Get(*me.ResponseBody(), "body").String(); got != "session=abc" {
+		t.Errorf("expected cookie from named session, got %q", got)
+	}
+	noSession := newTestRequest(t, u, "a.yaml", &RequestConfig{Method: "GET", RequestURL: "/me"}, vv)
+	if err := noSession.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if got := gjson.Get(*noSession.ResponseBody(), "body").String(); got != "" {
+		t.Errorf("expected no cookies without session, got %q", got)
+	}
+}
@@ -0,0 +1,197 @@
+package request
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"github.com/ixpectus/declarate/contract"
+)
+
+const (
+	bodyTypeJSON   = "json"
+	bodyTypeText   = "text"
+	bodyTypeBinary = "binary"
+)
+
+// envelope is a full response, non json body is stored as json string,
+// binary body is encoded with base64
+type envelope struct {
+	Body     json.RawMessage    `json:"body"`
+	Status   int                `json:"status"`
+	Header   http.Header        `json:"header"`
+	Cookies  map[string]string  `json:"cookies"`
+	BodyType string             `json:"bodyType"`
+	Timings  map[string]float64 `json:"timings"`
+}
+
+// String keeps json body as is, json.Marshal would compact it
+func (e envelope) String() string {
+	header, _ := json.Marshal(e.Header)
+	cookies, _ := json.Marshal(e.Cookies)
+	timings, _ := json.Marshal(e.Timings)
+
+	return fmt.Sprintf(
+		`{"body":%s, "status":%d, "header":%s, "cookies":%s, "bodyType":%q, "timings":%s}`,
+		e.Body, e.Status, header, cookies, e.BodyType, timings,
+	)
+}
+
+// decompress decodes body by Content-Encoding, http client decodes gzip
+// itself only when Accept-Encoding is not set explicitly
+func decompress(body []byte, encoding string) ([]byte, error) {
+	var (
+		r   io.Reader
+		err error
+	)
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		// deflate is zlib wrapped by spec, but raw deflate is common too
+		r, err = zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			r, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(body))
+	default:
+		return nil, fmt.Errorf("unsupported content encoding `%s`", encoding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decompress %s body: %w", encoding, err)
+	}
+	res, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("decompress %s body: %w", encoding, err)
+	}
+
+	return res, nil
+}
+
+// encodeBody classifies body by content type, json body is kept as is,
+// text body is stored as json string and binary one as base64 string
+func encodeBody(body []byte, contentType string) (json.RawMessage, string) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return json.RawMessage(`""`), bodyTypeText
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	// body is sniffed when content type is not json, servers often send
+	// json as text/plain
+	if json.Valid(body) && (isJSONMediaType(mediaType) || !isBinaryMediaType(mediaType)) {
+		return json.RawMessage(body), bodyTypeJSON
+	}
+	if !isBinaryMediaType(mediaType) && utf8.Valid(body) {
+		return jsonString(string(body)), bodyTypeText
+	}
+
+	return jsonString(base64.StdEncoding.EncodeToString(body)), bodyTypeBinary
+}
+
+// jsonString marshals string without html escaping
+func jsonString(s string) json.RawMessage {
+	b := &bytes.Buffer{}
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func isBinaryMediaType(mediaType string) bool {
+	switch {
+	case mediaType == "", strings.HasPrefix(mediaType, "text/"), isJSONMediaType(mediaType):
+		return false
+	case strings.HasSuffix(mediaType, "xml"), strings.HasSuffix(mediaType, "javascript"):
+		return false
+	case mediaType == "application/x-www-form-urlencoded":
+		return false
+	}
+
+	return true
+}
+
+// checkText checks body as text, json body is checked by its raw text
+func (e *Request) checkText() error {
+	if e.Config.ResponseText == nil && len(e.Config.ResponseContains) == 0 && e.Config.ResponseRegexp == "" {
+		return nil
+	}
+	body := e.responseText()
+	if e.Config.ResponseText != nil {
+		expected := strings.TrimSuffix(e.Vars.Apply(*e.Config.ResponseText), "\n")
+		errs := e.comparer.Compare(expected, body, e.Config.ComparisonParams)
+		if len(errs) > 0 {
+			return textError(expected, body, errs)
+		}
+	}
+	var errs []error
+	for _, v := range e.Config.ResponseContains {
+		v = e.Vars.Apply(v)
+		if !strings.Contains(body, v) {
+			errs = append(errs, fmt.Errorf("response does not contain `%s`", v))
+		}
+	}
+	if e.Config.ResponseRegexp != "" {
+		expr := e.Vars.Apply(e.Config.ResponseRegexp)
+		rx, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("compile response regexp: %w", err)
+		}
+		if !rx.MatchString(body) {
+			errs = append(errs, fmt.Errorf("response does not match regexp `%s`", expr))
+		}
+	}
+	if len(errs) > 0 {
+		expected := append(append([]string{}, e.Config.ResponseContains...), e.Config.ResponseRegexp)
+		return textError(strings.TrimSpace(strings.Join(expected, "\n")), body, errs)
+	}
+
+	return nil
+}
+
+func (e *Request) responseText() string {
+	var env envelope
+	if e.responseBody == nil || json.Unmarshal([]byte(*e.responseBody), &env) != nil {
+		return ""
+	}
+	if env.BodyType == bodyTypeJSON {
+		return string(env.Body)
+	}
+	var res string
+	_ = json.Unmarshal(env.Body, &res)
+
+	return res
+}
+
+func textError(expected, actual string, errs []error) error {
+	msgs := make([]string, 0, len(errs))
+	for _, v := range errs {
+		msgs = append(msgs, v.Error())
+	}
+	msg := strings.Join(msgs, "\n")
+
+	return &contract.TestError{
+		Title:         "response text differs",
+		Expected:      expected,
+		Actual:        actual,
+		Message:       msg,
+		OriginalError: fmt.Errorf("response text differs: %v", msg),
+	}
+}
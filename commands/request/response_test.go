@@ -0,0 +1,140 @@
+package request
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/eval"
+	"github.com/ixpectus/declarate/internal/testutil"
+	"github.com/ixpectus/declarate/tools"
+	"github.com/ixpectus/declarate/variables"
+	"github.com/tidwall/gjson"
+)
+
+func compressed(encoding string, data []byte) []byte {
+	b := &bytes.Buffer{}
+	switch encoding {
+	case "gzip":
+		w := gzip.NewWriter(b)
+		w.Write(data)
+		w.Close()
+	case "deflate":
+		w := zlib.NewWriter(b)
+		w.Write(data)
+		w.Close()
+	case "br":
+		w := brotli.NewWriter(b)
+		w.Write(data)
+		w.Close()
+	}
+
+	return b.Bytes()
+}
+
+func TestRequest_NonJSONResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html>\n<h1>\"Rex\"</h1>\n</html>"))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G', 0xff})
+		case "/sniffed":
+			w.Write([]byte(`{"name": "Rex"}`))
+		default:
+			encoding := strings.TrimPrefix(r.URL.Path, "/")
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", encoding)
+			w.Write(compressed(encoding, []byte(`{"name": "Rex"}`)))
+		}
+	}))
+	defer srv.Close()
+	u := NewUnmarshaller(srv.URL, nil)
+
+	tests := []struct {
+		path     string
+		bodyType string
+		body     string
+	}{
+		{path: "/html", bodyType: bodyTypeText, body: `"<html>\n<h1>\"Rex\"</h1>\n</html>"`},
+		{path: "/image", bodyType: bodyTypeBinary, body: `"iVBOR/8="`},
+		{path: "/sniffed", bodyType: bodyTypeJSON, body: `{"name": "Rex"}`},
+		{path: "/gzip", bodyType: bodyTypeJSON, body: `{"name": "Rex"}`},
+		{path: "/deflate", bodyType: bodyTypeJSON, body: `{"name": "Rex"}`},
+		{path: "/br", bodyType: bodyTypeJSON, body: `{"name": "Rex"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			r := newTestRequest(t, u, "a.yaml", &RequestConfig{
+				Method:     "GET",
+				RequestURL: tt.path,
+				HeadersVal: map[string]string{"Accept-Encoding": "gzip, deflate, br"},
+			}, testutil.Vars{})
+			if err := r.Do(); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if !json.Valid([]byte(*r.ResponseBody())) {
+				t.Fatalf("invalid response json %s", *r.ResponseBody())
+			}
+			res := gjson.Parse(*r.ResponseBody())
+			if got := res.Get("bodyType").String(); got != tt.bodyType {
+				t.Errorf("expected body type %s, got %s", tt.bodyType, got)
+			}
+			if got := res.Get("body").Raw; got != tt.body {
+				t.Errorf("expected body %s, got %s", tt.body, got)
+			}
+		})
+	}
+}
+
+func TestRequest_CheckText(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("name: Rex\nage: 3"))
+	}))
+	defer srv.Close()
+	vv := variables.New(eval.NewEval(nil), nil, false)
+	u := NewUnmarshaller(srv.URL, compare.New(contract.CompareParams{}, vv))
+
+	tests := []struct {
+		name   string
+		cfg    RequestConfig
+		errMsg string
+	}{
+		{name: "exact", cfg: RequestConfig{ResponseText: tools.To("name: Rex\nage: 3\n")}},
+		{name: "line by line", cfg: RequestConfig{ResponseText: tools.To("name: Rex\nage: 4")}, errMsg: "age: 4"},
+		{name: "contains", cfg: RequestConfig{ResponseContains: []string{"Rex", "age"}}},
+		{name: "not contains", cfg: RequestConfig{ResponseContains: []string{"Tom"}}, errMsg: "does not contain `Tom`"},
+		{name: "regexp", cfg: RequestConfig{ResponseRegexp: `age: \d+$`}},
+		{name: "regexp differs", cfg: RequestConfig{ResponseRegexp: `^age`}, errMsg: "does not match"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Method = "GET"
+			cfg.RequestURL = "/"
+			r := newTestRequest(t, u, "a.yaml", &cfg, testutil.Vars{})
+			r.mode = modeLight
+			if err := r.Do(); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			err := r.Check()
+			if tt.errMsg == "" && err != nil {
+				t.Errorf("Check() error = %v", err)
+			}
+			if tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)) {
+				t.Errorf("expected error with %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ixpectus/declarate/contract"
+)
+
+// ServiceConfig describes named service selected by `service` field of the
+// step, its headers, auth and transport override default ones
+type ServiceConfig struct {
+	Host       string            `json:"host" yaml:"host"`
+	HeadersVal map[string]string `json:"headers" yaml:"headers"`
+	Auth       *AuthConfig       `json:"auth" yaml:"auth"`
+	Transport  *TransportConfig  `json:"transport" yaml:"transport"`
+}
+
+func OptionServices(services map[string]ServiceConfig) Option {
+	return func(c *Unmarshaller) {
+		c.services = services
+	}
+}
+
+// withService adds service name to errors, so it is visible in outputs
+// and reports
+func (e *Request) withService(err error) error {
+	if err == nil || e.Config.Service == "" {
+		return err
+	}
+	var testErr *contract.TestError
+	if errors.As(err, &testErr) {
+		res := *testErr
+		res.Title = fmt.Sprintf("service %s: %s", e.Config.Service, testErr.Title)
+		return &res
+	}
+
+	return fmt.Errorf("service %s: %w", e.Config.Service, err)
+}
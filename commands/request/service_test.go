@@ -0,0 +1,97 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/eval"
+	"github.com/ixpectus/declarate/internal/testutil"
+	"github.com/ixpectus/declarate/tools"
+	"github.com/ixpectus/declarate/variables"
+	"github.com/tidwall/gjson"
+)
+
+func TestRequest_Services(t *testing.T) {
+	srv := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"service": "` + name + `", "auth": "` + r.Header.Get("Authorization") +
+				`", "tenant": "` + r.Header.Get("X-Tenant") + `"}`))
+		}))
+	}
+	main, billing := srv("main"), srv("billing")
+	defer main.Close()
+	defer billing.Close()
+	vv := variables.New(eval.NewEval(nil), nil, false)
+	u := NewUnmarshaller(
+		main.URL,
+		compare.New(contract.CompareParams{}, vv),
+		OptionDefaultRequestConfig(DefaultConfig{
+			HeadersVal: map[string]string{"X-Tenant": "default"},
+			Auth:       &AuthConfig{Bearer: "main-token"},
+		}),
+		OptionServices(map[string]ServiceConfig{
+			"billing": {
+				Host:       billing.URL,
+				HeadersVal: map[string]string{"X-Tenant": "billing"},
+				Auth:       &AuthConfig{Bearer: "billing-token"},
+			},
+		}),
+	)
+
+	build := func(yaml map[string]any) *Request {
+		t.Helper()
+		doer, err := u.Build(func(v any) error {
+			cfg := v.(*RequestConfig)
+			cfg.Method = "GET"
+			cfg.RequestURL = "/"
+			if s, ok := yaml["service"]; ok {
+				cfg.Service = s.(string)
+			}
+			if r, ok := yaml["response"]; ok {
+				cfg.Response = tools.To(r.(string))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+		r := doer.(*Request)
+		r.SetVars(testutil.Vars{})
+		r.SetReport(&testutil.Report{})
+		return r
+	}
+
+	r := build(map[string]any{"service": "billing"})
+	if err := r.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if got := *r.ResponseBody(); got != `{"service": "billing", "auth": "Bearer billing-token", "tenant": "billing"}` {
+		t.Errorf("unexpected billing response %s", got)
+	}
+
+	r = build(map[string]any{})
+	if err := r.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if got := gjson.Get(*r.ResponseBody(), "service").String(); got != "main" {
+		t.Errorf("expected default host, got %s", *r.ResponseBody())
+	}
+
+	r = build(map[string]any{"service": "billing", "response": `{"service": "main"}`})
+	if err := r.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	err := r.Check()
+	testErr, ok := err.(*contract.TestError)
+	if !ok || !strings.HasPrefix(testErr.Title, "service billing: ") {
+		t.Errorf("expected error with service name, got %#v", err)
+	}
+
+	if err := build(map[string]any{"service": "payments"}).IsValid(); err == nil {
+		t.Errorf("expected unknown service error")
+	}
+}
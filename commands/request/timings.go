@@ -0,0 +1,105 @@
+package request
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dailymotion/allure-go"
+	"github.com/ixpectus/declarate/tools"
+)
+
+// timings collects http phases, phases of reused connection are zero,
+// callbacks are called concurrently when addresses of several families are
+// dialed, so fields are guarded by mutex
+type timings struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	done         time.Time
+}
+
+// set stores current time to the field of timings
+func (t *timings) set(field *time.Time) {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*field = now
+}
+
+func (t *timings) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.set(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.set(&t.dnsDone) },
+		ConnectStart: func(string, string) {
+			now := time.Now()
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = now
+			}
+		},
+		ConnectDone:          func(string, string, error) { t.set(&t.connectDone) },
+		TLSHandshakeStart:    func() { t.set(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.set(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.set(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.set(&t.firstByte) },
+	}
+}
+
+// phases returns phases durations in milliseconds, ttfb is time between
+// writing request and the first response byte
+func (t *timings) phases() map[string]float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return map[string]float64{
+		"dns":      milliseconds(t.dnsStart, t.dnsDone),
+		"connect":  milliseconds(t.connectStart, t.connectDone),
+		"tls":      milliseconds(t.tlsStart, t.tlsDone),
+		"ttfb":     milliseconds(t.wroteRequest, t.firstByte),
+		"transfer": milliseconds(t.firstByte, t.done),
+		"total":    milliseconds(t.start, t.done),
+	}
+}
+
+func milliseconds(start, finish time.Time) float64 {
+	if start.IsZero() || finish.IsZero() || finish.Before(start) {
+		return 0
+	}
+	return float64(finish.Sub(start).Microseconds()) / 1000
+}
+
+func (e *Request) reportTimings(phases map[string]float64) {
+	if e.report == nil {
+		return
+	}
+	res := map[string]string{}
+	for k, v := range phases {
+		res[k] = strconv.FormatFloat(v, 'f', 3, 64) + "ms"
+	}
+	e.report.AddAttachment("timings", allure.TextPlain, []byte(tools.FormatVariables(res)))
+}
+
+func (e *Request) setTimingVariables(phases map[string]float64) error {
+	for k, phase := range e.Config.TimingVariables {
+		v, ok := phases[phase]
+		if !ok {
+			return fmt.Errorf("unknown timing %s for variable %s", phase, k)
+		}
+		if err := e.Vars.Set(k, strconv.FormatFloat(v, 'f', 3, 64)); err != nil {
+			return fmt.Errorf("set variable %s from timing: %w", k, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package request
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+func TestRequest_Timings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	u := NewUnmarshaller(srv.URL, nil)
+	vv := testutil.Vars{}
+
+	r := newTestRequest(t, u, "a.yaml", &RequestConfig{
+		Method:          "GET",
+		RequestURL:      "/",
+		TimingVariables: map[string]string{"wait": "ttfb", "took": "total"},
+	}, vv)
+	report := &testutil.Report{}
+	r.SetReport(report)
+	if err := r.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	timings := gjson.Get(*r.ResponseBody(), "timings")
+	for _, phase := range []string{"dns", "connect", "tls", "ttfb", "transfer", "total"} {
+		if !timings.Get(phase).Exists() {
+			t.Errorf("expected %s timing in %s", phase, timings.Raw)
+		}
+	}
+	if timings.Get("connect").Float() <= 0 || timings.Get("ttfb").Float() < 20 {
+		t.Errorf("unexpected timings %s", timings.Raw)
+	}
+	wait, _ := strconv.ParseFloat(vv["wait"], 64)
+	took, _ := strconv.ParseFloat(vv["took"], 64)
+	if wait < 20 || took < wait {
+		t.Errorf("unexpected timing variables %v", vv)
+	}
+	if report.Attachment("timings") == "" {
+		t.Errorf("expected timings attachment")
+	}
+
+	r = newTestRequest(t, u, "a.yaml", &RequestConfig{
+		Method:          "GET",
+		RequestURL:      "/",
+		TimingVariables: map[string]string{"wait": "latency"},
+	}, vv)
+	if err := r.Do(); err == nil {
+		t.Errorf("expected unknown timing error")
+	}
+}
+
+func TestRequest_TimingsDualStack(t *testing.T) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(context.Background(), "localhost")
+	if err != nil {
+		t.Skipf("resolve localhost: %v", err)
+	}
+	var v4, v6 bool
+	for _, v := range addrs {
+		if v.IP.To4() != nil {
+			v4 = true
+		} else {
+			v6 = true
+		}
+	}
+	if !v4 || !v6 {
+		t.Skipf("localhost has no both ipv4 and ipv6 addresses: %v", addrs)
+	}
+	// server listens on ipv4 only, so both families are dialed
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	srv.Listener = l
+	srv.Start()
+	defer srv.Close()
+	u := NewUnmarshaller("http://localhost:"+strconv.Itoa(l.Addr().(*net.TCPAddr).Port), nil)
+
+	r := newTestRequest(t, u, "a.yaml", &RequestConfig{Method: "GET", RequestURL: "/"}, testutil.Vars{})
+	if err := r.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if timings := gjson.Get(*r.ResponseBody(), "timings"); timings.Get("connect").Float() <= 0 {
+		t.Errorf("unexpected timings %s", timings.Raw)
+	}
+}
+
+func TestTimings_ConcurrentCallbacks(t *testing.T) {
+	tm := &timings{start: time.Now()}
+	trace := tm.trace()
+	var wg sync.WaitGroup
+	for _, network := range []string{"tcp4", "tcp6"} {
+		wg.Add(1)
+		go func(network string) {
+			defer wg.Done()
+			trace.ConnectStart(network, "localhost:80")
+			trace.ConnectDone(network, "localhost:80", nil)
+		}(network)
+	}
+	wg.Wait()
+	tm.set(&tm.done)
+	if tm.phases()["connect"] < 0 {
+		t.Errorf("unexpected phases %v", tm.phases())
+	}
+}
@@ -0,0 +1,228 @@
+package request
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	redirectFollow = "follow"
+	redirectNone   = "none"
+	proxyNone      = "none"
+)
+
+// TransportConfig describes http client used for requests, empty fields of
+// step config are taken from the default one
+type TransportConfig struct {
+	ConnectTimeout     time.Duration `json:"connectTimeout" yaml:"connectTimeout"`
+	Timeout            time.Duration `json:"timeout" yaml:"timeout"`
+	CACert             string        `json:"caCert" yaml:"caCert"`
+	ClientCert         string        `json:"clientCert" yaml:"clientCert"`
+	ClientKey          string        `json:"clientKey" yaml:"clientKey"`
+	InsecureSkipVerify *bool         `json:"insecureSkipVerify" yaml:"insecureSkipVerify"`
+	// Proxy is proxy url, proxy from environment is used when empty,
+	// `none` disables proxy
+	Proxy string `json:"proxy" yaml:"proxy"`
+	// Redirects is redirect policy, `follow`, `none` or max redirects count
+	Redirects string `json:"redirects" yaml:"redirects"`
+	KeepAlive *bool  `json:"keepAlive" yaml:"keepAlive"`
+}
+
+func (t TransportConfig) merge(step *TransportConfig) TransportConfig {
+	if step == nil {
+		return t
+	}
+	if step.ConnectTimeout != 0 {
+		t.ConnectTimeout = step.ConnectTimeout
+	}
+	if step.Timeout != 0 {
+		t.Timeout = step.Timeout
+	}
+	if step.CACert != "" {
+		t.CACert = step.CACert
+	}
+	if step.ClientCert != "" {
+		t.ClientCert = step.ClientCert
+	}
+	if step.ClientKey != "" {
+		t.ClientKey = step.ClientKey
+	}
+	if step.InsecureSkipVerify != nil {
+		t.InsecureSkipVerify = step.InsecureSkipVerify
+	}
+	if step.Proxy != "" {
+		t.Proxy = step.Proxy
+	}
+	if step.Redirects != "" {
+		t.Redirects = step.Redirects
+	}
+	if step.KeepAlive != nil {
+		t.KeepAlive = step.KeepAlive
+	}
+
+	return t
+}
+
+// key identifies transport config, pointers are replaced by values
+func (t TransportConfig) key() string {
+	insecure := t.InsecureSkipVerify != nil && *t.InsecureSkipVerify
+	keepAlive := t.KeepAlive == nil || *t.KeepAlive
+	t.InsecureSkipVerify = nil
+	t.KeepAlive = nil
+
+	return fmt.Sprintf("%+v %v %v", t, insecure, keepAlive)
+}
+
+// clients stores http clients by transport config, so connections are
+// pooled between requests with the same config
+type clients struct {
+	mu      sync.Mutex
+	clients map[string]*http.Client
+}
+
+func newClients() *clients {
+	return &clients{
+		clients: map[string]*http.Client{},
+	}
+}
+
+func (c *clients) get(cfg TransportConfig) (*http.Client, error) {
+	key := cfg.key()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.clients[key]; ok {
+		return client, nil
+	}
+	client, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.clients[key] = client
+
+	return client, nil
+}
+
+func newClient(cfg TransportConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.ConnectTimeout > 0 {
+		dialer := &net.Dialer{
+			Timeout:   cfg.ConnectTimeout,
+			KeepAlive: 30 * time.Second,
+		}
+		transport.DialContext = dialer.DialContext
+		transport.TLSHandshakeTimeout = cfg.ConnectTimeout
+	}
+	if cfg.KeepAlive != nil && !*cfg.KeepAlive {
+		transport.DisableKeepAlives = true
+	}
+	switch cfg.Proxy {
+	case "":
+	case proxyNone:
+		transport.Proxy = nil
+	default:
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+	checkRedirect, err := redirectPolicy(cfg.Redirects)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport:     transport,
+		Timeout:       cfg.Timeout,
+		CheckRedirect: checkRedirect,
+	}, nil
+}
+
+func newTLSConfig(cfg TransportConfig) (*tls.Config, error) {
+	res := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify != nil && *cfg.InsecureSkipVerify, //nolint:gosec
+	}
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("read ca cert: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CACert)
+		}
+		res.RootCAs = pool
+	}
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("load client cert: %w", err)
+		}
+		res.Certificates = []tls.Certificate{cert}
+	}
+
+	return res, nil
+}
+
+func redirectPolicy(redirects string) (func(req *http.Request, via []*http.Request) error, error) {
+	switch redirects {
+	case "", redirectFollow:
+		return nil, nil
+	case redirectNone:
+		return func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}, nil
+	}
+	limit, err := strconv.Atoi(redirects)
+	if err != nil || limit < 0 {
+		return nil, fmt.Errorf("unknown redirects policy `%s`, use follow, none or max redirects count", redirects)
+	}
+
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > limit {
+			return fmt.Errorf("stopped after %d redirects", limit)
+		}
+		return nil
+	}, nil
+}
+
+// client returns shared client for step transport config, cookie jar is
+// set on the copy of the client, so transport is still shared
+func (e *Request) client() (*http.Client, error) {
+	cfg := e.defaultConfig.Transport
+	if e.service != nil {
+		cfg = cfg.merge(e.service.Transport)
+	}
+	cfg = cfg.merge(e.Config.Transport)
+	cfg.CACert = e.Vars.Apply(cfg.CACert)
+	cfg.ClientCert = e.Vars.Apply(cfg.ClientCert)
+	cfg.ClientKey = e.Vars.Apply(cfg.ClientKey)
+	cfg.Proxy = e.Vars.Apply(cfg.Proxy)
+	if e.clients == nil {
+		e.clients = newClients()
+	}
+	client, err := e.clients.get(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("make http client: %w", err)
+	}
+	if jar := e.jar(); jar != nil {
+		c := *client
+		c.Jar = jar
+		return &c, nil
+	}
+
+	return client, nil
+}
@@ -0,0 +1,120 @@
+package request
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ixpectus/declarate/internal/testutil"
+	"github.com/ixpectus/declarate/tools"
+	"github.com/tidwall/gjson"
+)
+
+func TestRequest_TransportTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer srv.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	u := NewUnmarshaller(srv.URL, nil)
+
+	r := newTestRequest(t, u, "a.yaml", &RequestConfig{Method: "GET", RequestURL: "/"}, testutil.Vars{})
+	if err := r.Do(); err == nil {
+		t.Errorf("expected unknown authority error")
+	}
+
+	for name, transport := range map[string]*TransportConfig{
+		"ca cert":  {CACert: caFile},
+		"insecure": {InsecureSkipVerify: tools.To(true)},
+	} {
+		r := newTestRequest(t, u, "a.yaml", &RequestConfig{Method: "GET", RequestURL: "/", Transport: transport}, testutil.Vars{})
+		if err := r.Do(); err != nil {
+			t.Errorf("%s: Do() error = %v", name, err)
+		}
+	}
+}
+
+func TestRequest_TransportRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	u := NewUnmarshaller(srv.URL, nil, OptionDefaultRequestConfig(DefaultConfig{
+		Transport: TransportConfig{Redirects: redirectNone},
+	}))
+
+	r := newTestRequest(t, u, "a.yaml", &RequestConfig{Method: "GET", RequestURL: "/old"}, testutil.Vars{})
+	if err := r.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if status := gjson.Get(*r.ResponseBody(), "status").Int(); status != http.StatusFound {
+		t.Errorf("expected redirect response, got %d", status)
+	}
+
+	r = newTestRequest(t, u, "a.yaml", &RequestConfig{
+		Method:     "GET",
+		RequestURL: "/old",
+		Transport:  &TransportConfig{Redirects: "1"},
+	}, testutil.Vars{})
+	if err := r.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if status := gjson.Get(*r.ResponseBody(), "status").Int(); status != http.StatusOK {
+		t.Errorf("expected followed redirect, got %d", status)
+	}
+
+	r = newTestRequest(t, u, "a.yaml", &RequestConfig{
+		Method:     "GET",
+		RequestURL: "/old",
+		Transport:  &TransportConfig{Redirects: "sometimes"},
+	}, testutil.Vars{})
+	if err := r.Do(); err == nil {
+		t.Errorf("expected unknown redirects policy error")
+	}
+}
+
+func TestRequest_TransportTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	u := NewUnmarshaller(srv.URL, nil)
+
+	r := newTestRequest(t, u, "a.yaml", &RequestConfig{
+		Method:     "GET",
+		RequestURL: "/",
+		Transport:  &TransportConfig{Timeout: 10 * time.Millisecond},
+	}, testutil.Vars{})
+	if err := r.Do(); err == nil {
+		t.Errorf("expected timeout error")
+	}
+}
+
+func TestClients_Shared(t *testing.T) {
+	c := newClients()
+	a, err := c.get(TransportConfig{Timeout: time.Second, KeepAlive: tools.To(true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := c.get(TransportConfig{Timeout: time.Second})
+	if a != b {
+		t.Errorf("expected shared client for the same config")
+	}
+	other, _ := c.get(TransportConfig{Timeout: 2 * time.Second})
+	if a == other {
+		t.Errorf("expected new client for other config")
+	}
+}
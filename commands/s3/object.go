@@ -0,0 +1,100 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// object is s3 step response, missing object has only key
+type object struct {
+	Key          string            `json:"key"`
+	Exists       bool              `json:"exists"`
+	Size         int64             `json:"size"`
+	ETag         string            `json:"etag,omitempty"`
+	ContentType  string            `json:"contentType,omitempty"`
+	LastModified string            `json:"lastModified,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	Checksums    *objectChecksums  `json:"checksums,omitempty"`
+	Content      json.RawMessage   `json:"content,omitempty"`
+}
+
+type objectChecksums struct {
+	MD5    string `json:"md5"`
+	SHA256 string `json:"sha256"`
+}
+
+func newObject(info minio.ObjectInfo) *object {
+	metadata := map[string]string{}
+	for k, v := range info.UserMetadata {
+		metadata[strings.ToLower(k)] = v
+	}
+
+	return &object{
+		Key:          info.Key,
+		Exists:       true,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		ContentType:  info.ContentType,
+		LastModified: info.LastModified.UTC().Format(time.RFC3339),
+		Metadata:     metadata,
+	}
+}
+
+func checksums(data []byte) *objectChecksums {
+	md5Sum := md5.Sum(data)
+	sha256Sum := sha256.Sum256(data)
+
+	return &objectChecksums{
+		MD5:    hex.EncodeToString(md5Sum[:]),
+		SHA256: hex.EncodeToString(sha256Sum[:]),
+	}
+}
+
+// content renders json content as is, csv content as array of objects by
+// header row and other content as json string
+func content(key, contentType string, data []byte) json.RawMessage {
+	if json.Valid(data) {
+		return data
+	}
+	if isCSV(key, contentType) {
+		if rows, ok := parseCSV(data); ok {
+			b, _ := json.Marshal(rows)
+			return b
+		}
+	}
+	b, _ := json.Marshal(string(data))
+
+	return b
+}
+
+func isCSV(key, contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/csv" || strings.HasSuffix(strings.ToLower(key), ".csv")
+}
+
+func parseCSV(data []byte) ([]map[string]string, bool) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil || len(records) == 0 {
+		return nil, false
+	}
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, v := range record {
+			row[header[i]] = v
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, true
+}
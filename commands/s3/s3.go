@@ -0,0 +1,378 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dailymotion/allure-go"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/tools"
+	"github.com/ixpectus/declarate/variables"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// ConnConfig is s3 endpoint with credentials, buckets are addressed by path
+type ConnConfig struct {
+	// Endpoint is url like http://127.0.0.1:9000, https enables tls
+	Endpoint  string `json:"endpoint" yaml:"endpoint"`
+	Region    string `json:"region" yaml:"region"`
+	AccessKey string `json:"accessKey" yaml:"accessKey"`
+	SecretKey string `json:"secretKey" yaml:"secretKey"`
+}
+
+type S3 struct {
+	Config       *Config
+	Vars         contract.Vars
+	Report       contract.ReportAttachement
+	comparer     contract.Comparer
+	clients      *clients
+	defaultConn  ConnConfig
+	fileName     string
+	responseBody *string
+}
+
+type Unmarshaller struct {
+	conn     ConnConfig
+	comparer contract.Comparer
+	clients  *clients
+}
+
+// NewUnmarshaller makes s3 command builder, conn is used by steps without
+// endpoint
+func NewUnmarshaller(conn ConnConfig, comparer contract.Comparer) *Unmarshaller {
+	return &Unmarshaller{
+		conn:     conn,
+		comparer: comparer,
+		clients:  &clients{clients: map[ConnConfig]*minio.Client{}},
+	}
+}
+
+type Check struct {
+	S3 *Config `yaml:"s3,omitempty"`
+}
+
+type Config struct {
+	// ConnConfig fields override default connection
+	ConnConfig `yaml:",inline"`
+	Bucket     string     `json:"bucket" yaml:"bucket"`
+	Put        *PutConfig `json:"put" yaml:"put"`
+	// Get is key of object to download
+	Get *string `json:"get" yaml:"get"`
+	// Head is key of object to read metadata
+	Head *string `json:"head" yaml:"head"`
+	// List is prefix of listed keys
+	List *string `json:"list" yaml:"list"`
+	// Delete is key of object to delete
+	Delete           *string                `json:"delete" yaml:"delete"`
+	Response         *string                `json:"response" yaml:"response"`
+	ComparisonParams contract.CompareParams `json:"comparisonParams" yaml:"comparisonParams"`
+}
+
+type PutConfig struct {
+	Key string `json:"key" yaml:"key"`
+	// Body is object content, variables are applied
+	Body *string `json:"body" yaml:"body"`
+	// File is path to object content relative to the test file
+	File        string            `json:"file" yaml:"file"`
+	ContentType string            `json:"contentType" yaml:"contentType"`
+	Metadata    map[string]string `json:"metadata" yaml:"metadata"`
+}
+
+func (u *Unmarshaller) Build(unmarshal func(any) error) (contract.Doer, error) {
+	cfg := &Check{}
+	if err := unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("unmarshall s3: %w", err)
+	}
+	if cfg.S3 == nil {
+		return nil, nil
+	}
+
+	return &S3{
+		Config:      cfg.S3,
+		comparer:    u.comparer,
+		clients:     u.clients,
+		defaultConn: u.conn,
+	}, nil
+}
+
+func (e *S3) SetVars(vv contract.Vars) {
+	e.Vars = vv
+}
+
+func (e *S3) SetReport(r contract.ReportAttachement) {
+	e.Report = r
+}
+
+func (e *S3) SetFileName(fileName string) {
+	e.fileName = fileName
+}
+
+func (e *S3) GetConfig() any {
+	return e.Config
+}
+
+func (e *S3) IsValid() error {
+	operations := 0
+	if e.Config.Put != nil {
+		operations++
+	}
+	for _, v := range []*string{e.Config.Get, e.Config.Head, e.Config.List, e.Config.Delete} {
+		if v != nil {
+			operations++
+		}
+	}
+	switch {
+	case operations != 1:
+		return fmt.Errorf("s3 step requires one of put, get, head, list or delete")
+	case e.Config.Bucket == "":
+		return fmt.Errorf("s3 bucket is empty")
+	case e.Config.Endpoint == "" && e.defaultConn.Endpoint == "":
+		return fmt.Errorf("s3 endpoint is empty and default endpoint is not set")
+	}
+	if p := e.Config.Put; p != nil {
+		if p.Key == "" {
+			return fmt.Errorf("s3 put key is empty")
+		}
+		if (p.Body == nil) == (p.File == "") {
+			return fmt.Errorf("s3 put requires one of body or file")
+		}
+	}
+	if e.Config.Response != nil {
+		c := variables.VariableRx.ReplaceAllString(*e.Config.Response, "2")
+		if !json.Valid([]byte(c)) {
+			return fmt.Errorf("cannot parse s3 response: `%v`", *e.Config.Response)
+		}
+	}
+
+	return nil
+}
+
+func (e *S3) conn() ConnConfig {
+	if e.Config.Endpoint == "" {
+		return e.defaultConn
+	}
+	return ConnConfig{
+		Endpoint:  e.Vars.Apply(e.Config.Endpoint),
+		Region:    e.Vars.Apply(e.Config.Region),
+		AccessKey: e.Vars.Apply(e.Config.AccessKey),
+		SecretKey: e.Vars.Apply(e.Config.SecretKey),
+	}
+}
+
+func (e *S3) Do() error {
+	e.responseBody = nil
+	client, err := e.clients.get(e.conn())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	bucket := e.Vars.Apply(e.Config.Bucket)
+	var res any
+	switch {
+	case e.Config.Put != nil:
+		res, err = e.put(ctx, client, bucket)
+	case e.Config.Get != nil:
+		res, err = get(ctx, client, bucket, e.Vars.Apply(*e.Config.Get))
+	case e.Config.Head != nil:
+		res, err = head(ctx, client, bucket, e.Vars.Apply(*e.Config.Head))
+	case e.Config.List != nil:
+		res, err = list(ctx, client, bucket, e.Vars.Apply(*e.Config.List))
+	default:
+		key := e.Vars.Apply(*e.Config.Delete)
+		if err := client.RemoveObject(ctx, bucket, key, minio.RemoveObjectOptions{}); err != nil {
+			return fmt.Errorf("delete %s/%s: %w", bucket, key, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		return fmt.Errorf("marshall s3 response: %w", err)
+	}
+	body := string(b)
+	e.responseBody = &body
+	if e.Report != nil {
+		e.Report.AddAttachment("response", allure.ApplicationJson, []byte(tools.JSONPrettyPrint(body)))
+	}
+
+	return nil
+}
+
+func (e *S3) put(ctx context.Context, client *minio.Client, bucket string) (*object, error) {
+	cfg := e.Config.Put
+	key := e.Vars.Apply(cfg.Key)
+	contentType := e.Vars.Apply(cfg.ContentType)
+	var data []byte
+	if cfg.Body != nil {
+		data = []byte(e.Vars.Apply(*cfg.Body))
+	} else {
+		path := e.filePath(cfg.File)
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read s3 object file: %w", err)
+		}
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(path))
+		}
+	}
+	metadata := map[string]string{}
+	for k, v := range cfg.Metadata {
+		metadata[e.Vars.Apply(k)] = e.Vars.Apply(v)
+	}
+	info, err := client.PutObject(ctx, bucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType:  contentType,
+		UserMetadata: metadata,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("put %s/%s: %w", bucket, key, err)
+	}
+
+	return &object{
+		Key:       key,
+		Exists:    true,
+		Size:      int64(len(data)),
+		ETag:      info.ETag,
+		Checksums: checksums(data),
+	}, nil
+}
+
+// filePath makes path relative to the test file
+func (e *S3) filePath(path string) string {
+	path = e.Vars.Apply(path)
+	if filepath.IsAbs(path) || e.fileName == "" {
+		return path
+	}
+
+	return filepath.Join(filepath.Dir(e.fileName), path)
+}
+
+func get(ctx context.Context, client *minio.Client, bucket, key string) (*object, error) {
+	obj, err := client.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get %s/%s: %w", bucket, key, err)
+	}
+	defer obj.Close()
+	info, err := obj.Stat()
+	if notFound(err) {
+		return &object{Key: key}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get %s/%s: %w", bucket, key, err)
+	}
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, fmt.Errorf("read %s/%s: %w", bucket, key, err)
+	}
+	res := newObject(info)
+	res.Checksums = checksums(data)
+	res.Content = content(key, info.ContentType, data)
+
+	return res, nil
+}
+
+func head(ctx context.Context, client *minio.Client, bucket, key string) (*object, error) {
+	info, err := client.StatObject(ctx, bucket, key, minio.StatObjectOptions{})
+	if notFound(err) {
+		return &object{Key: key}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("head %s/%s: %w", bucket, key, err)
+	}
+
+	return newObject(info), nil
+}
+
+func list(ctx context.Context, client *minio.Client, bucket, prefix string) ([]*object, error) {
+	res := []*object{}
+	for info := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	}) {
+		if info.Err != nil {
+			return nil, fmt.Errorf("list %s/%s: %w", bucket, prefix, info.Err)
+		}
+		res = append(res, &object{
+			Key:          info.Key,
+			Exists:       true,
+			Size:         info.Size,
+			ETag:         info.ETag,
+			LastModified: info.LastModified.UTC().Format(time.RFC3339),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key < res[j].Key
+	})
+
+	return res, nil
+}
+
+func notFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	code := minio.ToErrorResponse(err).Code
+	return code == "NoSuchKey" || code == "NotFound"
+}
+
+// ResponseBody returns object, list of objects or put result
+func (e *S3) ResponseBody() *string {
+	return e.responseBody
+}
+
+func (e *S3) Check() error {
+	if e.Config.Response == nil || e.responseBody == nil {
+		return nil
+	}
+	expected := strings.TrimSuffix(e.Vars.Apply(*e.Config.Response), "\n")
+
+	return tools.CompareJSON(e.comparer, "s3 response differs", expected, *e.responseBody, e.Config.ComparisonParams)
+}
+
+// clients stores clients by connection config
+type clients struct {
+	mu      sync.Mutex
+	clients map[ConnConfig]*minio.Client
+}
+
+func (c *clients) get(conn ConnConfig) (*minio.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.clients[conn]; ok {
+		return client, nil
+	}
+	u, err := url.Parse(conn.Endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint `%s`, url with scheme and host expected", conn.Endpoint)
+	}
+	client, err := minio.New(u.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4(conn.AccessKey, conn.SecretKey, ""),
+		Secure:       u.Scheme == "https",
+		Region:       conn.Region,
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("make s3 client: %w", err)
+	}
+	c.clients[conn] = client
+
+	return client, nil
+}
@@ -0,0 +1,226 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/minio/minio-go/v7"
+
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+func newStorage(t *testing.T) (*minio.Client, *Unmarshaller) {
+	t.Helper()
+	backend := s3mem.New()
+	faker := gofakes3.New(backend).Server()
+	// gofakes3 groups keys by empty delimiter, s3 ignores it
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if v, ok := q["delimiter"]; ok && len(v) == 1 && v[0] == "" {
+			q.Del("delimiter")
+			r.URL.RawQuery = q.Encode()
+		}
+		faker.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	conn := ConnConfig{
+		Endpoint:  server.URL,
+		Region:    "us-east-1",
+		AccessKey: "key",
+		SecretKey: "secret",
+	}
+	vv := testutil.Vars{}
+	u := NewUnmarshaller(conn, compare.New(contract.CompareParams{}, vv))
+	client, err := u.clients.get(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.MakeBucket(context.Background(), "exports", minio.MakeBucketOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return client, u
+}
+
+func TestS3_PutGet(t *testing.T) {
+	_, u := newStorage(t)
+	vv := testutil.Vars{"id": "42"}
+	put := testutil.Run[*S3](t, u, `
+s3:
+  bucket: exports
+  put:
+    key: reports/{{$id}}.json
+    body: '{"id": {{$id}}, "total": 100}'
+    contentType: application/json
+    metadata:
+      Owner: "{{$id}}"
+`, vv)
+	sum := md5.Sum([]byte(`{"id": 42, "total": 100}`))
+	wantMD5 := hex.EncodeToString(sum[:])
+	if !strings.Contains(*put.ResponseBody(), `"md5":"`+wantMD5+`"`) {
+		t.Errorf("ResponseBody() = %s, want md5 %s", *put.ResponseBody(), wantMD5)
+	}
+	if !strings.Contains(*put.ResponseBody(), `"exists":true`) {
+		t.Errorf("ResponseBody() = %s, want existing object", *put.ResponseBody())
+	}
+
+	get := testutil.Run[*S3](t, u, `
+s3:
+  bucket: exports
+  get: reports/{{$id}}.json
+  response: |
+    {
+      "key": "reports/{{$id}}.json",
+      "exists": true,
+      "etag": "`+wantMD5+`",
+      "contentType": "application/json",
+      "metadata": {"owner": "{{$id}}"},
+      "checksums": {"md5": "`+wantMD5+`"},
+      "content": {"id": {{$id}}, "total": 100}
+    }
+`, vv)
+	if err := get.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	headMissing := testutil.Run[*S3](t, u, `
+s3:
+  bucket: exports
+  head: reports/missing.json
+  response: '{"exists": false}'
+`, vv)
+	if err := headMissing.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	wrong := testutil.Run[*S3](t, u, `
+s3:
+  bucket: exports
+  get: reports/{{$id}}.json
+  response: '{"content": {"total": 200}}'
+`, vv)
+	var testErr *contract.TestError
+	if err := wrong.Check(); !errors.As(err, &testErr) {
+		t.Errorf("Check() error = %v, want test error", err)
+	}
+}
+
+func TestS3_CSVListDelete(t *testing.T) {
+	_, u := newStorage(t)
+	vv := testutil.Vars{}
+	put := testutil.Build[*S3](t, u, `
+s3:
+  bucket: exports
+  put:
+    key: users/all.csv
+    file: users.csv
+`, vv)
+	put.SetFileName("testdata/s3.yaml")
+	if err := put.IsValid(); err != nil {
+		t.Fatalf("IsValid() error = %v", err)
+	}
+	if err := put.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	testutil.Run[*S3](t, u, `
+s3:
+  bucket: exports
+  put:
+    key: users/note.txt
+    body: plain
+`, vv)
+
+	get := testutil.Run[*S3](t, u, `
+s3:
+  bucket: exports
+  get: users/all.csv
+  response: |
+    {"contentType": "text/csv; charset=utf-8", "content": [{"id": "1", "name": "John"}, {"id": "2", "name": "Jane"}]}
+`, vv)
+	if err := get.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	listed := testutil.Run[*S3](t, u, `
+s3:
+  bucket: exports
+  list: users/
+  response: '[{"key": "users/all.csv", "size": 22}, {"key": "users/note.txt", "size": 5}]'
+`, vv)
+	if err := listed.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	testutil.Run[*S3](t, u, `
+s3:
+  bucket: exports
+  delete: users/note.txt
+`, vv)
+	afterDelete := testutil.Run[*S3](t, u, `
+s3:
+  bucket: exports
+  list: users/
+  response: '[{"key": "users/all.csv"}]'
+`, vv)
+	if err := afterDelete.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+}
+
+func TestS3_IsValid(t *testing.T) {
+	_, u := newStorage(t)
+	tests := []struct {
+		name string
+		step string
+	}{
+		{
+			name: "two operations",
+			step: "s3:\n  bucket: a\n  get: k\n  head: k\n",
+		},
+		{
+			name: "empty bucket",
+			step: "s3:\n  get: k\n",
+		},
+		{
+			name: "put body and file",
+			step: "s3:\n  bucket: a\n  put: {key: k, body: b, file: f}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := testutil.Build[*S3](t, u, tt.step, testutil.Vars{}).IsValid(); err == nil {
+				t.Errorf("IsValid() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestClients_Get(t *testing.T) {
+	c := &clients{clients: map[ConnConfig]*minio.Client{}}
+	conn := ConnConfig{Endpoint: "http://127.0.0.1:9000", Region: "us-east-1", AccessKey: "key", SecretKey: "secret"}
+	first, err := c.get(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same, _ := c.get(conn); same != first {
+		t.Errorf("expected shared client for the same config")
+	}
+	for _, other := range []ConnConfig{
+		{Endpoint: conn.Endpoint, Region: "eu-west-1", AccessKey: conn.AccessKey, SecretKey: conn.SecretKey},
+		{Endpoint: conn.Endpoint, Region: conn.Region, AccessKey: conn.AccessKey, SecretKey: "other"},
+	} {
+		if client, _ := c.get(other); client == first {
+			t.Errorf("expected separate client for %+v", other)
+		}
+	}
+}
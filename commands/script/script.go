@@ -2,6 +2,7 @@ package script
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/ixpectus/declarate/contract"
 )
@@ -105,6 +106,14 @@ func (e *ScriptCmd) Do() error {
 	return nil
 }
 
+func (e *ScriptCmd) Repro() string {
+	if e.Config == nil {
+		return ""
+	}
+
+	return strings.TrimRight(e.Config.Cmd, "\n")
+}
+
 func (e *ScriptCmd) ResponseBody() *string {
 	return &e.responseBody
 }
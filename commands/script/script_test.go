@@ -0,0 +1,28 @@
+package script
+
+import (
+	"testing"
+
+	"github.com/ixpectus/declarate/eval"
+	"github.com/ixpectus/declarate/report"
+	"github.com/ixpectus/declarate/variables"
+)
+
+func TestScriptCmd_Repro(t *testing.T) {
+	vv := variables.New(eval.NewEval(nil), nil, false)
+	if err := vv.Set("name", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	e := &ScriptCmd{Config: &Config{Cmd: "echo {{$name}}\n"}}
+	e.SetVars(vv)
+	e.SetReport(report.NewEmptyReport())
+	if err := e.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if got, expected := e.Repro(), "echo bob"; got != expected {
+		t.Errorf("Repro() = %q, want %q", got, expected)
+	}
+	if got := (&ScriptCmd{}).Repro(); got != "" {
+		t.Errorf("Repro() = %q, want empty", got)
+	}
+}
@@ -112,6 +112,14 @@ func (e *ShellCmd) IsValid() error {
 	return nil
 }
 
+func (e *ShellCmd) Repro() string {
+	if e.Config == nil {
+		return ""
+	}
+
+	return e.Config.Cmd
+}
+
 func (e *ShellCmd) ResponseBody() *string {
 	return &e.responseBody
 }
@@ -0,0 +1,27 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/ixpectus/declarate/eval"
+	"github.com/ixpectus/declarate/variables"
+)
+
+func TestShellCmd_Repro(t *testing.T) {
+	vv := variables.New(eval.NewEval(nil), nil, false)
+	if err := vv.Set("name", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	e := &ShellCmd{Config: &Config{Cmd: "echo {{$name}}\necho done"}}
+	e.SetVars(vv)
+	if err := e.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	// repro repeats commands with variables as they were during run
+	if got, expected := e.Repro(), "echo bob\necho done"; got != expected {
+		t.Errorf("Repro() = %q, want %q", got, expected)
+	}
+	if got := (&ShellCmd{}).Repro(); got != "" {
+		t.Errorf("Repro() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,110 @@
+package socket
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const readSize = 64 * 1024
+
+// conn keeps bytes read after delimiter, so the next read of named
+// connection starts from them
+type conn struct {
+	net.Conn
+	buf []byte
+}
+
+func dial(network, address string, timeout time.Duration) (*conn, error) {
+	c, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("connect %s %s: %w", network, address, err)
+	}
+
+	return &conn{Conn: c}, nil
+}
+
+// read reads until delimiter or count bytes, without them it reads until
+// timeout, ok is false when delimiter or count are not read in time
+func (c *conn) read(delimiter []byte, count int, timeout time.Duration) (res []byte, ok bool, err error) {
+	limited := len(delimiter) > 0 || count > 0
+	if err := c.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, false, fmt.Errorf("set read deadline: %w", err)
+	}
+	chunk := make([]byte, readSize)
+	for {
+		if i := bytes.Index(c.buf, delimiter); len(delimiter) > 0 && i >= 0 {
+			return c.take(i + len(delimiter)), true, nil
+		}
+		if count > 0 && len(c.buf) >= count {
+			return c.take(count), true, nil
+		}
+		n, err := c.Read(chunk)
+		c.buf = append(c.buf, chunk[:n]...)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return c.take(len(c.buf)), !limited, nil
+		}
+		if err != nil {
+			if limited {
+				return c.take(len(c.buf)), false, fmt.Errorf("read: %w", err)
+			}
+			// connection closed by server, everything is read
+			return c.take(len(c.buf)), true, nil
+		}
+	}
+}
+
+func (c *conn) take(n int) []byte {
+	res := c.buf[:n:n]
+	c.buf = c.buf[n:]
+
+	return res
+}
+
+// conns keeps named connections open between steps
+type conns struct {
+	mu    sync.Mutex
+	conns map[string]*conn
+}
+
+func newConns() *conns {
+	return &conns{
+		conns: map[string]*conn{},
+	}
+}
+
+func (c *conns) get(name string) *conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conns[name]
+}
+
+func (c *conns) set(name string, conn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conns[name] = conn
+}
+
+func (c *conns) remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.conns, name)
+}
+
+func (c *conns) closeAll() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for name, conn := range c.conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close socket connection %s: %w", name, err))
+		}
+		delete(c.conns, name)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,291 @@
+package socket
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dailymotion/allure-go"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/tools"
+	"github.com/ixpectus/declarate/variables"
+)
+
+const defaultTimeout = 5 * time.Second
+
+type Socket struct {
+	Config       *Config
+	Vars         contract.Vars
+	Report       contract.ReportAttachement
+	comparer     contract.Comparer
+	conns        *conns
+	responseBody *string
+	// failure is set when reply is not read in time
+	failure *contract.TestError
+}
+
+type Unmarshaller struct {
+	comparer contract.Comparer
+	conns    *conns
+}
+
+func NewUnmarshaller(comparer contract.Comparer) *Unmarshaller {
+	return &Unmarshaller{
+		comparer: comparer,
+		conns:    newConns(),
+	}
+}
+
+type Check struct {
+	Socket *Config `yaml:"socket,omitempty"`
+}
+
+type Config struct {
+	// Network is tcp or udp, tcp by default
+	Network string `json:"network" yaml:"network"`
+	Address string `json:"address" yaml:"address"`
+	// Name keeps connection open for the next steps with the same name
+	Name string `json:"name" yaml:"name"`
+	// Close closes named connection after the step
+	Close bool `json:"close" yaml:"close"`
+	// Send is text payload
+	Send *string `json:"send" yaml:"send"`
+	// SendHex is hex payload, spaces are ignored
+	SendHex *string     `json:"sendHex" yaml:"sendHex"`
+	Read    *ReadConfig `json:"read" yaml:"read"`
+	// Hex makes response json with hex of reply instead of reply text
+	Hex              bool                   `json:"hex" yaml:"hex"`
+	Response         *string                `json:"response" yaml:"response"`
+	ComparisonParams contract.CompareParams `json:"comparisonParams" yaml:"comparisonParams"`
+}
+
+// ReadConfig stops reading after delimiter or count bytes, without them
+// everything read before timeout is reply
+type ReadConfig struct {
+	Until    *string `json:"until" yaml:"until"`
+	UntilHex *string `json:"untilHex" yaml:"untilHex"`
+	Bytes    int     `json:"bytes" yaml:"bytes"`
+	// Timeout of reading, it is a failure when delimiter or bytes are set
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+// Stop closes named connections left open by steps
+func (u *Unmarshaller) Stop() error {
+	return u.conns.closeAll()
+}
+
+func (u *Unmarshaller) Build(unmarshal func(any) error) (contract.Doer, error) {
+	cfg := &Check{}
+	if err := unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("unmarshall socket: %w", err)
+	}
+	if cfg.Socket == nil {
+		return nil, nil
+	}
+
+	return &Socket{
+		Config:   cfg.Socket,
+		comparer: u.comparer,
+		conns:    u.conns,
+	}, nil
+}
+
+func (e *Socket) SetVars(vv contract.Vars) {
+	e.Vars = vv
+}
+
+func (e *Socket) SetReport(r contract.ReportAttachement) {
+	e.Report = r
+}
+
+func (e *Socket) GetConfig() any {
+	return e.Config
+}
+
+func (e *Socket) IsValid() error {
+	switch {
+	case e.Config.Address == "" && e.Config.Name == "":
+		return fmt.Errorf("socket address is empty")
+	case e.Config.Network != "" && e.Config.Network != "tcp" && e.Config.Network != "udp":
+		return fmt.Errorf("unknown socket network `%s`, tcp or udp expected", e.Config.Network)
+	case e.Config.Send != nil && e.Config.SendHex != nil:
+		return fmt.Errorf("impossible to fill send and sendHex simultaneously, choose one of")
+	case e.Config.Response != nil && e.Config.Read == nil:
+		return fmt.Errorf("socket response requires read")
+	}
+	hexes := []*string{e.Config.SendHex}
+	if r := e.Config.Read; r != nil {
+		if r.Until != nil && r.UntilHex != nil {
+			return fmt.Errorf("impossible to fill until and untilHex simultaneously, choose one of")
+		}
+		if r.Bytes < 0 {
+			return fmt.Errorf("socket read bytes must be positive")
+		}
+		hexes = append(hexes, r.UntilHex)
+	}
+	for _, v := range hexes {
+		if v == nil {
+			continue
+		}
+		if _, err := decodeHex(variables.VariableRx.ReplaceAllString(*v, "00")); err != nil {
+			return fmt.Errorf("cannot parse socket hex `%s`: %w", *v, err)
+		}
+	}
+	if e.Config.Hex && e.Config.Response != nil {
+		c := variables.VariableRx.ReplaceAllString(*e.Config.Response, "2")
+		if !json.Valid([]byte(c)) {
+			return fmt.Errorf("cannot parse socket response: `%v`", *e.Config.Response)
+		}
+	}
+
+	return nil
+}
+
+// decodeHex decodes hex ignoring spaces and new lines
+func decodeHex(s string) ([]byte, error) {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+
+	return hex.DecodeString(s)
+}
+
+// payload decodes text or hex value with variables applied
+func (e *Socket) payload(text, hexValue *string) ([]byte, error) {
+	if text != nil {
+		return []byte(e.Vars.Apply(*text)), nil
+	}
+	if hexValue != nil {
+		return decodeHex(e.Vars.Apply(*hexValue))
+	}
+
+	return nil, nil
+}
+
+// connect returns named connection or opens a new one
+func (e *Socket) connect() (*conn, error) {
+	if e.Config.Name != "" {
+		if c := e.conns.get(e.Config.Name); c != nil {
+			return c, nil
+		}
+		if e.Config.Address == "" {
+			return nil, fmt.Errorf("socket connection %s is not opened", e.Config.Name)
+		}
+	}
+	network := e.Config.Network
+	if network == "" {
+		network = "tcp"
+	}
+	c, err := dial(network, e.Vars.Apply(e.Config.Address), defaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if e.Config.Name != "" {
+		e.conns.set(e.Config.Name, c)
+	}
+
+	return c, nil
+}
+
+func (e *Socket) Do() error {
+	e.responseBody = nil
+	e.failure = nil
+	c, err := e.connect()
+	if err != nil {
+		return err
+	}
+	if e.Config.Name == "" || e.Config.Close {
+		defer func() {
+			_ = c.Close()
+			if e.Config.Name != "" {
+				e.conns.remove(e.Config.Name)
+			}
+		}()
+	}
+	data, err := e.payload(e.Config.Send, e.Config.SendHex)
+	if err != nil {
+		return fmt.Errorf("decode socket payload: %w", err)
+	}
+	if len(data) > 0 {
+		if e.Report != nil {
+			e.Report.AddAttachment("sent", allure.TextPlain, []byte(hex.Dump(data)))
+		}
+		if _, err := c.Write(data); err != nil {
+			return fmt.Errorf("send to %s: %w", c.RemoteAddr(), err)
+		}
+	}
+	r := e.Config.Read
+	if r == nil {
+		return nil
+	}
+	delimiter, err := e.payload(r.Until, r.UntilHex)
+	if err != nil {
+		return fmt.Errorf("decode socket delimiter: %w", err)
+	}
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	reply, ok, err := c.read(delimiter, r.Bytes, timeout)
+	if err != nil {
+		return fmt.Errorf("read from %s: %w", c.RemoteAddr(), err)
+	}
+	if e.Report != nil {
+		e.Report.AddAttachment("received", allure.TextPlain, []byte(hex.Dump(reply)))
+	}
+	res := string(reply)
+	if e.Config.Hex {
+		b, _ := json.Marshal(struct {
+			Hex  string `json:"hex"`
+			Size int    `json:"size"`
+		}{hex.EncodeToString(reply), len(reply)})
+		res = string(b)
+	}
+	e.responseBody = &res
+	if !ok {
+		expected := fmt.Sprintf("%d bytes", r.Bytes)
+		if len(delimiter) > 0 {
+			expected = fmt.Sprintf("reply ending with %q", delimiter)
+		}
+		e.failure = &contract.TestError{
+			Title:         "socket reply not received",
+			Expected:      expected,
+			Actual:        res,
+			Message:       fmt.Sprintf("timeout %v exceeded", timeout),
+			OriginalError: fmt.Errorf("socket reply not received, timeout %v exceeded", timeout),
+		}
+	}
+
+	return nil
+}
+
+// ResponseBody returns reply text or json with reply hex
+func (e *Socket) ResponseBody() *string {
+	return e.responseBody
+}
+
+func (e *Socket) Check() error {
+	if e.failure != nil {
+		return e.failure
+	}
+	if e.Config.Response == nil || e.responseBody == nil {
+		return nil
+	}
+	expected := e.Vars.Apply(*e.Config.Response)
+	if e.Config.Hex {
+		expected = strings.TrimSuffix(expected, "\n")
+		return tools.CompareJSON(e.comparer, "socket reply differs", expected, *e.responseBody, e.Config.ComparisonParams)
+	}
+	if errs := e.comparer.Compare(expected, *e.responseBody, e.Config.ComparisonParams); len(errs) > 0 {
+		return tools.Differs("socket reply differs", expected, *e.responseBody, errs)
+	}
+
+	return nil
+}
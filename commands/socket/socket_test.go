@@ -0,0 +1,195 @@
+package socket
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+// newServer accepts tcp connections, replies `+<line>` to every line and
+// `-bye` with closing to QUIT
+func newServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				scanner := bufio.NewScanner(c)
+				for scanner.Scan() {
+					if scanner.Text() == "QUIT" {
+						_, _ = c.Write([]byte("-bye\r\n"))
+						return
+					}
+					_, _ = c.Write([]byte("+" + scanner.Text() + "\r\n"))
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestSocket_TCP(t *testing.T) {
+	addr := newServer(t)
+	u := NewUnmarshaller(compare.New(contract.CompareParams{}, testutil.Vars{}))
+	vv := testutil.Vars{"addr": addr, "id": "42"}
+	first := testutil.Run[*Socket](t, u, `
+socket:
+  address: "{{$addr}}"
+  name: legacy
+  send: "GET {{$id}}\nPING\n"
+  read:
+    until: "\r\n"
+  response: "+GET {{$id}}\r\n"
+`, vv)
+	if err := first.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	second := testutil.Run[*Socket](t, u, `
+socket:
+  name: legacy
+  hex: true
+  read:
+    bytes: 7
+  response: '{"hex": "2b50494e470d0a", "size": 7}'
+`, vv)
+	if err := second.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	closed := testutil.Run[*Socket](t, u, `
+socket:
+  name: legacy
+  close: true
+  sendHex: 51 55 49 54 0a
+  read:
+    timeout: 1s
+  response: "-bye\r\n"
+`, vv)
+	if err := closed.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	if u.conns.get("legacy") != nil {
+		t.Errorf("connection legacy is not closed")
+	}
+
+	timeout := testutil.Run[*Socket](t, u, `
+socket:
+  address: "{{$addr}}"
+  read:
+    until: "\r\n"
+    timeout: 100ms
+`, vv)
+	var testErr *contract.TestError
+	if err := timeout.Check(); !errors.As(err, &testErr) {
+		t.Errorf("Check() error = %v, want test error", err)
+	}
+}
+
+func TestUnmarshaller_Stop(t *testing.T) {
+	u := NewUnmarshaller(compare.New(contract.CompareParams{}, testutil.Vars{}))
+	testutil.Run[*Socket](t, u, `
+socket:
+  address: "{{$addr}}"
+  name: legacy
+  send: "PING\n"
+  read:
+    until: "\r\n"
+`, testutil.Vars{"addr": newServer(t)})
+	c := u.conns.get("legacy")
+	if c == nil {
+		t.Fatalf("expected named connection")
+	}
+	if err := u.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if u.conns.get("legacy") != nil {
+		t.Errorf("expected connection to be removed")
+	}
+	if _, err := c.Write([]byte("PING\n")); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Write() error = %v, want closed connection", err)
+	}
+}
+
+func TestSocket_UDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = pc.Close() })
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = pc.WriteTo(append([]byte{0xff}, buf[:n]...), addr)
+		}
+	}()
+	u := NewUnmarshaller(compare.New(contract.CompareParams{}, testutil.Vars{}))
+	s := testutil.Run[*Socket](t, u, `
+socket:
+  network: udp
+  address: `+pc.LocalAddr().String()+`
+  sendHex: "0102"
+  hex: true
+  read:
+    bytes: 3
+  response: '{"hex": "ff0102"}'
+`, testutil.Vars{})
+	if err := s.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+}
+
+func TestSocket_IsValid(t *testing.T) {
+	u := NewUnmarshaller(nil)
+	tests := []struct {
+		name string
+		step string
+	}{
+		{
+			name: "empty address",
+			step: "socket:\n  send: a\n",
+		},
+		{
+			name: "unknown network",
+			step: "socket:\n  network: unix\n  address: a\n",
+		},
+		{
+			name: "send and sendHex",
+			step: "socket:\n  address: a\n  send: a\n  sendHex: '00'\n",
+		},
+		{
+			name: "invalid hex",
+			step: "socket:\n  address: a\n  sendHex: zz\n",
+		},
+		{
+			name: "response without read",
+			step: "socket:\n  address: a\n  response: a\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := testutil.Build[*Socket](t, u, tt.step, testutil.Vars{}).IsValid(); err == nil {
+				t.Errorf("IsValid() error = nil, want error")
+			}
+		})
+	}
+}
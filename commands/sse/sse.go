@@ -0,0 +1,352 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dailymotion/allure-go"
+
+	"github.com/ixpectus/declarate/commands/request"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/internal/collector"
+	"github.com/ixpectus/declarate/tools"
+	"github.com/ixpectus/declarate/variables"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// SSE subscribes through request command, so services, auth and transport
+// of request steps are used
+type SSE struct {
+	Config   *Config
+	Vars     contract.Vars
+	Report   contract.ReportAttachement
+	comparer contract.Comparer
+	subs     *collector.Registry
+	request  *request.Request
+	// host is service or default host, it is not used for absolute urls
+	host         string
+	responseBody *string
+	// failure is set when events are not collected in time
+	failure *contract.TestError
+	// waited is background subscription, it is removed after successful check
+	waited *collector.Collector
+}
+
+type Unmarshaller struct {
+	requests *request.Unmarshaller
+	comparer contract.Comparer
+	subs     *collector.Registry
+}
+
+func NewUnmarshaller(requests *request.Unmarshaller, comparer contract.Comparer) *Unmarshaller {
+	return &Unmarshaller{
+		requests: requests,
+		comparer: comparer,
+		subs:     collector.NewRegistry(),
+	}
+}
+
+type Check struct {
+	SSE *Config `yaml:"sse,omitempty"`
+}
+
+type Config struct {
+	// URL is stream url or path relative to service or default host
+	URL        string            `json:"url" yaml:"url"`
+	Service    string            `json:"service" yaml:"service"`
+	HeadersVal map[string]string `json:"headers" yaml:"headers"`
+	// Name identifies background subscription, step with the name and
+	// without url waits for it
+	Name       string `json:"name" yaml:"name"`
+	Background bool   `json:"background" yaml:"background"`
+	// Count stops collecting after count events
+	Count int `json:"count" yaml:"count"`
+	// Until stops collecting after event matching it
+	Until *string `json:"until" yaml:"until"`
+	// Timeout stops collecting, it is a failure when count or until are set
+	Timeout          time.Duration          `json:"timeout" yaml:"timeout"`
+	Response         *string                `json:"response" yaml:"response"`
+	ComparisonParams contract.CompareParams `json:"comparisonParams" yaml:"comparisonParams"`
+}
+
+func (u *Unmarshaller) Build(unmarshal func(any) error) (contract.Doer, error) {
+	cfg := &Check{}
+	if err := unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("unmarshall sse: %w", err)
+	}
+	if cfg.SSE == nil {
+		return nil, nil
+	}
+	var req *request.Request
+	if cfg.SSE.URL != "" {
+		cmd, err := u.requests.Build(func(v any) error {
+			reqCfg, ok := v.(*request.RequestConfig)
+			if !ok {
+				return fmt.Errorf("unexpected request config %T", v)
+			}
+			reqCfg.Method = http.MethodGet
+			reqCfg.RequestURL = cfg.SSE.URL
+			reqCfg.Service = cfg.SSE.Service
+			reqCfg.HeadersVal = map[string]string{"Accept": "text/event-stream"}
+			for k, v := range cfg.SSE.HeadersVal {
+				reqCfg.HeadersVal[k] = v
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		req, ok = cmd.(*request.Request)
+		if !ok {
+			return nil, fmt.Errorf("unexpected request command %T", cmd)
+		}
+	}
+
+	res := &SSE{
+		Config:   cfg.SSE,
+		comparer: u.comparer,
+		subs:     u.subs,
+		request:  req,
+	}
+	if req != nil {
+		res.host = req.Host
+	}
+
+	return res, nil
+}
+
+func (e *SSE) SetVars(vv contract.Vars) {
+	e.Vars = vv
+	if e.request != nil {
+		e.request.SetVars(vv)
+	}
+}
+
+func (e *SSE) SetReport(r contract.ReportAttachement) {
+	e.Report = r
+	if e.request != nil {
+		e.request.SetReport(r)
+	}
+}
+
+func (e *SSE) GetConfig() any {
+	return e.Config
+}
+
+func (e *SSE) IsValid() error {
+	if e.Config.URL == "" && e.Config.Name == "" {
+		return fmt.Errorf("sse url is empty")
+	}
+	if e.Config.Background && (e.Config.Name == "" || e.Config.URL == "") {
+		return fmt.Errorf("background sse subscription requires url and name")
+	}
+	if e.Config.Background && e.Config.Response != nil {
+		return fmt.Errorf("background sse subscription has no response, check it in the step waiting for %s", e.Config.Name)
+	}
+	for _, v := range []*string{e.Config.Until, e.Config.Response} {
+		if v == nil {
+			continue
+		}
+		c := variables.VariableRx.ReplaceAllString(*v, "2")
+		if !json.Valid([]byte(c)) {
+			return fmt.Errorf("cannot parse sse event: `%v`", *v)
+		}
+	}
+
+	return nil
+}
+
+func (e *SSE) Do() error {
+	e.responseBody = nil
+	e.failure = nil
+	e.waited = nil
+	var c *collector.Collector
+	if e.Config.URL != "" {
+		var err error
+		c, err = e.subscribe()
+		if err != nil {
+			return err
+		}
+		if e.Config.Background {
+			e.subs.Set(e.Config.Name, c)
+			return nil
+		}
+	} else {
+		c = e.subs.Get(e.Config.Name)
+		if c == nil {
+			return fmt.Errorf("sse subscription %s is not started", e.Config.Name)
+		}
+		e.waited = c
+	}
+	res, failure, err := c.Wait("sse events not received", "events")
+	if err != nil {
+		return err
+	}
+	e.responseBody = &res
+	if e.Report != nil {
+		e.Report.AddAttachment("events", allure.ApplicationJson, []byte(tools.JSONPrettyPrint(res)))
+	}
+	e.failure = failure
+
+	return nil
+}
+
+// subscribe connects to the stream and collects events in background, stop
+// conditions are resolved with variables of this step
+func (e *SSE) subscribe() (*collector.Collector, error) {
+	timeout := e.Config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	url := e.Vars.Apply(e.Config.URL)
+	e.request.Config.RequestURL = url
+	e.request.Host = e.host
+	if strings.Contains(url, "://") {
+		e.request.Host = ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	resp, err := e.request.Stream(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("subscribe %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("subscribe %s: unexpected status %d", url, resp.StatusCode)
+	}
+	cfg := collector.Config{
+		Count:    e.Config.Count,
+		Timeout:  timeout,
+		Comparer: e.comparer,
+		Params:   e.Config.ComparisonParams,
+	}
+	if e.Config.Until != nil {
+		cfg.Until = strings.TrimSuffix(e.Vars.Apply(*e.Config.Until), "\n")
+	}
+	c := collector.New(cfg)
+	go func() {
+		defer cancel()
+		defer resp.Body.Close()
+		read(ctx, resp, c)
+	}()
+
+	return c, nil
+}
+
+// ResponseBody returns array of collected events
+func (e *SSE) ResponseBody() *string {
+	return e.responseBody
+}
+
+func (e *SSE) Check() error {
+	if err := e.check(); err != nil {
+		return err
+	}
+	// waited subscription is kept until check passes, so poll retries see it
+	if e.waited != nil {
+		e.subs.Remove(e.Config.Name, e.waited)
+	}
+
+	return nil
+}
+
+func (e *SSE) check() error {
+	if e.failure != nil {
+		return e.failure
+	}
+	if e.Config.Response == nil || e.responseBody == nil {
+		return nil
+	}
+	expected := strings.TrimSuffix(e.Vars.Apply(*e.Config.Response), "\n")
+
+	return tools.CompareJSON(e.comparer, "sse events differ", expected, *e.responseBody, e.Config.ComparisonParams)
+}
+
+// read collects events of the stream until stop condition
+func read(ctx context.Context, resp *http.Response, c *collector.Collector) {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	ev := event{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			ev.add(line)
+			continue
+		}
+		if !ev.hasData {
+			ev = event{}
+			continue
+		}
+		raw := ev.json()
+		ev = event{}
+		if c.Add(raw) {
+			c.Close(nil, false)
+			return
+		}
+	}
+	if ctx.Err() != nil {
+		c.Close(nil, true)
+		return
+	}
+	if err := scanner.Err(); err != nil {
+		c.Close(fmt.Errorf("read sse stream: %w", err), false)
+		return
+	}
+	c.Close(nil, false)
+}
+
+// event is parsed by EventSource rules, data lines are joined with new line
+type event struct {
+	name    string
+	id      string
+	data    []string
+	hasData bool
+}
+
+func (e *event) add(line string) {
+	if strings.HasPrefix(line, ":") {
+		return
+	}
+	field, value, _ := strings.Cut(line, ":")
+	value = strings.TrimPrefix(value, " ")
+	switch field {
+	case "event":
+		e.name = value
+	case "id":
+		e.id = value
+	case "data":
+		e.data = append(e.data, value)
+		e.hasData = true
+	}
+}
+
+// json renders event as {event, id, data}, data is parsed as json when
+// possible
+func (e *event) json() string {
+	name := e.name
+	if name == "" {
+		name = "message"
+	}
+	data := strings.Join(e.data, "\n")
+	var raw json.RawMessage
+	if json.Valid([]byte(data)) {
+		raw = json.RawMessage(data)
+	} else {
+		raw, _ = json.Marshal(data)
+	}
+	b, _ := json.Marshal(struct {
+		Event string          `json:"event"`
+		ID    string          `json:"id"`
+		Data  json.RawMessage `json:"data"`
+	}{name, e.id, raw})
+
+	return string(b)
+}
@@ -0,0 +1,184 @@
+package sse
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ixpectus/declarate/commands/request"
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+// notificationsServer sends greeting, then every message posted to /notify
+func notificationsServer() *httptest.Server {
+	notifications := make(chan string, 10)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/notify" {
+			notifications <- r.URL.Query().Get("text")
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": comment\n\nevent: hello\nid: 0\ndata: plain\ndata: text\n\n")
+		w.(http.Flusher).Flush()
+		for i := 1; ; i++ {
+			select {
+			case text := <-notifications:
+				fmt.Fprintf(w, "id: %d\ndata: {\"text\": %q}\n\n", i, text)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+}
+
+func TestSSE_Background(t *testing.T) {
+	srv := notificationsServer()
+	defer srv.Close()
+	vv := testutil.Vars{"last": "bye"}
+	cmp := compare.New(contract.CompareParams{}, vv)
+	u := NewUnmarshaller(request.NewUnmarshaller(srv.URL, cmp), cmp)
+
+	start := testutil.Valid[*SSE](t, u, `
+sse:
+  url: /events
+  name: notifications
+  background: true
+  until: '{"data": {"text": "{{$last}}"}}'
+  timeout: 2s
+`, vv)
+	if err := start.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if start.ResponseBody() != nil {
+		t.Errorf("background step has response %s", *start.ResponseBody())
+	}
+	for _, v := range []string{"hi", "bye", "never"} {
+		resp, err := http.Get(srv.URL + "/notify?text=" + v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	wait := testutil.Valid[*SSE](t, u, `
+sse:
+  name: notifications
+  response: |
+    [
+      {"event": "hello", "id": "0", "data": "plain\ntext"},
+      {"event": "message", "id": "1", "data": {"text": "hi"}},
+      {"event": "message", "id": "2", "data": {"text": "bye"}}
+    ]
+`, vv)
+	if err := wait.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if err := wait.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	if err := testutil.Valid[*SSE](t, u, "sse:\n  name: notifications\n", vv).Do(); err == nil {
+		t.Errorf("expected error for finished subscription")
+	}
+}
+
+func TestSSE_BackgroundPollRetry(t *testing.T) {
+	srv := notificationsServer()
+	defer srv.Close()
+	vv := testutil.Vars{}
+	cmp := compare.New(contract.CompareParams{}, vv)
+	u := NewUnmarshaller(request.NewUnmarshaller(srv.URL, cmp), cmp)
+	if err := testutil.Valid[*SSE](t, u, "sse:\n  url: /events\n  name: notifications\n  background: true\n  count: 1\n", vv).Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	wrong := testutil.Valid[*SSE](t, u, "sse:\n  name: notifications\n  response: '[{\"event\": \"bye\"}]'\n", vv)
+	// poll retries the same step, subscription is kept until check passes
+	for i := 0; i < 2; i++ {
+		if err := wrong.Do(); err != nil {
+			t.Fatalf("attempt %d: Do() error = %v", i, err)
+		}
+		if err := wrong.Check(); err == nil {
+			t.Fatalf("attempt %d: expected check failure", i)
+		}
+	}
+	right := testutil.Valid[*SSE](t, u, "sse:\n  name: notifications\n  response: '[{\"event\": \"hello\"}]'\n", vv)
+	if err := right.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if err := right.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	if err := right.Do(); err == nil {
+		t.Errorf("expected error for subscription removed after successful check")
+	}
+}
+
+func TestSSE_CountAndTimeout(t *testing.T) {
+	srv := notificationsServer()
+	defer srv.Close()
+	vv := testutil.Vars{}
+	cmp := compare.New(contract.CompareParams{}, vv)
+	u := NewUnmarshaller(request.NewUnmarshaller(srv.URL, cmp), cmp)
+
+	count := testutil.Valid[*SSE](t, u, "sse:\n  url: /events\n  count: 1\n", vv)
+	if err := count.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if err := count.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	if got := *count.ResponseBody(); got != `[{"event":"hello","id":"0","data":"plain\ntext"}]` {
+		t.Errorf("ResponseBody() = %s", got)
+	}
+
+	timeout := testutil.Valid[*SSE](t, u, "sse:\n  url: /events\n  count: 2\n  timeout: 100ms\n", vv)
+	if err := timeout.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	var testErr *contract.TestError
+	if err := timeout.Check(); !errors.As(err, &testErr) {
+		t.Errorf("Check() error = %v, want test error", err)
+	}
+
+	collect := testutil.Valid[*SSE](t, u, "sse:\n  url: /events\n  timeout: 100ms\n", vv)
+	if err := collect.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if err := collect.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+}
+
+func TestSSE_Service(t *testing.T) {
+	srv := notificationsServer()
+	defer srv.Close()
+	events := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer events-token" || r.Header.Get("Accept") != "text/event-stream" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer events.Close()
+	vv := testutil.Vars{}
+	cmp := compare.New(contract.CompareParams{}, vv)
+	requests := request.NewUnmarshaller(srv.URL, cmp, request.OptionServices(map[string]request.ServiceConfig{
+		"events": {Host: events.URL, Auth: &request.AuthConfig{Bearer: "events-token"}},
+	}))
+	u := NewUnmarshaller(requests, cmp)
+
+	step := testutil.Valid[*SSE](t, u, "sse:\n  url: /events\n  service: events\n  count: 1\n", vv)
+	if err := step.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if err := step.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	if err := testutil.Valid[*SSE](t, u, "sse:\n  url: "+events.URL+"/events\n  count: 1\n", vv).Do(); err == nil {
+		t.Errorf("expected error for absolute url without service auth")
+	}
+}
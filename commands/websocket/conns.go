@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// conn reads messages in background, so expectations can wait with timeout
+// without breaking the connection
+type conn struct {
+	ws       *websocket.Conn
+	messages chan string
+	done     chan struct{}
+	closing  chan struct{}
+	// err is read error, it is set before done is closed
+	err error
+}
+
+func dial(url string, header http.Header, subprotocols []string) (*conn, error) {
+	dialer := *websocket.DefaultDialer
+	dialer.Subprotocols = subprotocols
+	ws, resp, err := dialer.Dial(url, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("connect %s: %w, status %d", url, err, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("connect %s: %w", url, err)
+	}
+	c := &conn{
+		ws:       ws,
+		messages: make(chan string, 100),
+		done:     make(chan struct{}),
+		closing:  make(chan struct{}),
+	}
+	go c.read()
+
+	return c, nil
+}
+
+func (c *conn) read() {
+	defer close(c.done)
+	for {
+		_, b, err := c.ws.ReadMessage()
+		if err != nil {
+			c.err = err
+			return
+		}
+		select {
+		case c.messages <- string(b):
+		case <-c.closing:
+			return
+		}
+	}
+}
+
+func (c *conn) send(msg string) error {
+	return c.ws.WriteMessage(websocket.TextMessage, []byte(msg))
+}
+
+func (c *conn) close() {
+	close(c.closing)
+	_ = c.ws.WriteMessage(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+	)
+	_ = c.ws.Close()
+}
+
+// conns keeps named connections open between steps
+type conns struct {
+	mu    sync.Mutex
+	conns map[string]*conn
+}
+
+func newConns() *conns {
+	return &conns{
+		conns: map[string]*conn{},
+	}
+}
+
+func (c *conns) get(name string) *conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conns[name]
+}
+
+func (c *conns) set(name string, conn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conns[name] = conn
+}
+
+func (c *conns) remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.conns, name)
+}
+
+func (c *conns) closeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, conn := range c.conns {
+		conn.close()
+		delete(c.conns, name)
+	}
+}
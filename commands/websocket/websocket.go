@@ -0,0 +1,338 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dailymotion/allure-go"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/tools"
+)
+
+const defaultTimeout = 5 * time.Second
+
+type WebSocket struct {
+	Config       *Config
+	Vars         contract.Vars
+	Report       contract.ReportAttachement
+	comparer     contract.Comparer
+	conns        *conns
+	host         string
+	responseBody *string
+	// failure is the first failed expectation
+	failure *contract.TestError
+}
+
+type Unmarshaller struct {
+	host     string
+	comparer contract.Comparer
+	conns    *conns
+}
+
+func NewUnmarshaller(host string, comparer contract.Comparer) *Unmarshaller {
+	return &Unmarshaller{
+		host:     host,
+		comparer: comparer,
+		conns:    newConns(),
+	}
+}
+
+// Stop closes named connections left open by steps
+func (u *Unmarshaller) Stop() error {
+	u.conns.closeAll()
+	return nil
+}
+
+type Check struct {
+	WebSocket *Config `yaml:"websocket,omitempty"`
+}
+
+type Config struct {
+	// URL is ws url or path relative to default host
+	URL string `json:"url" yaml:"url"`
+	// Name keeps connection open for the next steps with the same name
+	Name         string            `json:"name" yaml:"name"`
+	HeadersVal   map[string]string `json:"headers" yaml:"headers"`
+	Subprotocols []string          `json:"subprotocols" yaml:"subprotocols"`
+	// Close closes named connection after the step
+	Close bool `json:"close" yaml:"close"`
+	// Timeout is default timeout of expectations
+	Timeout          time.Duration          `json:"timeout" yaml:"timeout"`
+	Script           []ScriptEntry          `json:"script" yaml:"script"`
+	ComparisonParams contract.CompareParams `json:"comparisonParams" yaml:"comparisonParams"`
+}
+
+// ScriptEntry sends message or waits for messages, ordered expectation
+// matches the next message, unordered one matches the next messages in any
+// order
+type ScriptEntry struct {
+	Send            *string       `json:"send" yaml:"send"`
+	Expect          *string       `json:"expect" yaml:"expect"`
+	ExpectUnordered []string      `json:"expectUnordered" yaml:"expectUnordered"`
+	Timeout         time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+func (u *Unmarshaller) Build(unmarshal func(any) error) (contract.Doer, error) {
+	cfg := &Check{}
+	if err := unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("unmarshall websocket: %w", err)
+	}
+	if cfg.WebSocket == nil {
+		return nil, nil
+	}
+
+	return &WebSocket{
+		Config:   cfg.WebSocket,
+		comparer: u.comparer,
+		conns:    u.conns,
+		host:     u.host,
+	}, nil
+}
+
+func (e *WebSocket) SetVars(vv contract.Vars) {
+	e.Vars = vv
+}
+
+func (e *WebSocket) SetReport(r contract.ReportAttachement) {
+	e.Report = r
+}
+
+func (e *WebSocket) GetConfig() any {
+	return e.Config
+}
+
+func (e *WebSocket) IsValid() error {
+	if e.Config.URL == "" && e.Config.Name == "" {
+		return fmt.Errorf("websocket url is empty")
+	}
+	for i, v := range e.Config.Script {
+		count := 0
+		if v.Send != nil {
+			count++
+		}
+		if v.Expect != nil {
+			count++
+		}
+		if len(v.ExpectUnordered) > 0 {
+			count++
+		}
+		if count != 1 {
+			return fmt.Errorf("websocket script entry %d must have one of send, expect and expectUnordered", i+1)
+		}
+	}
+
+	return nil
+}
+
+func (e *WebSocket) url() string {
+	url := e.Vars.Apply(e.Config.URL)
+	if strings.Contains(url, "://") {
+		return url
+	}
+	host := e.host
+	if strings.HasPrefix(host, "http") {
+		host = "ws" + strings.TrimPrefix(host, "http")
+	}
+
+	return host + url
+}
+
+// connect returns named connection or opens a new one
+func (e *WebSocket) connect() (*conn, error) {
+	if e.Config.Name != "" {
+		if c := e.conns.get(e.Config.Name); c != nil {
+			return c, nil
+		}
+		if e.Config.URL == "" {
+			return nil, fmt.Errorf("websocket connection %s is not opened", e.Config.Name)
+		}
+	}
+	header := http.Header{}
+	for k, v := range e.Config.HeadersVal {
+		header.Set(e.Vars.Apply(k), e.Vars.Apply(v))
+	}
+	c, err := dial(e.url(), header, e.Config.Subprotocols)
+	if err != nil {
+		return nil, err
+	}
+	if e.Config.Name != "" {
+		e.conns.set(e.Config.Name, c)
+	}
+
+	return c, nil
+}
+
+func (e *WebSocket) Do() error {
+	e.responseBody = nil
+	e.failure = nil
+	c, err := e.connect()
+	if err != nil {
+		return err
+	}
+	if e.Config.Name == "" || e.Config.Close {
+		defer func() {
+			c.close()
+			if e.Config.Name != "" {
+				e.conns.remove(e.Config.Name)
+			}
+		}()
+	}
+	log := []string{}
+	received := []string{}
+	defer func() {
+		res := "[" + strings.Join(received, ",") + "]"
+		e.responseBody = &res
+		if e.Report != nil {
+			e.Report.AddAttachment("messages", allure.TextPlain, []byte(strings.Join(log, "\n")))
+		}
+	}()
+	for i, entry := range e.Config.Script {
+		timeout := entry.Timeout
+		if timeout <= 0 {
+			timeout = e.Config.Timeout
+		}
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		switch {
+		case entry.Send != nil:
+			msg := e.Vars.Apply(*entry.Send)
+			log = append(log, "> "+msg)
+			if err := c.send(msg); err != nil {
+				return fmt.Errorf("send websocket message %d: %w", i+1, err)
+			}
+		case entry.Expect != nil:
+			expected := e.Vars.Apply(strings.TrimSuffix(*entry.Expect, "\n"))
+			msg, err := e.receive(c, timeout)
+			if err != nil {
+				e.failure = notReceived(i, []string{expected}, received, err)
+				return nil
+			}
+			log = append(log, "< "+msg)
+			received = append(received, encode(msg))
+			if errs := e.compare(expected, msg); len(errs) > 0 {
+				e.failure = differs(i, expected, msg, errs)
+				return nil
+			}
+		default:
+			if failure := e.expectUnordered(c, i, entry, timeout, &log, &received); failure != nil {
+				e.failure = failure
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *WebSocket) expectUnordered(
+	c *conn,
+	i int,
+	entry ScriptEntry,
+	timeout time.Duration,
+	log *[]string,
+	received *[]string,
+) *contract.TestError {
+	pending := []string{}
+	for _, v := range entry.ExpectUnordered {
+		pending = append(pending, e.Vars.Apply(strings.TrimSuffix(v, "\n")))
+	}
+	deadline := time.Now().Add(timeout)
+	for len(pending) > 0 {
+		msg, err := e.receive(c, time.Until(deadline))
+		if err != nil {
+			return notReceived(i, pending, *received, err)
+		}
+		*log = append(*log, "< "+msg)
+		*received = append(*received, encode(msg))
+		matched := -1
+		for j, expected := range pending {
+			if len(e.compare(expected, msg)) == 0 {
+				matched = j
+				break
+			}
+		}
+		if matched < 0 {
+			return differs(i, strings.Join(pending, "\n"), msg, []error{fmt.Errorf("message does not match any of expected")})
+		}
+		pending = append(pending[:matched], pending[matched+1:]...)
+	}
+
+	return nil
+}
+
+func (e *WebSocket) receive(c *conn, timeout time.Duration) (string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case msg := <-c.messages:
+		return msg, nil
+	case <-c.done:
+		// messages read before close are still delivered
+		select {
+		case msg := <-c.messages:
+			return msg, nil
+		default:
+		}
+		return "", fmt.Errorf("connection closed: %w", c.err)
+	case <-timer.C:
+		return "", fmt.Errorf("timeout %v exceeded", timeout)
+	}
+}
+
+// compare compares json messages as json and other messages as text
+func (e *WebSocket) compare(expected, actual string) []error {
+	if json.Valid([]byte(expected)) && json.Valid([]byte(actual)) {
+		errs, err := e.comparer.CompareJsonBody(expected, actual, e.Config.ComparisonParams)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return errs
+	}
+
+	return e.comparer.Compare(expected, actual, e.Config.ComparisonParams)
+}
+
+// encode keeps json messages as is and stores other messages as json strings
+func encode(msg string) string {
+	if json.Valid([]byte(msg)) {
+		return msg
+	}
+	b, _ := json.Marshal(msg)
+	return string(b)
+}
+
+func notReceived(i int, expected, received []string, err error) *contract.TestError {
+	return &contract.TestError{
+		Title:         fmt.Sprintf("websocket message not received, script entry %d", i+1),
+		Expected:      strings.Join(expected, "\n"),
+		Actual:        tools.JSONPrettyPrint("[" + strings.Join(received, ",") + "]"),
+		Message:       err.Error(),
+		OriginalError: fmt.Errorf("websocket message not received: %w", err),
+	}
+}
+
+func differs(i int, expected, actual string, errs []error) *contract.TestError {
+	return tools.Differs(
+		fmt.Sprintf("websocket message differs, script entry %d", i+1),
+		tools.JSONPrettyPrint(expected),
+		tools.JSONPrettyPrint(actual),
+		errs,
+	)
+}
+
+// ResponseBody returns array of messages received by the step
+func (e *WebSocket) ResponseBody() *string {
+	return e.responseBody
+}
+
+func (e *WebSocket) Check() error {
+	if e.failure != nil {
+		return e.failure
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/internal/testutil"
+)
+
+// chatServer greets with user from header, answers `both` with two
+// messages and echoes other messages
+func chatServer() *httptest.Server {
+	upgrader := websocket.Upgrader{Subprotocols: []string{"chat.v1"}}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		ws.WriteMessage(websocket.TextMessage, []byte(`{"type":"hello","user":"`+r.Header.Get("X-User")+`","protocol":"`+ws.Subprotocol()+`"}`))
+		for {
+			_, b, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(b) == "both" {
+				ws.WriteMessage(websocket.TextMessage, []byte(`{"n":1}`))
+				ws.WriteMessage(websocket.TextMessage, []byte(`{"n":2}`))
+				continue
+			}
+			ws.WriteMessage(websocket.TextMessage, b)
+		}
+	}))
+}
+
+func TestWebSocket(t *testing.T) {
+	srv := chatServer()
+	defer srv.Close()
+	vv := testutil.Vars{"user": "tom"}
+	u := NewUnmarshaller(srv.URL, compare.New(contract.CompareParams{}, vv))
+
+	tests := []struct {
+		name     string
+		step     string
+		wantErr  bool
+		response string
+	}{
+		{
+			name: "ordered",
+			step: `
+websocket:
+  url: /ws
+  headers:
+    X-User: "{{$user}}"
+  subprotocols: [chat.v1]
+  script:
+    - expect: '{"type": "hello", "user": "{{$user}}", "protocol": "chat.v1"}'
+    - send: ping
+    - expect: $matchRegexp(^pi)
+`,
+			response: `[{"type":"hello","user":"tom","protocol":"chat.v1"},"ping"]`,
+		},
+		{
+			name: "unordered",
+			step: `
+websocket:
+  url: /ws
+  script:
+    - expect: '{"type": "hello"}'
+    - send: both
+    - expectUnordered:
+        - '{"n": 2}'
+        - '{"n": 1}'
+`,
+			response: `[{"type":"hello","user":"","protocol":""},{"n":1},{"n":2}]`,
+		},
+		{
+			name: "differs",
+			step: `
+websocket:
+  url: /ws
+  script:
+    - expect: '{"type": "bye"}'
+`,
+			wantErr: true,
+		},
+		{
+			name: "timeout",
+			step: `
+websocket:
+  url: /ws
+  script:
+    - expect: '{"type": "hello"}'
+    - expect: '{"type": "more"}'
+      timeout: 50ms
+`,
+			wantErr:  true,
+			response: `[{"type":"hello","user":"","protocol":""}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := testutil.Build[*WebSocket](t, u, tt.step, vv)
+			if err := w.IsValid(); err != nil {
+				t.Fatalf("IsValid() error = %v", err)
+			}
+			if err := w.Do(); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			err := w.Check()
+			var testErr *contract.TestError
+			if tt.wantErr && !errors.As(err, &testErr) {
+				t.Errorf("Check() error = %v, want test error", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Check() error = %v", err)
+			}
+			if tt.response != "" && *w.ResponseBody() != tt.response {
+				t.Errorf("ResponseBody() = %s, want %s", *w.ResponseBody(), tt.response)
+			}
+		})
+	}
+}
+
+func TestWebSocket_Named(t *testing.T) {
+	srv := chatServer()
+	defer srv.Close()
+	vv := testutil.Vars{}
+	u := NewUnmarshaller(srv.URL, compare.New(contract.CompareParams{}, vv))
+
+	steps := []string{`
+websocket:
+  url: /ws
+  name: chat
+  script:
+    - expect: '{"type": "hello"}'
+    - send: both
+`, `
+websocket:
+  name: chat
+  close: true
+  script:
+    - expect: '{"n": 1}'
+    - expect: '{"n": 2}'
+`}
+	for _, step := range steps {
+		w := testutil.Build[*WebSocket](t, u, step, vv)
+		if err := w.Do(); err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		if err := w.Check(); err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+	}
+	if u.conns.get("chat") != nil {
+		t.Errorf("expected closed connection to be removed")
+	}
+	w := testutil.Build[*WebSocket](t, u, "websocket:\n  name: chat\n", vv)
+	if err := w.Do(); err == nil {
+		t.Errorf("expected error for closed named connection")
+	}
+}
+
+func TestUnmarshaller_Stop(t *testing.T) {
+	srv := chatServer()
+	defer srv.Close()
+	vv := testutil.Vars{}
+	u := NewUnmarshaller(srv.URL, compare.New(contract.CompareParams{}, vv))
+	testutil.Run[*WebSocket](t, u, "websocket:\n  url: /ws\n  name: chat\n", vv)
+	c := u.conns.get("chat")
+	if c == nil {
+		t.Fatalf("expected named connection")
+	}
+	if err := u.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if u.conns.get("chat") != nil {
+		t.Errorf("expected connection to be removed")
+	}
+	select {
+	case <-c.done:
+	case <-time.After(time.Second):
+		t.Errorf("connection is not closed")
+	}
+}
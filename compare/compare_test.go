@@ -0,0 +1,37 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/eval"
+	"github.com/ixpectus/declarate/variables"
+)
+
+func TestComparer_CompareJsonBody(t *testing.T) {
+	c := New(contract.CompareParams{}, variables.New(eval.NewEval(nil), nil, false))
+	actual := `{"name":"bob the builder","age":20,"tags":["admin","user"]}`
+	tests := []struct {
+		name     string
+		expected string
+		wantErr  bool
+	}{
+		{name: "number condition", expected: `{"age": "$gt(18)"}`},
+		{name: "number condition fails", expected: `{"age": "$lt(18)"}`, wantErr: true},
+		{name: "string condition", expected: `{"name": "$contains(\"the builder\")"}`},
+		{name: "string condition fails", expected: `{"name": "$endsWith(\"bob\")"}`, wantErr: true},
+		{name: "string regex", expected: `{"name": "$matchRegexp(^bob)"}`},
+		{name: "array with pure value", expected: `{"tags": "admin"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := c.CompareJsonBody(tt.expected, actual, contract.CompareParams{})
+			if err != nil {
+				t.Fatalf("CompareJsonBody() error = %v", err)
+			}
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("CompareJsonBody() errors = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package contract
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 )
 
@@ -27,6 +28,30 @@ type Doer interface {
 	SetReport(r ReportAttachement)
 }
 
+// Reproducer is implemented by commands which can be repeated outside of
+// declarate, Repro returns shell command with variables already applied
+type Reproducer interface {
+	Repro() string
+}
+
+// FileAware is implemented by commands which depend on the test file they
+// are run from
+type FileAware interface {
+	SetFileName(fileName string)
+}
+
+// Stopper is implemented by command builders keeping resources between
+// steps, suite stops them after tests
+type Stopper interface {
+	Stop() error
+}
+
+// Service is started by suite before tests and stopped after them
+type Service interface {
+	Start() error
+	Stopper
+}
+
 type TestError struct {
 	Title         string
 	Expected      string
@@ -43,6 +68,16 @@ func (e *TestError) Unwrap() error {
 	return e.OriginalError
 }
 
+// DurationError is returned when step command runs longer than allowed
+type DurationError struct {
+	Max    time.Duration
+	Actual time.Duration
+}
+
+func (e *DurationError) Error() string {
+	return fmt.Sprintf("duration %v exceeds max duration %v", e.Actual, e.Max)
+}
+
 type (
 	MessageType string
 	ActionType  string
@@ -56,16 +56,8 @@ func (c *Converter) Convert() error {
 		if len(converted) == 0 {
 			continue
 		}
-		bb, _ := yaml.Marshal(converted)
-		targetFile := c.targetDir + "/" + relatedName
-		res := strings.ReplaceAll(string(bb), "|-", "|")
-
-		if err := os.MkdirAll(path.Dir(targetFile), os.ModePerm); err != nil {
-			log.Printf("failed to mkdir for file %s, %v", targetFile, err)
-		}
-		err = os.WriteFile(targetFile, []byte(res), os.ModePerm)
-		if err != nil {
-			log.Printf("failed to write to file %s, %v", targetFile, err)
+		if err := writeTests(c.targetDir+"/"+relatedName, converted); err != nil {
+			log.Println(err)
 			continue
 		}
 	}
@@ -73,6 +65,23 @@ func (c *Converter) Convert() error {
 	return nil
 }
 
+func writeTests(targetFile string, tests []DeclarateTest) error {
+	bb, err := yaml.Marshal(tests)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tests for file %s, %w", targetFile, err)
+	}
+	res := strings.ReplaceAll(string(bb), "|-", "|")
+
+	if err := os.MkdirAll(path.Dir(targetFile), os.ModePerm); err != nil {
+		log.Printf("failed to mkdir for file %s, %v", targetFile, err)
+	}
+	if err := os.WriteFile(targetFile, []byte(res), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to write to file %s, %w", targetFile, err)
+	}
+
+	return nil
+}
+
 func convert(originalTests []GonkeyTest) []DeclarateTest {
 	res := make([]DeclarateTest, 0, len(originalTests))
 	i := 0
@@ -0,0 +1,264 @@
+package converter
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ixpectus/declarate/tools"
+)
+
+// curlIgnoredFlags do not change request and are dropped silently,
+// value is true when flag has an argument
+var curlIgnoredFlags = map[string]bool{
+	"-s":                false,
+	"--silent":          false,
+	"-S":                false,
+	"--show-error":      false,
+	"-k":                false,
+	"--insecure":        false,
+	"-L":                false,
+	"--location":        false,
+	"-v":                false,
+	"--verbose":         false,
+	"-i":                false,
+	"--include":         false,
+	"--compressed":      false,
+	"-f":                false,
+	"--fail":            false,
+	"-o":                true,
+	"--output":          true,
+	"-m":                true,
+	"--max-time":        true,
+	"--connect-timeout": true,
+}
+
+// CurlConverter converts curl commands to request steps, every command
+// starting with `curl` becomes a step, host is dropped and requests are
+// sent relative to the default host
+type CurlConverter struct {
+	source    string
+	targetDir string
+	warnings  []string
+}
+
+func NewCurl(source, targetDir string) *CurlConverter {
+	return &CurlConverter{
+		source:    source,
+		targetDir: targetDir,
+	}
+}
+
+// Warnings returns everything that could not be translated during conversion
+func (c *CurlConverter) Warnings() []string {
+	return c.warnings
+}
+
+func (c *CurlConverter) Convert() error {
+	data, err := os.ReadFile(c.source)
+	if err != nil {
+		return fmt.Errorf("read curl file: %w", err)
+	}
+	tests, err := c.convert(string(data))
+	if err != nil {
+		return err
+	}
+	for _, v := range c.warnings {
+		log.Printf("not converted: %s", v)
+	}
+	name := strings.TrimSuffix(path.Base(c.source), path.Ext(c.source)) + ".yaml"
+
+	return writeTests(c.targetDir+"/"+name, tests)
+}
+
+func (c *CurlConverter) convert(data string) ([]DeclarateTest, error) {
+	commands := []string{}
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimPrefix(strings.TrimSpace(line), "$ ")
+		if strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if trimmed == "curl" || strings.HasPrefix(trimmed, "curl ") {
+			commands = append(commands, trimmed)
+			continue
+		}
+		if len(commands) > 0 {
+			commands[len(commands)-1] += "\n" + line
+		}
+	}
+	tests := []DeclarateTest{}
+	for _, v := range commands {
+		args, err := tools.ShellArgs(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse curl command: %w", err)
+		}
+		test, err := c.command(args[1:])
+		if err != nil {
+			return nil, err
+		}
+		tests = append(tests, test)
+	}
+
+	return tests, nil
+}
+
+func (c *CurlConverter) command(args []string) (DeclarateTest, error) {
+	var (
+		method  string
+		rawURL  string
+		data    []string
+		asQuery bool
+		form    bool
+		skipped []string
+	)
+	headers := map[string]string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		value := func() string {
+			if i+1 < len(args) {
+				i++
+				return args[i]
+			}
+			return ""
+		}
+		switch arg {
+		case "-X", "--request":
+			method = strings.ToUpper(value())
+		case "-H", "--header":
+			h := value()
+			k, v, _ := strings.Cut(h, ":")
+			headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii", "--data-urlencode":
+			d := value()
+			if strings.HasPrefix(d, "@") && arg != "--data-raw" {
+				skipped = append(skipped, fmt.Sprintf("data from file `%s`", d))
+				continue
+			}
+			if arg == "--data-urlencode" {
+				// name@file reads content from file
+				if name, _, ok := strings.Cut(d, "@"); ok && !strings.Contains(name, "=") {
+					skipped = append(skipped, fmt.Sprintf("data from file `%s`", d))
+					continue
+				}
+				d = urlencodeData(d)
+			}
+			data = append(data, d)
+			form = true
+		case "--json":
+			data = append(data, value())
+			headers["Content-Type"] = "application/json"
+			headers["Accept"] = "application/json"
+		case "-u", "--user":
+			headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(value()))
+		case "-b", "--cookie":
+			headers["Cookie"] = value()
+		case "-A", "--user-agent":
+			headers["User-Agent"] = value()
+		case "-e", "--referer":
+			headers["Referer"] = value()
+		case "-G", "--get":
+			asQuery = true
+		case "-I", "--head":
+			method = "HEAD"
+		case "--url":
+			rawURL = value()
+		default:
+			if withValue, ok := curlIgnoredFlags[arg]; ok {
+				if withValue {
+					value()
+				}
+				continue
+			}
+			if strings.HasPrefix(arg, "-") {
+				skipped = append(skipped, fmt.Sprintf("flag `%s`", arg))
+				continue
+			}
+			if rawURL != "" {
+				skipped = append(skipped, fmt.Sprintf("argument `%s`", rawURL))
+			}
+			rawURL = arg
+		}
+	}
+	if rawURL == "" {
+		return DeclarateTest{}, fmt.Errorf("curl command without url: %s", strings.Join(args, " "))
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return DeclarateTest{}, fmt.Errorf("parse url %s: %w", rawURL, err)
+	}
+	query := u.RawQuery
+	body := strings.Join(data, "&")
+	if asQuery && body != "" {
+		if query != "" {
+			query += "&"
+		}
+		query += body
+		body = ""
+	}
+	if method == "" {
+		method = "GET"
+		if body != "" {
+			method = "POST"
+		}
+	}
+	res := DeclarateTest{
+		Name:        method + " " + u.Path,
+		Method:      method,
+		RequestURL:  u.EscapedPath(),
+		RequestTmpl: body,
+	}
+	if query != "" {
+		res.Query = "?" + query
+	}
+	// curl sends data as form unless content type is set
+	if form && body != "" && !hasHeader(headers, "Content-Type") {
+		headers["Content-Type"] = "application/x-www-form-urlencoded"
+	}
+	if len(headers) > 0 {
+		res.HeadersVal = headers
+	}
+	for _, v := range skipped {
+		c.warn(res.Name, v)
+	}
+
+	return res, nil
+}
+
+func (c *CurlConverter) warn(name, msg string) {
+	c.warnings = append(c.warnings, name+": "+msg)
+}
+
+// urlencodeData encodes `--data-urlencode` value like curl, `content` and
+// `=content` are encoded entirely, `name=content` only after the name
+func urlencodeData(d string) string {
+	name, content, ok := strings.Cut(d, "=")
+	if !ok {
+		return escapeForm(d)
+	}
+	if name == "" {
+		return escapeForm(content)
+	}
+
+	return name + "=" + escapeForm(content)
+}
+
+// escapeForm escapes value like curl does, space becomes %20
+func escapeForm(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package converter
+
+import (
+	"testing"
+)
+
+func TestCurlConverter_Convert(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCurl("./testdata/requests.sh", dir)
+	if err := c.Convert(); err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	tests := readConverted(t, dir+"/requests.yaml")
+	if len(tests) != 3 {
+		t.Fatalf("expected three steps, got %+v", tests)
+	}
+
+	create := tests[0]
+	if create.Method != "POST" || create.RequestURL != "/pets" || create.Query != "?source=curl" {
+		t.Errorf("create pet: unexpected request %+v", create)
+	}
+	if create.RequestTmpl != `{"name": "Rex"}` || create.HeadersVal["Content-Type"] != "application/json" {
+		t.Errorf("create pet: unexpected body or headers %+v", create)
+	}
+
+	get := tests[1]
+	if get.Method != "GET" || get.RequestURL != "/pets/1" {
+		t.Errorf("get pet: unexpected request %+v", get)
+	}
+	if get.HeadersVal["Authorization"] != "Basic YWRtaW46cXdlcnR5" || get.HeadersVal["X-Request-Id"] != `it's "quoted"` {
+		t.Errorf("get pet: unexpected headers %v", get.HeadersVal)
+	}
+
+	list := tests[2]
+	if list.Method != "GET" || list.Query != "?limit=10&offset=20" || list.RequestTmpl != "" {
+		t.Errorf("list pets: unexpected request %+v", list)
+	}
+	if len(c.Warnings()) != 2 {
+		t.Errorf("expected warnings for unknown flag and its value, got %v", c.Warnings())
+	}
+}
+
+func TestCurlConverter_FormData(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     string
+		body        string
+		query       string
+		contentType string
+	}{
+		{
+			name:        "data",
+			command:     `curl -d name=Rex -d age=3 localhost/pets`,
+			body:        "name=Rex&age=3",
+			contentType: "application/x-www-form-urlencoded",
+		},
+		{
+			name:        "urlencoded data",
+			command:     `curl --data-urlencode 'name=Rex the dog&co' --data-urlencode '=a/b' --data-urlencode 'tag=x+y' localhost/pets`,
+			body:        "name=Rex%20the%20dog%26co&a%2Fb&tag=x%2By",
+			contentType: "application/x-www-form-urlencoded",
+		},
+		{
+			name:        "explicit content type",
+			command:     `curl -d '{"name": "Rex"}' -H 'content-type: application/json' localhost/pets`,
+			body:        `{"name": "Rex"}`,
+			contentType: "application/json",
+		},
+		{
+			name:    "urlencoded query",
+			command: `curl -G --data-urlencode 'q=rex dog' localhost/pets`,
+			query:   "?q=rex%20dog",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCurl("", "")
+			res, err := c.convert(tt.command)
+			if err != nil {
+				t.Fatalf("convert() error = %v", err)
+			}
+			if len(res) != 1 {
+				t.Fatalf("expected one step, got %+v", res)
+			}
+			step := res[0]
+			if step.RequestTmpl != tt.body || step.Query != tt.query {
+				t.Errorf("unexpected body or query %+v", step)
+			}
+			contentType := step.HeadersVal["Content-Type"]
+			if contentType == "" {
+				contentType = step.HeadersVal["content-type"]
+			}
+			if contentType != tt.contentType {
+				t.Errorf("content type = %q, want %q", contentType, tt.contentType)
+			}
+		})
+	}
+}
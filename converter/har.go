@@ -0,0 +1,193 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// HARFilter selects entries by regular expressions on url host and path,
+// empty expression matches everything
+type HARFilter struct {
+	Host string
+	Path string
+}
+
+// skippedHARHeaders are set by the client itself or by the browser
+var skippedHARHeaders = map[string]bool{
+	"host":                      true,
+	"content-length":            true,
+	"connection":                true,
+	"accept-encoding":           true,
+	"cookie":                    true,
+	"upgrade-insecure-requests": true,
+}
+
+// HARConverter converts HAR archive to request steps, cookies and
+// authorization headers are replaced with variables so they can be supplied
+// per environment
+type HARConverter struct {
+	source    string
+	targetDir string
+	filter    HARFilter
+	warnings  []string
+	// placeholders are variables replacing recorded values, each is reported
+	// once
+	placeholders map[string]bool
+}
+
+func NewHAR(source, targetDir string, filter HARFilter) *HARConverter {
+	return &HARConverter{
+		source:    source,
+		targetDir: targetDir,
+		filter:    filter,
+	}
+}
+
+// Warnings returns variables that must be supplied to converted tests
+func (c *HARConverter) Warnings() []string {
+	return c.warnings
+}
+
+func (c *HARConverter) Convert() error {
+	data, err := os.ReadFile(c.source)
+	if err != nil {
+		return fmt.Errorf("read har file: %w", err)
+	}
+	archive := &HARArchive{}
+	if err := json.Unmarshal(data, archive); err != nil {
+		return fmt.Errorf("unmarshall har file: %w", err)
+	}
+	tests, err := c.convert(archive)
+	if err != nil {
+		return err
+	}
+	for _, v := range c.warnings {
+		log.Printf("not converted: %s", v)
+	}
+	name := strings.TrimSuffix(path.Base(c.source), path.Ext(c.source)) + ".yaml"
+
+	return writeTests(c.targetDir+"/"+name, tests)
+}
+
+func (c *HARConverter) convert(archive *HARArchive) ([]DeclarateTest, error) {
+	hostRx, err := regexp.Compile(c.filter.Host)
+	if err != nil {
+		return nil, fmt.Errorf("compile host filter: %w", err)
+	}
+	pathRx, err := regexp.Compile(c.filter.Path)
+	if err != nil {
+		return nil, fmt.Errorf("compile path filter: %w", err)
+	}
+	tests := []DeclarateTest{}
+	seen := map[string]bool{}
+	for _, e := range archive.Log.Entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		if !hostRx.MatchString(u.Host) || !pathRx.MatchString(u.Path) {
+			continue
+		}
+		test := c.entry(e, u)
+		key := strings.Join([]string{test.Method, test.RequestURL, test.Query, test.RequestTmpl}, "\n")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		tests = append(tests, test)
+	}
+
+	return tests, nil
+}
+
+func (c *HARConverter) entry(e HAREntry, u *url.URL) DeclarateTest {
+	req := e.Request
+	res := DeclarateTest{
+		Name:           req.Method + " " + u.Path,
+		Method:         req.Method,
+		RequestURL:     u.EscapedPath(),
+		ResponseStatus: e.Response.Status,
+	}
+	if u.RawQuery != "" {
+		res.Query = "?" + u.RawQuery
+	}
+	for _, h := range req.Headers {
+		name := strings.ToLower(h.Name)
+		if strings.HasPrefix(name, ":") || strings.HasPrefix(name, "sec-") || skippedHARHeaders[name] {
+			continue
+		}
+		value := h.Value
+		if name == "authorization" || name == "proxy-authorization" {
+			value = c.placeholder(res.Name, strings.ToUpper(varName(name)))
+		}
+		res.HeadersVal = withValue(res.HeadersVal, h.Name, value)
+	}
+	cookies := []string{}
+	for _, name := range cookieNames(req.Headers, req.Cookies) {
+		cookies = append(cookies, name+"="+c.placeholder(res.Name, "COOKIE_"+strings.ToUpper(varName(name))))
+	}
+	if len(cookies) > 0 {
+		res.HeadersVal = withValue(res.HeadersVal, "Cookie", strings.Join(cookies, "; "))
+	}
+	if req.PostData != nil {
+		if req.PostData.Text != "" {
+			res.RequestTmpl = req.PostData.Text
+		} else if len(req.PostData.Params) > 0 {
+			values := url.Values{}
+			for _, v := range req.PostData.Params {
+				values.Add(v.Name, v.Value)
+			}
+			res.RequestTmpl = values.Encode()
+		}
+	}
+
+	return res
+}
+
+// placeholder returns variable replacing recorded value, it is reported on
+// first use, because it is not defined in converted tests
+func (c *HARConverter) placeholder(name, variable string) string {
+	if c.placeholders == nil {
+		c.placeholders = map[string]bool{}
+	}
+	if !c.placeholders[variable] {
+		c.placeholders[variable] = true
+		c.warnings = append(c.warnings, fmt.Sprintf(
+			"%s: recorded value is replaced with `{{$%s}}`, set it in variables or environment",
+			name, variable,
+		))
+	}
+
+	return "{{$" + variable + "}}"
+}
+
+// cookieNames returns names of request cookies, they are parsed from
+// Cookie header when archive has no cookies
+func cookieNames(headers, cookies []HARRecord) []string {
+	res := []string{}
+	for _, v := range cookies {
+		res = append(res, v.Name)
+	}
+	if len(res) > 0 {
+		return res
+	}
+	for _, h := range headers {
+		if !strings.EqualFold(h.Name, "cookie") {
+			continue
+		}
+		for _, part := range strings.Split(h.Value, ";") {
+			name, _, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name != "" {
+				res = append(res, name)
+			}
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,72 @@
+package converter
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHARConverter_Convert(t *testing.T) {
+	dir := t.TempDir()
+	c := NewHAR("./testdata/session.har", dir, HARFilter{Host: `^localhost`, Path: `^/(pets|login)`})
+	if err := c.Convert(); err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	tests := readConverted(t, dir+"/session.yaml")
+	if len(tests) != 2 {
+		t.Fatalf("expected filtered and deduplicated steps, got %+v", tests)
+	}
+
+	create := tests[0]
+	if create.Method != "POST" || create.RequestURL != "/pets" || create.Query != "?source=har" {
+		t.Errorf("create pet: unexpected request %+v", create)
+	}
+	if create.RequestTmpl != `{"name":"Rex"}` || create.ResponseStatus != 201 {
+		t.Errorf("create pet: unexpected body or status %+v", create)
+	}
+	expectedHeaders := map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": "{{$AUTHORIZATION}}",
+		"Cookie":        "session={{$COOKIE_SESSION}}; theme={{$COOKIE_THEME}}",
+	}
+	if len(create.HeadersVal) != len(expectedHeaders) {
+		t.Errorf("create pet: unexpected headers %v", create.HeadersVal)
+	}
+	for k, v := range expectedHeaders {
+		if create.HeadersVal[k] != v {
+			t.Errorf("create pet: header %s expected %q, got %q", k, v, create.HeadersVal[k])
+		}
+	}
+
+	login := tests[1]
+	if login.RequestTmpl != "password=qwerty&user=admin" || login.ResponseStatus != 302 {
+		t.Errorf("login: unexpected body or status %+v", login)
+	}
+	if len(c.Warnings()) != 3 {
+		t.Errorf("expected warning for every placeholder variable, got %v", c.Warnings())
+	}
+}
+
+func TestHARConverter_CookieHeader(t *testing.T) {
+	archive := &HARArchive{}
+	data := `{"log": {"entries": [{"request": {
+		"method": "GET",
+		"url": "http://localhost:8181/pets",
+		"headers": [{"name": "cookie", "value": "session=abc; theme=dark"}],
+		"cookies": []
+	}, "response": {"status": 200}}]}}`
+	if err := json.Unmarshal([]byte(data), archive); err != nil {
+		t.Fatal(err)
+	}
+	c := NewHAR("", "", HARFilter{})
+	tests, err := c.convert(archive)
+	if err != nil {
+		t.Fatalf("convert() error = %v", err)
+	}
+	if len(tests) != 1 || tests[0].HeadersVal["Cookie"] != "session={{$COOKIE_SESSION}}; theme={{$COOKIE_THEME}}" {
+		t.Errorf("unexpected steps %+v", tests)
+	}
+	if len(c.Warnings()) != 2 {
+		t.Errorf("expected warnings for cookie variables, got %v", c.Warnings())
+	}
+}
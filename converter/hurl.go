@@ -0,0 +1,566 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/tools"
+)
+
+var (
+	hurlRequestRx   = regexp.MustCompile(`^([A-Z]+)\s+(\S+)\s*$`)
+	hurlResponseRx  = regexp.MustCompile(`^HTTP(?:/[\d.]+)?\s+(\d{3}|\*)\s*$`)
+	hurlSectionRx   = regexp.MustCompile(`^\[(\w+)\]\s*$`)
+	hurlKeyValueRx  = regexp.MustCompile(`^([^:\s]+)\s*:\s*(.*)$`)
+	hurlVarRx       = regexp.MustCompile(`{{\s*([\w-]+)\s*}}`)
+	hurlJSONPathRx  = regexp.MustCompile(`^jsonpath\s+"((?:[^"\\]|\\.)*)"\s*(.*)$`)
+	hurlPredicateRx = regexp.MustCompile(`^(not\s+)?(==|!=|>=|<=|>|<|startsWith|endsWith|contains|includes|matches|exists|isInteger|isFloat|isNumber)\s*(.*)$`)
+	hurlPathPartRx  = regexp.MustCompile(`^(?:\.([\w-]+)|\['([^']+)'\]|\[(\d+)\])`)
+)
+
+// hurlMatchers maps hurl predicates to comparer matchers
+var hurlMatchers = map[string]string{
+	"!=":         "$notEqual(%s)",
+	">":          "$gt(%s)",
+	">=":         "$gte(%s)",
+	"<":          "$lt(%s)",
+	"<=":         "$lte(%s)",
+	"startsWith": "$startsWith(%s)",
+	"endsWith":   "$endsWith(%s)",
+	"contains":   "$contains(%s)",
+}
+
+// HurlConverter converts hurl file, every hurl entry becomes request step
+type HurlConverter struct {
+	source    string
+	targetDir string
+	warnings  []string
+}
+
+func NewHurl(source, targetDir string) *HurlConverter {
+	return &HurlConverter{
+		source:    source,
+		targetDir: targetDir,
+	}
+}
+
+// Warnings returns everything that could not be translated during conversion
+func (c *HurlConverter) Warnings() []string {
+	return c.warnings
+}
+
+func (c *HurlConverter) Convert() error {
+	data, err := os.ReadFile(c.source)
+	if err != nil {
+		return fmt.Errorf("read hurl file: %w", err)
+	}
+	entries := parseHurl(string(data))
+	tests := make([]DeclarateTest, 0, len(entries))
+	for i, e := range entries {
+		name := e.name
+		if name == "" {
+			name = fmt.Sprintf("entry %d", i+1)
+		}
+		tests = append(tests, c.entry(name, e))
+	}
+	name := strings.TrimSuffix(path.Base(c.source), path.Ext(c.source)) + ".yaml"
+	if err := writeTests(c.targetDir+"/"+name, tests); err != nil {
+		return err
+	}
+	for _, v := range c.warnings {
+		log.Printf("not converted: %s", v)
+	}
+
+	return nil
+}
+
+type hurlEntry struct {
+	// name is comment right before request line
+	name     string
+	method   string
+	url      string
+	headers  [][2]string
+	sections []hurlSection
+	body     string
+	status   string
+	// response part
+	responseHeaders  [][2]string
+	responseSections []hurlSection
+	responseBody     string
+}
+
+// hurlSection keeps lines of a section, sections are kept in file order
+type hurlSection struct {
+	name  string
+	lines []string
+}
+
+// addHurlLine adds line to the section, repeated section is merged with
+// the first one
+func addHurlLine(sections []hurlSection, name, line string) []hurlSection {
+	for i := range sections {
+		if sections[i].name == name {
+			sections[i].lines = append(sections[i].lines, line)
+			return sections
+		}
+	}
+
+	return append(sections, hurlSection{name: name, lines: []string{line}})
+}
+
+// parseHurl splits hurl file to entries, every entry is a request
+// with optional response description
+func parseHurl(data string) []*hurlEntry {
+	entries := []*hurlEntry{}
+	var (
+		current   *hurlEntry
+		section   string
+		inBody    bool
+		multiline bool
+		body      []string
+		response  bool
+		comments  []string
+	)
+	flushBody := func() {
+		if current == nil || len(body) == 0 {
+			body = nil
+			return
+		}
+		b := strings.TrimSpace(strings.Join(body, "\n"))
+		if response {
+			current.responseBody = b
+		} else {
+			current.body = b
+		}
+		body = nil
+		inBody = false
+	}
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if multiline {
+			if trimmed == "```" {
+				multiline = false
+				flushBody()
+				continue
+			}
+			body = append(body, line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			if c := strings.TrimSpace(strings.TrimLeft(trimmed, "#")); c != "" {
+				comments = append(comments, c)
+			}
+			continue
+		}
+		name := strings.Join(comments, " ")
+		if trimmed != "" {
+			comments = nil
+		}
+		if m := hurlRequestRx.FindStringSubmatch(trimmed); m != nil && m[1] != "HTTP" {
+			flushBody()
+			current = &hurlEntry{
+				name:   name,
+				method: m[1],
+				url:    m[2],
+			}
+			entries = append(entries, current)
+			section, response = "", false
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if m := hurlResponseRx.FindStringSubmatch(trimmed); m != nil {
+			flushBody()
+			current.status = m[1]
+			section, response = "", true
+			continue
+		}
+		if inBody {
+			body = append(body, line)
+			continue
+		}
+		if trimmed == "" {
+			continue
+		}
+		if m := hurlSectionRx.FindStringSubmatch(trimmed); m != nil {
+			section = m[1]
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") {
+			multiline = true
+			continue
+		}
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") ||
+			strings.HasPrefix(trimmed, "`") || strings.HasPrefix(trimmed, "file,") {
+			inBody = true
+			body = append(body, line)
+			continue
+		}
+		if section != "" {
+			if response {
+				current.responseSections = addHurlLine(current.responseSections, section, trimmed)
+			} else {
+				current.sections = addHurlLine(current.sections, section, trimmed)
+			}
+			continue
+		}
+		if m := hurlKeyValueRx.FindStringSubmatch(trimmed); m != nil {
+			if response {
+				current.responseHeaders = append(current.responseHeaders, [2]string{m[1], m[2]})
+			} else {
+				current.headers = append(current.headers, [2]string{m[1], m[2]})
+			}
+		}
+	}
+	flushBody()
+
+	return entries
+}
+
+func (c *HurlConverter) entry(name string, e *hurlEntry) DeclarateTest {
+	res := DeclarateTest{
+		Name:   name,
+		Method: e.method,
+	}
+	rawURL := hurlVars(e.url)
+	if idx := strings.Index(rawURL, "?"); idx >= 0 {
+		res.Query = rawURL[idx:]
+		rawURL = rawURL[:idx]
+	}
+	res.RequestURL = c.path(name, rawURL)
+	for _, h := range e.headers {
+		res.HeadersVal = withValue(res.HeadersVal, h[0], hurlVars(h[1]))
+	}
+	c.requestSections(name, e, &res)
+	if strings.HasPrefix(e.body, "`") {
+		res.RequestTmpl = hurlVars(strings.Trim(e.body, "`"))
+	} else if strings.HasPrefix(e.body, "file,") {
+		c.warn(name, "file body")
+	} else {
+		res.RequestTmpl = hurlVars(e.body)
+	}
+
+	if e.status != "" && e.status != "*" {
+		res.ResponseStatus, _ = strconv.Atoi(e.status)
+	}
+	for _, h := range e.responseHeaders {
+		c.warn(name, fmt.Sprintf("response header assert `%s: %s`", h[0], h[1]))
+	}
+
+	var tmpl any
+	if e.responseBody != "" {
+		if err := json.Unmarshal([]byte(hurlVars(e.responseBody)), &tmpl); err != nil {
+			c.warn(name, "response body is not json")
+			tmpl = nil
+		}
+	}
+	for _, section := range e.responseSections {
+		switch section.name {
+		case "Captures":
+			for _, line := range section.lines {
+				c.capture(name, line, &res)
+			}
+		case "Asserts":
+			for _, line := range section.lines {
+				tmpl = c.assert(name, line, tmpl, &res)
+			}
+		default:
+			c.warn(name, fmt.Sprintf("response section [%s]", section.name))
+		}
+	}
+	if tmpl != nil {
+		res.ResponseTmpls = marshalTemplate(tmpl)
+	}
+
+	return res
+}
+
+func (c *HurlConverter) requestSections(name string, e *hurlEntry, res *DeclarateTest) {
+	for _, section := range e.sections {
+		switch section.name {
+		case "QueryStringParams", "Query":
+			values := []string{}
+			for _, line := range section.lines {
+				if m := hurlKeyValueRx.FindStringSubmatch(line); m != nil {
+					values = append(values, url.QueryEscape(m[1])+"="+hurlFormValue(m[2]))
+				}
+			}
+			if res.Query == "" {
+				res.Query = "?" + strings.Join(values, "&")
+			} else {
+				res.Query += "&" + strings.Join(values, "&")
+			}
+		case "FormParams", "Form":
+			values := []string{}
+			for _, line := range section.lines {
+				if m := hurlKeyValueRx.FindStringSubmatch(line); m != nil {
+					values = append(values, url.QueryEscape(m[1])+"="+hurlFormValue(m[2]))
+				}
+			}
+			res.RequestTmpl = strings.Join(values, "&")
+			res.HeadersVal = withValue(res.HeadersVal, "Content-Type", "application/x-www-form-urlencoded")
+		case "Options":
+			c.options(name, section.lines, res)
+		default:
+			c.warn(name, fmt.Sprintf("request section [%s]", section.name))
+		}
+	}
+}
+
+func hurlFormValue(s string) string {
+	s = hurlVars(s)
+	if strings.Contains(s, "{{") {
+		return s
+	}
+
+	return url.QueryEscape(s)
+}
+
+// options converts retry options to poll
+func (c *HurlConverter) options(name string, lines []string, res *DeclarateTest) {
+	retry := 0
+	interval := time.Second
+	for _, line := range lines {
+		m := hurlKeyValueRx.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		switch m[1] {
+		case "retry":
+			v, err := strconv.Atoi(m[2])
+			if err != nil || v < 0 {
+				c.warn(name, fmt.Sprintf("option `%s`", line))
+				continue
+			}
+			retry = v
+		case "retry-interval":
+			d, err := parseHurlDuration(m[2])
+			if err != nil {
+				c.warn(name, fmt.Sprintf("option `%s`", line))
+				continue
+			}
+			interval = d
+		default:
+			c.warn(name, fmt.Sprintf("option `%s`", line))
+		}
+	}
+	if retry > 0 {
+		res.Poll = &Poll{
+			Duration: interval * time.Duration(retry),
+			Interval: interval,
+		}
+	}
+}
+
+// parseHurlDuration parses duration, value without unit is in milliseconds
+func parseHurlDuration(s string) (time.Duration, error) {
+	if v, err := strconv.Atoi(s); err == nil {
+		return time.Duration(v) * time.Millisecond, nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+func (c *HurlConverter) capture(name, line string, res *DeclarateTest) {
+	m := hurlKeyValueRx.FindStringSubmatch(line)
+	if m == nil {
+		c.warn(name, fmt.Sprintf("capture `%s`", line))
+		return
+	}
+	query := strings.TrimSpace(m[2])
+	if query == "body" {
+		res.Variables = withValue(res.Variables, m[1], "*")
+		return
+	}
+	jm := hurlJSONPathRx.FindStringSubmatch(query)
+	if jm == nil || jm[2] != "" {
+		c.warn(name, fmt.Sprintf("capture `%s`", line))
+		return
+	}
+	parts, ok := jsonPathParts(jm[1])
+	if !ok {
+		c.warn(name, fmt.Sprintf("capture `%s`", line))
+		return
+	}
+	res.Variables = withValue(res.Variables, m[1], strings.Join(parts, "."))
+}
+
+// assert adds jsonpath asserts to response template, status assert becomes responseStatus
+func (c *HurlConverter) assert(name, line string, tmpl any, res *DeclarateTest) any {
+	if strings.HasPrefix(line, "status ") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[1] == "==" {
+			res.ResponseStatus, _ = strconv.Atoi(fields[2])
+			return tmpl
+		}
+		c.warn(name, fmt.Sprintf("assert `%s`", line))
+		return tmpl
+	}
+	jm := hurlJSONPathRx.FindStringSubmatch(line)
+	if jm == nil {
+		c.warn(name, fmt.Sprintf("assert `%s`", line))
+		return tmpl
+	}
+	parts, ok := jsonPathParts(jm[1])
+	if !ok || hasIndex(parts) {
+		c.warn(name, fmt.Sprintf("assert `%s`", line))
+		return tmpl
+	}
+	pm := hurlPredicateRx.FindStringSubmatch(jm[2])
+	if pm == nil || pm[1] != "" {
+		c.warn(name, fmt.Sprintf("assert `%s`", line))
+		return tmpl
+	}
+	value, ok := hurlMatcher(pm[2], hurlVars(strings.TrimSpace(pm[3])))
+	if !ok {
+		c.warn(name, fmt.Sprintf("assert `%s`", line))
+		return tmpl
+	}
+	if pm[2] == "includes" {
+		res.ComparisonParams = contract.CompareParams{
+			IgnoreArraysOrdering: tools.To(true),
+			AllowArrayExtraItems: tools.To(true),
+		}
+	}
+	if tmpl == nil {
+		tmpl = map[string]any{}
+	}
+
+	tmpl, ok = setTemplatePath(tmpl, parts, value)
+	if !ok {
+		c.warn(name, fmt.Sprintf("assert `%s`, response body is not an object", line))
+	}
+
+	return tmpl
+}
+
+func hurlMatcher(predicate, value string) (any, bool) {
+	switch predicate {
+	case "==":
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return nil, false
+		}
+		return v, true
+	case "includes":
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return nil, false
+		}
+		return []any{v}, true
+	case "matches":
+		if strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") && len(value) > 1 {
+			return "$matchRegexp(" + strings.ReplaceAll(value[1:len(value)-1], `\/`, "/") + ")", true
+		}
+		var rx string
+		if err := json.Unmarshal([]byte(value), &rx); err != nil {
+			return nil, false
+		}
+		return "$matchRegexp(" + rx + ")", true
+	case "exists":
+		return "$matchRegexp(^.*$)", true
+	case "isInteger", "isFloat", "isNumber":
+		return "$num", true
+	}
+	if f, ok := hurlMatchers[predicate]; ok && value != "" {
+		return fmt.Sprintf(f, value), true
+	}
+
+	return nil, false
+}
+
+// jsonPathParts splits simple json path like $.items[0]['name'] to parts
+func jsonPathParts(p string) ([]string, bool) {
+	if !strings.HasPrefix(p, "$") {
+		return nil, false
+	}
+	p = p[1:]
+	parts := []string{}
+	for p != "" {
+		m := hurlPathPartRx.FindStringSubmatch(p)
+		if m == nil {
+			return nil, false
+		}
+		switch {
+		case m[1] != "":
+			parts = append(parts, m[1])
+		case m[2] != "":
+			parts = append(parts, m[2])
+		default:
+			parts = append(parts, m[3])
+		}
+		p = p[len(m[0]):]
+	}
+
+	return parts, len(parts) > 0
+}
+
+func hasIndex(parts []string) bool {
+	for _, v := range parts {
+		if _, err := strconv.Atoi(v); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setTemplatePath sets value by path in response template, template is
+// returned unchanged with false when path goes through not an object
+func setTemplatePath(tmpl any, parts []string, value any) (any, bool) {
+	m, ok := tmpl.(map[string]any)
+	if !ok {
+		return tmpl, false
+	}
+	if len(parts) == 1 {
+		m[parts[0]] = value
+		return m, true
+	}
+	child, exists := m[parts[0]]
+	if !exists {
+		child = map[string]any{}
+	}
+	child, ok = setTemplatePath(child, parts[1:], value)
+	if !ok {
+		return tmpl, false
+	}
+	m[parts[0]] = child
+
+	return m, true
+}
+
+func (c *HurlConverter) path(name, raw string) string {
+	if m := postmanHostVarRx.FindStringSubmatch(raw); m != nil {
+		raw = raw[len(m[0]):]
+	} else if strings.Contains(raw, "://") {
+		host := postmanSchemeHostRx.FindString(raw)
+		c.warn(name, fmt.Sprintf("host `%s`, request is converted relative to default host", host))
+		raw = raw[len(host):]
+	}
+	if !strings.HasPrefix(raw, "/") {
+		raw = "/" + raw
+	}
+
+	return raw
+}
+
+// hurlVars converts hurl variables {{name}} to declarate ones {{$name}}
+func hurlVars(s string) string {
+	return hurlVarRx.ReplaceAllStringFunc(s, func(v string) string {
+		return "{{$" + varName(hurlVarRx.FindStringSubmatch(v)[1]) + "}}"
+	})
+}
+
+func (c *HurlConverter) warn(name, msg string) {
+	c.warnings = append(c.warnings, c.source+" "+name+": "+msg)
+}
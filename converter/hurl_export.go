@@ -0,0 +1,371 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/suite"
+	declarateVariables "github.com/ixpectus/declarate/variables"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	hurlMatcherRx     = regexp.MustCompile(`^\$(\w+)\((.*)\)$`)
+	gjsonSimplePathRx = regexp.MustCompile(`^[\w-]+(\.[\w-]+)*$`)
+	jsonPathKeyRx     = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+	varPlaceholderRx  = regexp.MustCompile(`__var_(\w+)__`)
+)
+
+// hurlPredicates maps comparer matchers to hurl predicates
+var hurlPredicates = map[string]string{
+	"notEqual":   "!=",
+	"gt":         ">",
+	"gte":        ">=",
+	"lt":         "<",
+	"lte":        "<=",
+	"startsWith": "startsWith",
+	"endsWith":   "endsWith",
+	"contains":   "contains",
+}
+
+// HurlExporter converts declarate tests to hurl files, only request
+// steps are exported, host is taken from `host` hurl variable
+type HurlExporter struct {
+	sourceDir string
+	targetDir string
+	warnings  []string
+}
+
+func NewHurlExporter(sourceDir, targetDir string) *HurlExporter {
+	return &HurlExporter{
+		sourceDir: sourceDir,
+		targetDir: targetDir,
+	}
+}
+
+// Warnings returns everything that could not be translated during conversion
+func (c *HurlExporter) Warnings() []string {
+	return c.warnings
+}
+
+type hurlStep struct {
+	Name             string                 `yaml:"name"`
+	Method           string                 `yaml:"method"`
+	RequestURL       string                 `yaml:"path"`
+	Query            string                 `yaml:"query"`
+	RequestTmpl      string                 `yaml:"request"`
+	Response         *string                `yaml:"response"`
+	ResponseStatus   *string                `yaml:"responseStatus"`
+	FullResponse     *string                `yaml:"fullResponse"`
+	HeadersVal       map[string]string      `yaml:"headers"`
+	ComparisonParams contract.CompareParams `yaml:"comparisonParams"`
+	Variables        map[string]string      `yaml:"variables"`
+	Poll             *Poll                  `yaml:"poll"`
+	Steps            []hurlStep             `yaml:"steps"`
+	Definition       *Definition            `yaml:"definition"`
+}
+
+func (c *HurlExporter) Convert() error {
+	s := suite.New(c.sourceDir, suite.RunConfig{})
+	tt, err := s.AllTests(c.sourceDir)
+	if err != nil {
+		return err
+	}
+	for _, v := range tt {
+		if !strings.HasSuffix(v, ".yaml") {
+			continue
+		}
+		data, err := os.ReadFile(v)
+		if err != nil {
+			log.Printf("failed to convert %s, %v", v, err)
+			continue
+		}
+		steps := []hurlStep{}
+		if err := yaml.Unmarshal(data, &steps); err != nil {
+			log.Printf("failed to unmarshall on convert %s, %v", v, err)
+			continue
+		}
+		entries := []string{}
+		for _, step := range steps {
+			entries = append(entries, c.steps(v, step)...)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		relatedName := strings.TrimPrefix(strings.ReplaceAll(v, c.sourceDir, ""), "/")
+		if relatedName == "" {
+			relatedName = path.Base(c.sourceDir)
+		}
+		targetFile := c.targetDir + "/" + strings.TrimSuffix(relatedName, ".yaml") + ".hurl"
+		if err := os.MkdirAll(path.Dir(targetFile), os.ModePerm); err != nil {
+			log.Printf("failed to mkdir for file %s, %v", targetFile, err)
+		}
+		if err := os.WriteFile(targetFile, []byte(strings.Join(entries, "\n\n")+"\n"), os.ModePerm); err != nil {
+			log.Printf("failed to write to file %s, %v", targetFile, err)
+		}
+	}
+	for _, v := range c.warnings {
+		log.Printf("not converted: %s", v)
+	}
+
+	return nil
+}
+
+func (c *HurlExporter) steps(file string, step hurlStep) []string {
+	if step.Definition != nil {
+		return nil
+	}
+	name := file + " " + step.Name
+	if len(step.Steps) > 0 {
+		res := []string{}
+		for _, v := range step.Steps {
+			res = append(res, c.steps(file, v)...)
+		}
+		if step.Poll != nil {
+			c.warn(name, "poll of nested steps")
+		}
+		return res
+	}
+	if step.Method == "" {
+		if step.RequestURL == "" && len(step.Variables) > 0 {
+			c.warn(name, "variables, pass them to hurl with --variable")
+		} else {
+			c.warn(name, "not a request step")
+		}
+		return nil
+	}
+
+	return []string{c.entry(name, step)}
+}
+
+func (c *HurlExporter) entry(name string, step hurlStep) string {
+	lines := []string{}
+	if step.Name != "" {
+		lines = append(lines, "# "+step.Name)
+	}
+	lines = append(lines, fmt.Sprintf("%s {{host}}%s%s", strings.ToUpper(step.Method), declarateVars(step.RequestURL), declarateVars(step.Query)))
+	headers := make([]string, 0, len(step.HeadersVal))
+	for k := range step.HeadersVal {
+		headers = append(headers, k)
+	}
+	sort.Strings(headers)
+	for _, k := range headers {
+		lines = append(lines, fmt.Sprintf("%s: %s", k, declarateVars(step.HeadersVal[k])))
+	}
+	if step.Poll != nil && step.Poll.Duration > 0 {
+		interval := step.Poll.Interval
+		if interval == 0 {
+			interval = time.Second
+		}
+		lines = append(lines,
+			"[Options]",
+			fmt.Sprintf("retry: %d", int(step.Poll.Duration/interval)),
+			fmt.Sprintf("retry-interval: %s", interval),
+		)
+	}
+	if body := strings.TrimSpace(step.RequestTmpl); body != "" {
+		if json.Valid([]byte(declarateVariables.VariableRx.ReplaceAllString(body, "2"))) {
+			lines = append(lines, declarateVars(body))
+		} else {
+			lines = append(lines, "```", declarateVars(body), "```")
+		}
+	}
+
+	status := "*"
+	response := step.Response
+	if step.ResponseStatus != nil {
+		status = strings.TrimSpace(*step.ResponseStatus)
+	}
+	if step.FullResponse != nil {
+		var full struct {
+			Body   json.RawMessage `json:"body"`
+			Status any             `json:"status"`
+		}
+		if err := json.Unmarshal([]byte(*step.FullResponse), &full); err != nil {
+			c.warn(name, "full response is not json")
+		}
+		if full.Status != nil {
+			status = fmt.Sprintf("%v", full.Status)
+		}
+		if len(full.Body) > 0 {
+			body := string(full.Body)
+			response = &body
+		}
+	}
+	if _, err := strconv.Atoi(status); err != nil && status != "*" {
+		c.warn(name, fmt.Sprintf("response status `%s`", status))
+		status = "*"
+	}
+	lines = append(lines, "HTTP "+status)
+
+	captures := c.captures(name, step.Variables)
+	if len(captures) > 0 {
+		lines = append(lines, "[Captures]")
+		lines = append(lines, captures...)
+	}
+	if response != nil && strings.TrimSpace(*response) != "" {
+		asserts := c.asserts(name, *response, step.ComparisonParams)
+		if len(asserts) > 0 {
+			lines = append(lines, "[Asserts]")
+			lines = append(lines, asserts...)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (c *HurlExporter) captures(name string, vars map[string]string) []string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	res := []string{}
+	for _, k := range keys {
+		v := vars[k]
+		if v == "*" {
+			res = append(res, fmt.Sprintf("%s: body", k))
+			continue
+		}
+		if !gjsonSimplePathRx.MatchString(v) {
+			c.warn(name, fmt.Sprintf("variable `%s: %s`", k, v))
+			continue
+		}
+		res = append(res, fmt.Sprintf("%s: jsonpath \"%s\"", k, jsonPath(strings.Split(v, "."))))
+	}
+
+	return res
+}
+
+// asserts flattens response template to jsonpath asserts
+func (c *HurlExporter) asserts(name, response string, params contract.CompareParams) []string {
+	var tmpl any
+	if err := json.Unmarshal([]byte(declarateVariables.VariableRx.ReplaceAllString(response, "__var_${1}__")), &tmpl); err != nil {
+		c.warn(name, "response is not json")
+		return nil
+	}
+	unordered := (params.IgnoreArraysOrdering != nil && *params.IgnoreArraysOrdering) ||
+		(params.AllowArrayExtraItems != nil && *params.AllowArrayExtraItems)
+	res := []string{}
+	c.flatten(name, []string{}, tmpl, unordered, &res)
+
+	return res
+}
+
+func (c *HurlExporter) flatten(name string, parts []string, v any, unordered bool, res *[]string) {
+	p := jsonPath(parts)
+	switch vv := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			c.flatten(name, append(append([]string{}, parts...), k), vv[k], unordered, res)
+		}
+	case []any:
+		if !unordered {
+			*res = append(*res, fmt.Sprintf("jsonpath \"%s\" count == %d", p, len(vv)))
+			for i, item := range vv {
+				c.flatten(name, append(append([]string{}, parts...), strconv.Itoa(i)), item, unordered, res)
+			}
+			return
+		}
+		for _, item := range vv {
+			switch item.(type) {
+			case map[string]any, []any:
+				c.warn(name, fmt.Sprintf("unordered array item at `%s`", p))
+			default:
+				*res = append(*res, fmt.Sprintf("jsonpath \"%s\" includes %s", p, hurlValue(item)))
+			}
+		}
+	case string:
+		if predicate, ok := c.predicate(name, p, vv); ok {
+			*res = append(*res, fmt.Sprintf("jsonpath \"%s\" %s", p, predicate))
+		}
+	default:
+		*res = append(*res, fmt.Sprintf("jsonpath \"%s\" == %s", p, hurlValue(vv)))
+	}
+}
+
+func (c *HurlExporter) predicate(name, p, v string) (string, bool) {
+	switch v {
+	case "$any", "$notEmpty":
+		return "exists", true
+	case "$num":
+		return "isNumber", true
+	}
+	m := hurlMatcherRx.FindStringSubmatch(v)
+	if m == nil {
+		return "== " + hurlValue(v), true
+	}
+	if m[1] == "matchRegexp" {
+		return "matches /" + strings.ReplaceAll(m[2], "/", `\/`) + "/", true
+	}
+	if predicate, ok := hurlPredicates[m[1]]; ok {
+		return predicate + " " + hurlArgument(m[1], m[2]), true
+	}
+	c.warn(name, fmt.Sprintf("matcher `%s` at `%s`", v, p))
+
+	return "", false
+}
+
+// hurlArgument renders matcher argument, arguments of string matchers are
+// quoted so hurl compares them as strings
+func hurlArgument(matcher, arg string) string {
+	var v any
+	if err := json.Unmarshal([]byte(arg), &v); err == nil {
+		return hurlValue(v)
+	}
+	switch matcher {
+	case "gt", "gte", "lt", "lte":
+		return varPlaceholderRx.ReplaceAllString(arg, "{{$1}}")
+	}
+
+	return hurlValue(arg)
+}
+
+// hurlValue renders value as json, strings with variables stay strings
+func hurlValue(v any) string {
+	if s, ok := v.(string); ok {
+		s = varPlaceholderRx.ReplaceAllString(s, "{{$1}}")
+		return strconv.Quote(s)
+	}
+	b, _ := json.Marshal(v)
+
+	return string(b)
+}
+
+func jsonPath(parts []string) string {
+	res := "$"
+	for _, v := range parts {
+		if _, err := strconv.Atoi(v); err == nil {
+			res += "[" + v + "]"
+		} else if jsonPathKeyRx.MatchString(v) {
+			res += "." + v
+		} else {
+			res += "['" + v + "']"
+		}
+	}
+
+	return res
+}
+
+// declarateVars converts declarate variables {{$name}} to hurl ones {{name}}
+func declarateVars(s string) string {
+	return declarateVariables.VariableRx.ReplaceAllString(s, "{{$1}}")
+}
+
+func (c *HurlExporter) warn(name, msg string) {
+	c.warnings = append(c.warnings, name+": "+msg)
+}
@@ -0,0 +1,163 @@
+package converter
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHurlConverter_Convert(t *testing.T) {
+	dir := t.TempDir()
+	c := NewHurl("./testdata/pets.hurl", dir)
+	if err := c.Convert(); err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	tests := readConverted(t, dir+"/pets.yaml")
+	if len(tests) != 2 {
+		t.Fatalf("expected two steps, got %+v", tests)
+	}
+
+	create := tests[0]
+	if create.Name != "create pet" || tests[1].Name != "entry 2" {
+		t.Errorf("expected names from comments, got %q and %q", create.Name, tests[1].Name)
+	}
+	if create.Method != "POST" || create.RequestURL != "/pets" || create.Query != "?source=hurl" || create.ResponseStatus != 201 {
+		t.Errorf("create pet: unexpected request %+v", create)
+	}
+	if create.HeadersVal["Authorization"] != "Bearer {{$token}}" || !strings.Contains(create.RequestTmpl, `"name": "{{$name}}"`) {
+		t.Errorf("create pet: unexpected headers or body %+v", create)
+	}
+	if create.Variables["pet_id"] != "id" || create.Variables["raw"] != "*" {
+		t.Errorf("create pet: unexpected variables %v", create.Variables)
+	}
+	var tmpl map[string]any
+	if err := json.Unmarshal([]byte(create.ResponseTmpls), &tmpl); err != nil {
+		t.Fatalf("create pet: response template is not json: %v", err)
+	}
+	owner, _ := tmpl["owner"].(map[string]any)
+	if tmpl["name"] != "Tom" || tmpl["age"] != "$gt(3)" || owner["email"] != `$matchRegexp(^.+@example\.com$)` {
+		t.Errorf("create pet: unexpected response template %v", tmpl)
+	}
+	if tags, _ := tmpl["tags"].([]any); len(tags) != 1 || tags[0] != "cat" || create.ComparisonParams.AllowArrayExtraItems == nil {
+		t.Errorf("create pet: includes should become array with extra items, got %v", tmpl)
+	}
+
+	get := tests[1]
+	if get.RequestURL != "/pets/{{$pet_id}}" || get.ResponseStatus != 200 || !strings.Contains(get.ResponseTmpls, `"{{$pet_id}}"`) {
+		t.Errorf("get pet: unexpected request %+v", get)
+	}
+	if get.Poll == nil || get.Poll.Duration != 2500*time.Millisecond || get.Poll.Interval != 500*time.Millisecond {
+		t.Errorf("get pet: unexpected poll %+v", get.Poll)
+	}
+
+	warnings := strings.Join(c.Warnings(), "\n")
+	for _, expected := range []string{
+		"capture `x_token: header \"X-Token\"`",
+		"assert `jsonpath \"$.items[0]\" == 1`",
+		"assert `header \"Content-Type\" contains \"json\"`",
+	} {
+		if !strings.Contains(warnings, expected) {
+			t.Errorf("expected warning %s, got\n%s", expected, warnings)
+		}
+	}
+}
+
+func TestHurlConverter_SectionsOrder(t *testing.T) {
+	entries := parseHurl(`GET http://localhost/pets?a=1
+[QueryStringParams]
+b: 2
+[BasicAuth]
+admin: secret
+[Query]
+c: 3
+[Cookies]
+theme: dark
+HTTP 200
+[Asserts]
+jsonpath "$.total" == 1
+[{"name": "Rex"}]
+`)
+	if len(entries) != 1 {
+		t.Fatalf("expected one entry, got %+v", entries)
+	}
+	for i := 0; i < 10; i++ {
+		c := NewHurl("", "")
+		res := c.entry("list pets", entries[0])
+		if res.Query != "?a=1&b=2&c=3" {
+			t.Fatalf("query params are not in file order: %s", res.Query)
+		}
+		expected := []string{
+			" list pets: host `http://localhost`, request is converted relative to default host",
+			" list pets: request section [BasicAuth]",
+			" list pets: request section [Cookies]",
+			" list pets: assert `jsonpath \"$.total\" == 1`, response body is not an object",
+		}
+		if strings.Join(c.Warnings(), "\n") != strings.Join(expected, "\n") {
+			t.Fatalf("unexpected warnings %q", c.Warnings())
+		}
+	}
+}
+
+func TestHurlExporter_Convert(t *testing.T) {
+	dir := t.TempDir()
+	c := NewHurlExporter("./testdata/hurl_export", dir)
+	if err := c.Convert(); err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	data, err := os.ReadFile(dir + "/pets.hurl")
+	if err != nil {
+		t.Fatalf("read exported file: %v", err)
+	}
+	res := string(data)
+	for _, expected := range []string{
+		"POST {{host}}/pets\nAuthorization: Bearer {{token}}\n{\"name\": \"{{name}}\"}\nHTTP 201",
+		"[Captures]\npet_id: jsonpath \"$.id\"",
+		"jsonpath \"$.age\" > 3",
+		"jsonpath \"$.id\" exists",
+		"jsonpath \"$.name\" == \"{{name}}\"",
+		"jsonpath \"$.owner.name\" startsWith \"Jo\"",
+		"jsonpath \"$.owner.email\" matches /^.+@example\\.com$/",
+		"jsonpath \"$.tags\" includes \"cat\"",
+		"GET {{host}}/pets/{{pet_id}}\n[Options]\nretry: 5\nretry-interval: 2s\nHTTP 200",
+		"jsonpath \"$.id\" == \"{{pet_id}}\"",
+	} {
+		if !strings.Contains(res, expected) {
+			t.Errorf("expected %s in exported file\n%s", expected, res)
+		}
+	}
+
+	warnings := strings.Join(c.Warnings(), "\n")
+	for _, expected := range []string{
+		"set variables: variables, pass them to hurl with --variable",
+		"matcher `$oneOf(\"cat\", \"dog\")` at `$.kind`",
+		"get pet in db: not a request step",
+	} {
+		if !strings.Contains(warnings, expected) {
+			t.Errorf("expected warning %s, got\n%s", expected, warnings)
+		}
+	}
+}
+
+func TestHurl_RoundTrip(t *testing.T) {
+	hurlDir, yamlDir := t.TempDir(), t.TempDir()
+	if err := NewHurlExporter("./testdata/hurl_export", hurlDir).Convert(); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+	if err := NewHurl(hurlDir+"/pets.hurl", yamlDir).Convert(); err != nil {
+		t.Fatalf("import error = %v", err)
+	}
+	tests := readConverted(t, yamlDir+"/pets.yaml")
+	if len(tests) != 2 || tests[0].Name != "create pet" || tests[1].Name != "get pet in api" {
+		t.Fatalf("expected step names to be kept, got %+v", tests)
+	}
+	var tmpl map[string]any
+	if err := json.Unmarshal([]byte(tests[0].ResponseTmpls), &tmpl); err != nil {
+		t.Fatalf("response template is not json: %v", err)
+	}
+	owner, _ := tmpl["owner"].(map[string]any)
+	if tmpl["name"] != "{{$name}}" || tmpl["age"] != "$gt(3)" || owner["name"] != `$startsWith("Jo")` {
+		t.Errorf("unexpected response template %v", tmpl)
+	}
+}
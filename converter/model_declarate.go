@@ -13,6 +13,7 @@ type DeclarateTest struct {
 	ScriptResponse   *string                `json:"script_response,omitempty" yaml:"script_response,omitempty"`
 	RequestTmpl      string                 `json:"request,omitempty" yaml:"request,omitempty"`
 	RequestURL       string                 `json:"path,omitempty" yaml:"path,omitempty"`
+	Query            string                 `json:"query,omitempty" yaml:"query,omitempty"`
 	Method           string                 `json:"method,omitempty" yaml:"method,omitempty"`
 	ResponseTmpls    string                 `json:"response,omitempty" yaml:"response,omitempty"`
 	ResponseStatus   int                    `json:"responseStatus,omitempty" yaml:"responseStatus,omitempty"`
@@ -24,6 +25,7 @@ type DeclarateTest struct {
 }
 
 type Definition struct {
-	Tags []string `yaml:"tags,omitempty"`
-	ID   int      `yaml:"id,omitempty"`
+	Tags        []string `yaml:"tags,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	ID          int      `yaml:"id,omitempty"`
 }
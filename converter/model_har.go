@@ -0,0 +1,29 @@
+package converter
+
+type HARArchive struct {
+	Log struct {
+		Entries []HAREntry `json:"entries"`
+	} `json:"log"`
+}
+
+type HAREntry struct {
+	Request struct {
+		Method   string      `json:"method"`
+		URL      string      `json:"url"`
+		Headers  []HARRecord `json:"headers"`
+		Cookies  []HARRecord `json:"cookies"`
+		PostData *struct {
+			MimeType string      `json:"mimeType"`
+			Text     string      `json:"text"`
+			Params   []HARRecord `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status int `json:"status"`
+	} `json:"response"`
+}
+
+type HARRecord struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
@@ -0,0 +1,129 @@
+package converter
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+type OpenAPIDocument struct {
+	OpenAPI    string                      `json:"openapi" yaml:"openapi"`
+	Servers    []OpenAPIServer             `json:"servers" yaml:"servers"`
+	Paths      map[string]*OpenAPIPathItem `json:"paths" yaml:"paths"`
+	Components struct {
+		Schemas       map[string]*OpenAPISchema      `json:"schemas" yaml:"schemas"`
+		Parameters    map[string]*OpenAPIParameter   `json:"parameters" yaml:"parameters"`
+		RequestBodies map[string]*OpenAPIRequestBody `json:"requestBodies" yaml:"requestBodies"`
+		Responses     map[string]*OpenAPIResponse    `json:"responses" yaml:"responses"`
+	} `json:"components" yaml:"components"`
+}
+
+type OpenAPIServer struct {
+	URL string `json:"url" yaml:"url"`
+}
+
+type OpenAPIPathItem struct {
+	Parameters []*OpenAPIParameter `json:"parameters" yaml:"parameters"`
+	Get        *OpenAPIOperation   `json:"get" yaml:"get"`
+	Put        *OpenAPIOperation   `json:"put" yaml:"put"`
+	Post       *OpenAPIOperation   `json:"post" yaml:"post"`
+	Delete     *OpenAPIOperation   `json:"delete" yaml:"delete"`
+	Options    *OpenAPIOperation   `json:"options" yaml:"options"`
+	Head       *OpenAPIOperation   `json:"head" yaml:"head"`
+	Patch      *OpenAPIOperation   `json:"patch" yaml:"patch"`
+	Trace      *OpenAPIOperation   `json:"trace" yaml:"trace"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId" yaml:"operationId"`
+	Summary     string                      `json:"summary" yaml:"summary"`
+	Tags        []string                    `json:"tags" yaml:"tags"`
+	Parameters  []*OpenAPIParameter         `json:"parameters" yaml:"parameters"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody" yaml:"requestBody"`
+	Responses   map[string]*OpenAPIResponse `json:"responses" yaml:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Ref      string         `json:"$ref" yaml:"$ref"`
+	Name     string         `json:"name" yaml:"name"`
+	In       string         `json:"in" yaml:"in"`
+	Required bool           `json:"required" yaml:"required"`
+	Example  any            `json:"example" yaml:"example"`
+	Schema   *OpenAPISchema `json:"schema" yaml:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Ref     string                       `json:"$ref" yaml:"$ref"`
+	Content map[string]*OpenAPIMediaType `json:"content" yaml:"content"`
+}
+
+type OpenAPIResponse struct {
+	Ref         string                       `json:"$ref" yaml:"$ref"`
+	Description string                       `json:"description" yaml:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content" yaml:"content"`
+}
+
+type OpenAPIMediaType struct {
+	Schema   *OpenAPISchema `json:"schema" yaml:"schema"`
+	Example  any            `json:"example" yaml:"example"`
+	Examples map[string]struct {
+		Value any `json:"value" yaml:"value"`
+	} `json:"examples" yaml:"examples"`
+}
+
+type OpenAPISchema struct {
+	Ref        string                    `json:"$ref" yaml:"$ref"`
+	Type       OpenAPISchemaType         `json:"type" yaml:"type"`
+	Format     string                    `json:"format" yaml:"format"`
+	Nullable   bool                      `json:"nullable" yaml:"nullable"`
+	Properties map[string]*OpenAPISchema `json:"properties" yaml:"properties"`
+	Required   []string                  `json:"required" yaml:"required"`
+	Items      *OpenAPISchema            `json:"items" yaml:"items"`
+	Enum       []any                     `json:"enum" yaml:"enum"`
+	Example    any                       `json:"example" yaml:"example"`
+	Default    any                       `json:"default" yaml:"default"`
+	AllOf      []*OpenAPISchema          `json:"allOf" yaml:"allOf"`
+	OneOf      []*OpenAPISchema          `json:"oneOf" yaml:"oneOf"`
+	AnyOf      []*OpenAPISchema          `json:"anyOf" yaml:"anyOf"`
+}
+
+// OpenAPISchemaType holds schema type, in OpenAPI 3.1 it can be a list
+// of types, for example ["string", "null"]
+type OpenAPISchemaType []string
+
+func (t *OpenAPISchemaType) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*t = OpenAPISchemaType{value.Value}
+		return nil
+	case yaml.SequenceNode:
+		res := []string{}
+		if err := value.Decode(&res); err != nil {
+			return err
+		}
+		*t = res
+		return nil
+	}
+
+	return fmt.Errorf("unexpected schema type at line %d", value.Line)
+}
+
+func (t OpenAPISchemaType) main() string {
+	for _, v := range t {
+		if v != "null" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func (t OpenAPISchemaType) nullable() bool {
+	for _, v := range t {
+		if v == "null" {
+			return true
+		}
+	}
+
+	return false
+}
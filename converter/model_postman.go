@@ -0,0 +1,124 @@
+package converter
+
+import (
+	"encoding/json"
+)
+
+type PostmanCollection struct {
+	Info struct {
+		Name string `json:"name"`
+	} `json:"info"`
+	Item     []PostmanItem     `json:"item"`
+	Variable []PostmanVariable `json:"variable"`
+	Event    []PostmanEvent    `json:"event"`
+	Auth     *PostmanAuth      `json:"auth"`
+}
+
+type PostmanEnvironment struct {
+	Name   string            `json:"name"`
+	Values []PostmanVariable `json:"values"`
+}
+
+type PostmanVariable struct {
+	Key      string `json:"key"`
+	Value    any    `json:"value"`
+	Enabled  *bool  `json:"enabled"`
+	Disabled bool   `json:"disabled"`
+}
+
+func (v PostmanVariable) isEnabled() bool {
+	return !v.Disabled && (v.Enabled == nil || *v.Enabled)
+}
+
+type PostmanItem struct {
+	Name    string          `json:"name"`
+	Item    []PostmanItem   `json:"item"`
+	Request *PostmanRequest `json:"request"`
+	Event   []PostmanEvent  `json:"event"`
+	Auth    *PostmanAuth    `json:"auth"`
+}
+
+func (i PostmanItem) isFolder() bool {
+	return i.Request == nil
+}
+
+type PostmanRequest struct {
+	Method string          `json:"method"`
+	Header []PostmanHeader `json:"header"`
+	URL    PostmanURL      `json:"url"`
+	Body   *PostmanBody    `json:"body"`
+	Auth   *PostmanAuth    `json:"auth"`
+}
+
+type PostmanHeader struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// PostmanURL can be described as a string or as an object
+type PostmanURL struct {
+	Raw      string            `json:"raw"`
+	Variable []PostmanVariable `json:"variable"`
+}
+
+func (u *PostmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		u.Raw = raw
+		return nil
+	}
+	type plain PostmanURL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+type PostmanBody struct {
+	Mode       string          `json:"mode"`
+	Raw        string          `json:"raw"`
+	URLEncoded []PostmanHeader `json:"urlencoded"`
+	FormData   []PostmanHeader `json:"formdata"`
+	Disabled   bool            `json:"disabled"`
+}
+
+type PostmanAuth struct {
+	Type   string            `json:"type"`
+	Bearer []PostmanVariable `json:"bearer"`
+	Basic  []PostmanVariable `json:"basic"`
+}
+
+func (a *PostmanAuth) param(params []PostmanVariable, key string) string {
+	for _, v := range params {
+		if v.Key == key {
+			if s, ok := v.Value.(string); ok {
+				return s
+			}
+		}
+	}
+
+	return ""
+}
+
+type PostmanEvent struct {
+	Listen string `json:"listen"`
+	Script struct {
+		Exec PostmanExec `json:"exec"`
+	} `json:"script"`
+}
+
+// PostmanExec is a script source, it can be a string or a list of lines
+type PostmanExec []string
+
+func (e *PostmanExec) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*e = PostmanExec{raw}
+		return nil
+	}
+	lines := []string{}
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return err
+	}
+	*e = lines
+
+	return nil
+}
@@ -0,0 +1,599 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/tools"
+	"gopkg.in/yaml.v3"
+)
+
+const maxSchemaDepth = 10
+
+var (
+	openAPIPathParamRx = regexp.MustCompile(`{([^}]+)}`)
+	nonWordRx          = regexp.MustCompile(`\W+`)
+	openAPIMethods     = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+)
+
+// OpenAPIConverter generates skeleton tests from OpenAPI 3 document,
+// one test file for every operation
+type OpenAPIConverter struct {
+	source    string
+	targetDir string
+	doc       *OpenAPIDocument
+}
+
+func NewOpenAPI(source, targetDir string) *OpenAPIConverter {
+	return &OpenAPIConverter{
+		source:    source,
+		targetDir: targetDir,
+	}
+}
+
+func (c *OpenAPIConverter) Convert() error {
+	data, err := os.ReadFile(c.source)
+	if err != nil {
+		return fmt.Errorf("read openapi document: %w", err)
+	}
+	doc := &OpenAPIDocument{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return fmt.Errorf("unmarshall openapi document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3") {
+		return fmt.Errorf("unsupported openapi version `%s`, only 3.x is supported", doc.OpenAPI)
+	}
+	c.doc = doc
+
+	for _, file := range c.files() {
+		if err := writeTests(c.targetDir+"/"+file.name, file.tests); err != nil {
+			log.Println(err)
+		}
+	}
+
+	return nil
+}
+
+type convertedFile struct {
+	name  string
+	tests []DeclarateTest
+}
+
+func (c *OpenAPIConverter) files() []convertedFile {
+	paths := make([]string, 0, len(c.doc.Paths))
+	for k := range c.doc.Paths {
+		paths = append(paths, k)
+	}
+	sort.Strings(paths)
+
+	res := []convertedFile{}
+	used := map[string]bool{}
+	for _, p := range paths {
+		item := c.doc.Paths[p]
+		if item == nil {
+			continue
+		}
+		for _, method := range openAPIMethods {
+			op := item.operation(method)
+			if op == nil {
+				continue
+			}
+			name := c.fileName(method, p, op)
+			for i := 2; used[name]; i++ {
+				name = strings.TrimSuffix(name, ".yaml") + "_" + strconv.Itoa(i) + ".yaml"
+			}
+			used[name] = true
+			res = append(res, convertedFile{
+				name:  name,
+				tests: c.operationTests(method, p, item, op),
+			})
+		}
+	}
+
+	return res
+}
+
+func (p *OpenAPIPathItem) operation(method string) *OpenAPIOperation {
+	switch method {
+	case "get":
+		return p.Get
+	case "put":
+		return p.Put
+	case "post":
+		return p.Post
+	case "delete":
+		return p.Delete
+	case "options":
+		return p.Options
+	case "head":
+		return p.Head
+	case "patch":
+		return p.Patch
+	case "trace":
+		return p.Trace
+	}
+
+	return nil
+}
+
+func (c *OpenAPIConverter) fileName(method, path string, op *OpenAPIOperation) string {
+	name := op.OperationID
+	if name == "" {
+		name = method + "_" + path
+	}
+	name = strings.Trim(nonWordRx.ReplaceAllString(name, "_"), "_")
+	if len(op.Tags) > 0 {
+		return strings.Trim(nonWordRx.ReplaceAllString(op.Tags[0], "_"), "_") + "/" + name + ".yaml"
+	}
+
+	return name + ".yaml"
+}
+
+func (c *OpenAPIConverter) operationTests(
+	method string,
+	path string,
+	item *OpenAPIPathItem,
+	op *OpenAPIOperation,
+) []DeclarateTest {
+	name := op.OperationID
+	if name == "" {
+		name = strings.ToUpper(method) + " " + path
+	}
+	tests := []DeclarateTest{}
+	if len(op.Tags) > 0 || op.Summary != "" {
+		tests = append(tests, DeclarateTest{
+			Definition: &Definition{
+				Tags:        op.Tags,
+				Description: op.Summary,
+			},
+		})
+	}
+
+	vars := map[string]string{}
+	query := url.Values{}
+	headers := map[string]string{}
+	for _, param := range c.parameters(item.Parameters, op.Parameters) {
+		example := c.paramExample(param)
+		switch param.In {
+		case "path":
+			vars[varName(param.Name)] = example
+		case "query":
+			if param.Required {
+				query.Set(param.Name, example)
+			}
+		case "header":
+			if param.Required {
+				headers[param.Name] = example
+			}
+		}
+	}
+	if len(vars) > 0 {
+		tests = append(tests, DeclarateTest{
+			Name:      "set " + name + " parameters",
+			Variables: vars,
+		})
+	}
+
+	request := DeclarateTest{
+		Method: strings.ToUpper(method),
+		RequestURL: c.basePath() + openAPIPathParamRx.ReplaceAllStringFunc(path, func(s string) string {
+			return "{{$" + varName(strings.Trim(s, "{}")) + "}}"
+		}),
+	}
+	if len(query) > 0 {
+		request.Query = "?" + query.Encode()
+	}
+	if len(headers) > 0 {
+		request.HeadersVal = headers
+	}
+	if body := c.resolveRequestBody(op.RequestBody); body != nil {
+		if mediaType, contentType := jsonMediaType(body.Content); mediaType != nil {
+			if example, ok := c.mediaTypeExample(mediaType); ok {
+				request.RequestTmpl = marshalTemplate(example)
+			}
+			if contentType != "application/json" {
+				request.HeadersVal = withValue(request.HeadersVal, "Content-Type", contentType)
+			}
+		}
+	}
+
+	codes := responseCodes(op.Responses)
+	if len(codes) == 0 {
+		request.Name = name
+		tests = append(tests, request)
+	}
+	for _, code := range codes {
+		step := request
+		step.Name = name
+		if !strings.HasPrefix(code, "2") {
+			step.Name = name + " responds " + code
+		}
+		step.ResponseStatus, _ = strconv.Atoi(code)
+		resp := c.resolveResponse(op.Responses[code])
+		if resp != nil {
+			if mediaType, _ := jsonMediaType(resp.Content); mediaType != nil && mediaType.Schema != nil {
+				if tmpl, ok := c.schemaTemplate(mediaType.Schema, 0); ok {
+					step.ResponseTmpls = marshalTemplate(tmpl)
+					step.ComparisonParams = contract.CompareParams{
+						IgnoreArraysOrdering: tools.To(true),
+						AllowArrayExtraItems: tools.To(true),
+					}
+				}
+			}
+		}
+		tests = append(tests, step)
+	}
+
+	return tests
+}
+
+// responseCodes returns documented response status codes, successful first,
+// ranges like 2XX and default response are skipped
+func responseCodes(responses map[string]*OpenAPIResponse) []string {
+	codes := []string{}
+	for k := range responses {
+		if _, err := strconv.Atoi(k); err == nil {
+			codes = append(codes, k)
+		}
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		iSuccess := strings.HasPrefix(codes[i], "2")
+		jSuccess := strings.HasPrefix(codes[j], "2")
+		if iSuccess != jSuccess {
+			return iSuccess
+		}
+
+		return codes[i] < codes[j]
+	})
+
+	return codes
+}
+
+func (c *OpenAPIConverter) basePath() string {
+	if len(c.doc.Servers) == 0 {
+		return ""
+	}
+	u, err := url.Parse(c.doc.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// parameters merges path level and operation level parameters,
+// operation parameters override path ones with the same name and location
+func (c *OpenAPIConverter) parameters(pathParams, opParams []*OpenAPIParameter) []*OpenAPIParameter {
+	res := []*OpenAPIParameter{}
+	index := map[string]int{}
+	all := append(append([]*OpenAPIParameter{}, pathParams...), opParams...)
+	for _, v := range all {
+		p := c.resolveParameter(v)
+		if p == nil {
+			continue
+		}
+		key := p.In + ":" + p.Name
+		if i, ok := index[key]; ok {
+			res[i] = p
+			continue
+		}
+		index[key] = len(res)
+		res = append(res, p)
+	}
+
+	return res
+}
+
+func (c *OpenAPIConverter) paramExample(p *OpenAPIParameter) string {
+	example := p.Example
+	if example == nil && p.Schema != nil {
+		example, _ = c.schemaExample(p.Schema, 0)
+	}
+	if example == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%v", example)
+}
+
+func (c *OpenAPIConverter) mediaTypeExample(m *OpenAPIMediaType) (any, bool) {
+	if m.Example != nil {
+		return m.Example, true
+	}
+	names := make([]string, 0, len(m.Examples))
+	for k := range m.Examples {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if m.Examples[k].Value != nil {
+			return m.Examples[k].Value, true
+		}
+	}
+	if m.Schema != nil {
+		return c.schemaExample(m.Schema, 0)
+	}
+
+	return nil, false
+}
+
+// schemaExample builds example value for schema, preferring examples,
+// defaults and enums declared in the document
+func (c *OpenAPIConverter) schemaExample(s *OpenAPISchema, depth int) (any, bool) {
+	s = c.resolveSchema(s)
+	if s == nil || depth > maxSchemaDepth {
+		return nil, false
+	}
+	if s.Example != nil {
+		return s.Example, true
+	}
+	if s.Default != nil {
+		return s.Default, true
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0], true
+	}
+	if len(s.AllOf) > 0 {
+		res := map[string]any{}
+		for _, v := range s.AllOf {
+			if part, ok := c.schemaExample(v, depth+1); ok {
+				if m, ok := part.(map[string]any); ok {
+					for k, v := range m {
+						res[k] = v
+					}
+				}
+			}
+		}
+		return res, true
+	}
+	if len(s.OneOf) > 0 {
+		return c.schemaExample(s.OneOf[0], depth+1)
+	}
+	if len(s.AnyOf) > 0 {
+		return c.schemaExample(s.AnyOf[0], depth+1)
+	}
+
+	switch s.schemaType() {
+	case "object":
+		res := map[string]any{}
+		for k, v := range s.Properties {
+			if example, ok := c.schemaExample(v, depth+1); ok {
+				res[k] = example
+			}
+		}
+		return res, true
+	case "array":
+		if example, ok := c.schemaExample(s.Items, depth+1); ok {
+			return []any{example}, true
+		}
+		return []any{}, true
+	case "string":
+		switch s.Format {
+		case "date-time":
+			return "2023-01-01T00:00:00Z", true
+		case "date":
+			return "2023-01-01", true
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000", true
+		case "email":
+			return "user@example.com", true
+		}
+		return "string", true
+	case "integer":
+		return 0, true
+	case "number":
+		return 0.0, true
+	case "boolean":
+		return true, true
+	}
+
+	return nil, false
+}
+
+// schemaTemplate builds expected response with type matchers,
+// optional properties are omitted since they could be absent in the response
+func (c *OpenAPIConverter) schemaTemplate(s *OpenAPISchema, depth int) (any, bool) {
+	s = c.resolveSchema(s)
+	if s == nil || depth > maxSchemaDepth {
+		return nil, false
+	}
+	if s.Nullable || s.Type.nullable() {
+		if t := s.schemaType(); t != "object" && t != "array" {
+			return "$matchRegexp(^.*$)", true
+		}
+		return nil, false
+	}
+	if len(s.Enum) > 0 {
+		items := make([]string, 0, len(s.Enum))
+		for _, v := range s.Enum {
+			if str, ok := v.(string); ok {
+				items = append(items, strconv.Quote(str))
+			} else {
+				items = append(items, fmt.Sprintf("%v", v))
+			}
+		}
+		return fmt.Sprintf("$oneOf(%s)", strings.Join(items, ", ")), true
+	}
+	if len(s.AllOf) > 0 {
+		res := map[string]any{}
+		for _, v := range s.AllOf {
+			part, ok := c.schemaTemplate(v, depth+1)
+			if !ok {
+				continue
+			}
+			if m, ok := part.(map[string]any); ok {
+				for k, v := range m {
+					res[k] = v
+				}
+			}
+		}
+		return res, true
+	}
+	if len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		return nil, false
+	}
+
+	switch s.schemaType() {
+	case "object":
+		res := map[string]any{}
+		for _, k := range s.Required {
+			if tmpl, ok := c.schemaTemplate(s.Properties[k], depth+1); ok {
+				res[k] = tmpl
+			}
+		}
+		return res, true
+	case "array":
+		if tmpl, ok := c.schemaTemplate(s.Items, depth+1); ok {
+			return []any{tmpl}, true
+		}
+		return []any{}, true
+	case "string":
+		switch s.Format {
+		case "date-time":
+			return `$matchRegexp(^\d{4}-\d{2}-\d{2}T.+$)`, true
+		case "date":
+			return `$matchRegexp(^\d{4}-\d{2}-\d{2}$)`, true
+		case "uuid":
+			return `$matchRegexp(^[0-9a-fA-F-]{36}$)`, true
+		case "email":
+			return `$matchRegexp(^.+@.+$)`, true
+		}
+		return "$matchRegexp(^.*$)", true
+	case "integer", "number":
+		return "$num", true
+	case "boolean":
+		return "$matchRegexp(^(true|false)$)", true
+	}
+
+	return nil, false
+}
+
+func (s *OpenAPISchema) schemaType() string {
+	if t := s.Type.main(); t != "" {
+		return t
+	}
+	if len(s.Properties) > 0 {
+		return "object"
+	}
+	if s.Items != nil {
+		return "array"
+	}
+
+	return ""
+}
+
+func (c *OpenAPIConverter) resolveSchema(s *OpenAPISchema) *OpenAPISchema {
+	for i := 0; s != nil && s.Ref != "" && i < maxSchemaDepth; i++ {
+		s = c.doc.Components.Schemas[refName(s.Ref)]
+	}
+
+	return s
+}
+
+func (c *OpenAPIConverter) resolveParameter(p *OpenAPIParameter) *OpenAPIParameter {
+	if p != nil && p.Ref != "" {
+		return c.doc.Components.Parameters[refName(p.Ref)]
+	}
+
+	return p
+}
+
+func (c *OpenAPIConverter) resolveRequestBody(b *OpenAPIRequestBody) *OpenAPIRequestBody {
+	if b != nil && b.Ref != "" {
+		return c.doc.Components.RequestBodies[refName(b.Ref)]
+	}
+
+	return b
+}
+
+func (c *OpenAPIConverter) resolveResponse(r *OpenAPIResponse) *OpenAPIResponse {
+	if r != nil && r.Ref != "" {
+		return c.doc.Components.Responses[refName(r.Ref)]
+	}
+
+	return r
+}
+
+// refName returns component name from local reference like #/components/schemas/Pet
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+func jsonMediaType(content map[string]*OpenAPIMediaType) (*OpenAPIMediaType, string) {
+	if m, ok := content["application/json"]; ok {
+		return m, "application/json"
+	}
+	types := make([]string, 0, len(content))
+	for k := range content {
+		types = append(types, k)
+	}
+	sort.Strings(types)
+	for _, k := range types {
+		if strings.Contains(k, "json") {
+			return content[k], k
+		}
+	}
+
+	return nil, ""
+}
+
+func varName(name string) string {
+	return nonWordRx.ReplaceAllString(name, "_")
+}
+
+func withValue(m map[string]string, k, v string) map[string]string {
+	if m == nil {
+		m = map[string]string{}
+	}
+	m[k] = v
+
+	return m
+}
+
+func marshalTemplate(v any) string {
+	v = toJSONCompatible(v)
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return ""
+	}
+
+	return buf.String()
+}
+
+// toJSONCompatible converts maps decoded from yaml to maps with string keys
+func toJSONCompatible(v any) any {
+	switch vv := v.(type) {
+	case map[string]any:
+		res := make(map[string]any, len(vv))
+		for k, v := range vv {
+			res[k] = toJSONCompatible(v)
+		}
+		return res
+	case map[any]any:
+		res := make(map[string]any, len(vv))
+		for k, v := range vv {
+			res[fmt.Sprintf("%v", k)] = toJSONCompatible(v)
+		}
+		return res
+	case []any:
+		res := make([]any, len(vv))
+		for i, v := range vv {
+			res[i] = toJSONCompatible(v)
+		}
+		return res
+	}
+
+	return v
+}
@@ -0,0 +1,78 @@
+package converter
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func readConverted(t *testing.T, file string) []DeclarateTest {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("read converted file: %v", err)
+	}
+	res := []DeclarateTest{}
+	if err := yaml.Unmarshal(data, &res); err != nil {
+		t.Fatalf("unmarshal converted file: %v", err)
+	}
+
+	return res
+}
+
+func TestOpenAPIConverter_Convert(t *testing.T) {
+	dir := t.TempDir()
+	if err := NewOpenAPI("./testdata/openapi.yaml", dir).Convert(); err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	list := readConverted(t, dir+"/pets/listPets.yaml")
+	if len(list) != 2 {
+		t.Fatalf("listPets: expected definition and request, got %+v", list)
+	}
+	if list[0].Definition == nil || list[0].Definition.Tags[0] != "pets" {
+		t.Errorf("listPets: expected pets tag, got %+v", list[0].Definition)
+	}
+	req := list[1]
+	if req.Method != "GET" || req.RequestURL != "/v1/pets" || req.Query != "?limit=10" || req.ResponseStatus != 200 {
+		t.Errorf("listPets: unexpected request %+v", req)
+	}
+	var tmpl []map[string]any
+	if err := json.Unmarshal([]byte(req.ResponseTmpls), &tmpl); err != nil {
+		t.Fatalf("listPets: response template is not json: %v", err)
+	}
+	if tmpl[0]["status"] != `$oneOf("available", "sold")` || tmpl[0]["id"] != "$matchRegexp(^[0-9a-fA-F-]{36}$)" {
+		t.Errorf("listPets: unexpected response template %v", tmpl)
+	}
+	if _, ok := tmpl[0]["age"]; ok {
+		t.Errorf("listPets: optional field should be omitted, got %v", tmpl)
+	}
+
+	create := readConverted(t, dir+"/pets/createPet.yaml")
+	if len(create) != 3 || create[1].ResponseStatus != 201 || create[2].ResponseStatus != 400 {
+		t.Fatalf("createPet: expected steps for 201 and 400, got %+v", create)
+	}
+	var body map[string]any
+	if err := json.Unmarshal([]byte(create[1].RequestTmpl), &body); err != nil {
+		t.Fatalf("createPet: request is not json: %v", err)
+	}
+	if body["name"] != "Tom" || body["status"] != "available" {
+		t.Errorf("createPet: unexpected request example %v", body)
+	}
+
+	show := readConverted(t, dir+"/pets/showPet.yaml")
+	if len(show) != 3 {
+		t.Fatalf("showPet: expected definition, variables and request, got %+v", show)
+	}
+	if show[0].Definition.Description != "show pet by id" {
+		t.Errorf("showPet: unexpected definition %+v", show[0].Definition)
+	}
+	if show[1].Variables["petId"] != "00000000-0000-0000-0000-000000000000" {
+		t.Errorf("showPet: unexpected variables %v", show[1].Variables)
+	}
+	if show[2].RequestURL != "/v1/pets/{{$petId}}" {
+		t.Errorf("showPet: unexpected path %v", show[2].RequestURL)
+	}
+}
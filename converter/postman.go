@@ -0,0 +1,401 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	postmanVarRx        = regexp.MustCompile(`{{\s*([^{}$\s][^{}]*?)\s*}}`)
+	postmanDynamicVarRx = regexp.MustCompile(`{{\s*\$(\w+)\s*}}`)
+	postmanPathVarRx    = regexp.MustCompile(`/:(\w+)`)
+	postmanHostVarRx    = regexp.MustCompile(`^{{\s*([^{}]+?)\s*}}`)
+	postmanSchemeHostRx = regexp.MustCompile(`^(https?://)?[^/?]+`)
+	postmanSetVarRx     = regexp.MustCompile(`^pm\.(environment|collectionVariables|globals|variables)\.set\(\s*["']([^"']+)["']\s*,\s*(.+?)\s*\)\s*;?$`)
+	postmanJSONAliasRx  = regexp.MustCompile(`^(?:var|let|const)\s+(\w+)\s*=\s*(?:pm\.response\.json\(\)|JSON\.parse\(\s*responseBody\s*\))\s*;?$`)
+	postmanStatusRx     = regexp.MustCompile(`^pm\.response\.to\.have\.status\(\s*(\d+)\s*\)\s*;?$`)
+	postmanAccessorRx   = regexp.MustCompile(`^(?:\.(\w+)|\[\s*(\d+)\s*\]|\[\s*["']([^"']+)["']\s*\])`)
+	postmanIgnoredRx    = regexp.MustCompile(`^(//.*|[})\];]*|pm\.test\(.*function\s*\(\)\s*{|pm\.test\(.*\(\)\s*=>\s*{)$`)
+)
+
+// postmanDynamicVars maps postman dynamic variables to declarate functions
+var postmanDynamicVars = map[string]string{
+	"randomFirstName": "$(randFirstName())",
+	"randomFullName":  "$(randName())",
+	"randomInt":       "$(randInt32())",
+	"randomUserName":  "$(randLogin())",
+}
+
+// PostmanConverter converts postman collection, top level folders become
+// test files, nested folders become steps
+type PostmanConverter struct {
+	source      string
+	environment string
+	targetDir   string
+	warnings    []string
+	hostVars    map[string]bool
+}
+
+func NewPostman(source, environment, targetDir string) *PostmanConverter {
+	return &PostmanConverter{
+		source:      source,
+		environment: environment,
+		targetDir:   targetDir,
+		hostVars:    map[string]bool{},
+	}
+}
+
+// Warnings returns everything that could not be translated during conversion
+func (c *PostmanConverter) Warnings() []string {
+	return c.warnings
+}
+
+func (c *PostmanConverter) Convert() error {
+	data, err := os.ReadFile(c.source)
+	if err != nil {
+		return fmt.Errorf("read postman collection: %w", err)
+	}
+	collection := &PostmanCollection{}
+	if err := json.Unmarshal(data, collection); err != nil {
+		return fmt.Errorf("unmarshall postman collection: %w", err)
+	}
+	vars := map[string]string{}
+	if c.environment != "" {
+		data, err := os.ReadFile(c.environment)
+		if err != nil {
+			return fmt.Errorf("read postman environment: %w", err)
+		}
+		env := &PostmanEnvironment{}
+		if err := json.Unmarshal(data, env); err != nil {
+			return fmt.Errorf("unmarshall postman environment: %w", err)
+		}
+		c.addVariables(vars, env.Values)
+	}
+	c.addVariables(vars, collection.Variable)
+
+	for _, file := range c.files(collection, vars) {
+		if err := writeTests(c.targetDir+"/"+file.name, file.tests); err != nil {
+			log.Println(err)
+		}
+	}
+	for _, v := range c.warnings {
+		log.Printf("not converted: %s", v)
+	}
+
+	return nil
+}
+
+func (c *PostmanConverter) files(collection *PostmanCollection, vars map[string]string) []convertedFile {
+	for _, e := range collection.Event {
+		c.warnScript(collection.Info.Name, e)
+	}
+	res := []convertedFile{}
+	rootItems := []PostmanItem{}
+	for _, item := range collection.Item {
+		if !item.isFolder() {
+			rootItems = append(rootItems, item)
+			continue
+		}
+		res = append(res, convertedFile{
+			name:  fileNameFor(item.Name),
+			tests: c.fileTests(vars, c.items(item.Name, item.Item, inheritAuth(item.Auth, collection.Auth))),
+		})
+	}
+	if len(rootItems) > 0 {
+		name := collection.Info.Name
+		if name == "" {
+			name = "collection"
+		}
+		res = append(res, convertedFile{
+			name:  fileNameFor(name),
+			tests: c.fileTests(vars, c.items(name, rootItems, collection.Auth)),
+		})
+	}
+
+	return res
+}
+
+func (c *PostmanConverter) fileTests(vars map[string]string, steps []DeclarateTest) []DeclarateTest {
+	tests := []DeclarateTest{}
+	if len(vars) > 0 {
+		tests = append(tests, DeclarateTest{
+			Name:      "set collection variables",
+			Variables: vars,
+		})
+	}
+
+	return append(tests, steps...)
+}
+
+func (c *PostmanConverter) items(parent string, items []PostmanItem, auth *PostmanAuth) []DeclarateTest {
+	res := make([]DeclarateTest, 0, len(items))
+	for _, item := range items {
+		itemPath := parent + "/" + item.Name
+		if item.isFolder() {
+			for _, e := range item.Event {
+				c.warnScript(itemPath, e)
+			}
+			res = append(res, DeclarateTest{
+				Name:  item.Name,
+				Steps: c.items(itemPath, item.Item, inheritAuth(item.Auth, auth)),
+			})
+			continue
+		}
+		res = append(res, c.request(itemPath, item, inheritAuth(item.Request.Auth, inheritAuth(item.Auth, auth))))
+	}
+
+	return res
+}
+
+func inheritAuth(auth, parent *PostmanAuth) *PostmanAuth {
+	if auth == nil || auth.Type == "inherit" {
+		return parent
+	}
+
+	return auth
+}
+
+func (c *PostmanConverter) request(itemPath string, item PostmanItem, auth *PostmanAuth) DeclarateTest {
+	req := item.Request
+	res := DeclarateTest{
+		Name:   item.Name,
+		Method: strings.ToUpper(req.Method),
+	}
+	if res.Method == "" {
+		res.Method = "GET"
+	}
+
+	raw := req.URL.Raw
+	if idx := strings.Index(raw, "?"); idx >= 0 {
+		res.Query = c.vars(itemPath, raw[idx:])
+		raw = raw[:idx]
+	}
+	res.RequestURL = c.path(itemPath, raw)
+	c.applyRequest(itemPath, item, auth, &res)
+
+	pathVars := map[string]string{}
+	for _, v := range req.URL.Variable {
+		if s, ok := v.Value.(string); ok && s != "" {
+			pathVars[varName(v.Key)] = c.vars(itemPath, s)
+		}
+	}
+	if len(pathVars) == 0 {
+		return res
+	}
+
+	// path variables should be set before request
+	return DeclarateTest{
+		Name: item.Name,
+		Steps: []DeclarateTest{
+			{Name: "set path variables", Variables: pathVars},
+			res,
+		},
+	}
+}
+
+func (c *PostmanConverter) applyRequest(itemPath string, item PostmanItem, auth *PostmanAuth, res *DeclarateTest) {
+	req := item.Request
+	for _, h := range req.Header {
+		if h.Disabled {
+			continue
+		}
+		res.HeadersVal = withValue(res.HeadersVal, h.Key, c.vars(itemPath, h.Value))
+	}
+	if auth != nil {
+		switch auth.Type {
+		case "noauth":
+		case "bearer":
+			token := c.vars(itemPath, auth.param(auth.Bearer, "token"))
+			res.HeadersVal = withValue(res.HeadersVal, "Authorization", "Bearer "+token)
+		default:
+			c.warn(itemPath, fmt.Sprintf("auth type `%s`", auth.Type))
+		}
+	}
+	if req.Body != nil && !req.Body.Disabled {
+		switch req.Body.Mode {
+		case "raw":
+			res.RequestTmpl = c.vars(itemPath, req.Body.Raw)
+		case "urlencoded":
+			values := []string{}
+			for _, v := range req.Body.URLEncoded {
+				if !v.Disabled {
+					values = append(values, c.formValue(itemPath, v.Key)+"="+c.formValue(itemPath, v.Value))
+				}
+			}
+			res.RequestTmpl = strings.Join(values, "&")
+			res.HeadersVal = withValue(res.HeadersVal, "Content-Type", "application/x-www-form-urlencoded")
+		case "":
+		default:
+			c.warn(itemPath, fmt.Sprintf("body mode `%s`", req.Body.Mode))
+		}
+	}
+	for _, e := range item.Event {
+		if e.Listen != "test" {
+			c.warnScript(itemPath, e)
+			continue
+		}
+		status, vars := c.testScript(itemPath, e.Script.Exec)
+		if status > 0 {
+			res.ResponseStatus = status
+		}
+		for k, v := range vars {
+			res.Variables = withValue(res.Variables, k, v)
+		}
+	}
+}
+
+// testScript translates status checks and variables extraction from
+// postman test script, other lines are reported
+func (c *PostmanConverter) testScript(itemPath string, exec PostmanExec) (int, map[string]string) {
+	status := 0
+	vars := map[string]string{}
+	aliases := map[string]bool{}
+	for _, line := range strings.Split(strings.Join(exec, "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || postmanIgnoredRx.MatchString(line) {
+			continue
+		}
+		if m := postmanJSONAliasRx.FindStringSubmatch(line); m != nil {
+			aliases[m[1]] = true
+			continue
+		}
+		if m := postmanStatusRx.FindStringSubmatch(line); m != nil {
+			status, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if m := postmanSetVarRx.FindStringSubmatch(line); m != nil {
+			if path, ok := jsonPathFromScript(m[3], aliases); ok {
+				vars[varName(m[2])] = path
+				continue
+			}
+		}
+		c.warn(itemPath, fmt.Sprintf("test script line `%s`", line))
+	}
+
+	return status, vars
+}
+
+// jsonPathFromScript converts expressions like pm.response.json().items[0].id
+// to gjson path items.0.id
+func jsonPathFromScript(expr string, aliases map[string]bool) (string, bool) {
+	switch {
+	case strings.HasPrefix(expr, "pm.response.json()"):
+		expr = strings.TrimPrefix(expr, "pm.response.json()")
+	default:
+		idx := strings.IndexAny(expr, ".[")
+		if idx < 0 {
+			idx = len(expr)
+		}
+		if !aliases[expr[:idx]] {
+			return "", false
+		}
+		expr = expr[idx:]
+	}
+	if expr == "" {
+		return "*", true
+	}
+	parts := []string{}
+	for expr != "" {
+		m := postmanAccessorRx.FindStringSubmatch(expr)
+		if m == nil {
+			return "", false
+		}
+		switch {
+		case m[1] != "":
+			parts = append(parts, m[1])
+		case m[2] != "":
+			parts = append(parts, m[2])
+		default:
+			parts = append(parts, strings.ReplaceAll(m[3], ".", `\.`))
+		}
+		expr = expr[len(m[0]):]
+	}
+
+	return strings.Join(parts, "."), true
+}
+
+func (c *PostmanConverter) warnScript(itemPath string, e PostmanEvent) {
+	for _, line := range e.Script.Exec {
+		if strings.TrimSpace(line) != "" {
+			c.warn(itemPath, fmt.Sprintf("%s script", e.Listen))
+			return
+		}
+	}
+}
+
+func (c *PostmanConverter) path(itemPath, raw string) string {
+	if m := postmanHostVarRx.FindStringSubmatch(raw); m != nil {
+		if !c.hostVars[m[1]] {
+			c.hostVars[m[1]] = true
+			c.warn(itemPath, fmt.Sprintf("variable `%s` used as host, requests are converted relative to default host", m[1]))
+		}
+		raw = raw[len(m[0]):]
+	} else if strings.Contains(raw, "://") {
+		host := postmanSchemeHostRx.FindString(raw)
+		c.warn(itemPath, fmt.Sprintf("host `%s`, request is converted relative to default host", host))
+		raw = raw[len(host):]
+	}
+	if !strings.HasPrefix(raw, "/") {
+		raw = "/" + raw
+	}
+	raw = c.vars(itemPath, raw)
+
+	return postmanPathVarRx.ReplaceAllString(raw, "/{{$$$1}}")
+}
+
+// vars converts postman variables {{name}} to declarate ones {{$name}}
+func (c *PostmanConverter) vars(itemPath, s string) string {
+	s = postmanDynamicVarRx.ReplaceAllStringFunc(s, func(v string) string {
+		name := postmanDynamicVarRx.FindStringSubmatch(v)[1]
+		if f, ok := postmanDynamicVars[name]; ok {
+			return f
+		}
+		c.warn(itemPath, fmt.Sprintf("dynamic variable `%s`", v))
+		return v
+	})
+
+	return postmanVarRx.ReplaceAllStringFunc(s, func(v string) string {
+		return "{{$" + varName(postmanVarRx.FindStringSubmatch(v)[1]) + "}}"
+	})
+}
+
+// formValue escapes form value, values with variables are left as is
+func (c *PostmanConverter) formValue(itemPath, s string) string {
+	if strings.Contains(s, "{{") {
+		return c.vars(itemPath, s)
+	}
+
+	return url.QueryEscape(s)
+}
+
+func (c *PostmanConverter) addVariables(res map[string]string, vars []PostmanVariable) {
+	for _, v := range vars {
+		if !v.isEnabled() || v.Key == "" {
+			continue
+		}
+		value := ""
+		if v.Value != nil {
+			value = fmt.Sprintf("%v", v.Value)
+		}
+		res[varName(v.Key)] = c.vars("variables", value)
+	}
+}
+
+func (c *PostmanConverter) warn(itemPath, msg string) {
+	c.warnings = append(c.warnings, itemPath+": "+msg)
+}
+
+func fileNameFor(name string) string {
+	name = strings.Trim(nonWordRx.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		name = "test"
+	}
+
+	return name + ".yaml"
+}
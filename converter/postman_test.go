@@ -0,0 +1,71 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPostmanConverter_Convert(t *testing.T) {
+	dir := t.TempDir()
+	c := NewPostman("./testdata/postman.json", "./testdata/postman_env.json", dir)
+	if err := c.Convert(); err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	pets := readConverted(t, dir+"/pets.yaml")
+	if len(pets) != 3 {
+		t.Fatalf("pets: expected variables and two steps, got %+v", pets)
+	}
+	vars := pets[0].Variables
+	if vars["baseUrl"] != "http://localhost:8181" || vars["password"] != "qwerty" || vars["unused"] != "" {
+		t.Errorf("pets: unexpected variables %v", vars)
+	}
+
+	create := pets[1]
+	if create.Method != "POST" || create.RequestURL != "/pets" || create.Query != "?source=postman" {
+		t.Errorf("create pet: unexpected request %+v", create)
+	}
+	if create.RequestTmpl != `{"name": "{{$name}}"}` || create.ResponseStatus != 201 {
+		t.Errorf("create pet: unexpected body or status %+v", create)
+	}
+	if create.HeadersVal["Authorization"] != "Bearer {{$token}}" {
+		t.Errorf("create pet: expected inherited bearer auth, got %v", create.HeadersVal)
+	}
+	if _, ok := create.HeadersVal["X-Disabled"]; ok {
+		t.Errorf("create pet: disabled header converted %v", create.HeadersVal)
+	}
+	if create.Variables["pet_id"] != "id" || create.Variables["first_tag"] != "tags.0.name" {
+		t.Errorf("create pet: unexpected variables %v", create.Variables)
+	}
+
+	details := pets[2]
+	if details.Name != "details" || len(details.Steps) != 1 || len(details.Steps[0].Steps) != 2 {
+		t.Fatalf("details: expected folder with request steps, got %+v", details)
+	}
+	get := details.Steps[0].Steps
+	if get[0].Variables["id"] != "{{$pet_id}}" || get[1].RequestURL != "/pets/{{$id}}" {
+		t.Errorf("get pet: unexpected steps %+v", get)
+	}
+	if _, ok := get[1].HeadersVal["Authorization"]; ok {
+		t.Errorf("get pet: noauth request has authorization %v", get[1].HeadersVal)
+	}
+
+	login := readConverted(t, dir+"/Pets_API.yaml")
+	if len(login) != 2 || login[1].RequestTmpl != "user=tom+smith&password={{$password}}" || login[1].RequestURL != "/login" {
+		t.Errorf("login: unexpected request %+v", login)
+	}
+
+	warnings := strings.Join(c.Warnings(), "\n")
+	for _, expected := range []string{
+		"dynamic variable `{{$guid}}`",
+		"test script line `pm.environment.set(\"now\", Date.now());`",
+		"test script line `pm.expect(jsonData.name).to.eql(\"Tom\");`",
+		"variable `baseUrl` used as host",
+		"Pets API/login: prerequest script",
+		"host `http://example.com`",
+	} {
+		if !strings.Contains(warnings, expected) {
+			t.Errorf("expected warning %s, got\n%s", expected, warnings)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+# create pet
+curl -X 'POST' -d '{"name": "Rex"}' -H 'Content-Type: application/json' 'http://127.0.0.1:8181/pets?source=curl'
+
+$ curl -s -u admin:qwerty \
+    -H "X-Request-Id: it's \"quoted\"" \
+    --compressed \
+    http://127.0.0.1:8181/pets/1
+curl -G -d limit=10 -d offset=20 --retry 3 localhost:8181/pets
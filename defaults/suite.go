@@ -2,13 +2,24 @@ package defaults
 
 import (
 	"testing"
+	"time"
 
 	"github.com/ixpectus/declarate/commands/db"
 	"github.com/ixpectus/declarate/commands/echo"
+	"github.com/ixpectus/declarate/commands/email"
+	"github.com/ixpectus/declarate/commands/graphql"
+	"github.com/ixpectus/declarate/commands/grpc"
+	"github.com/ixpectus/declarate/commands/kafka"
+	"github.com/ixpectus/declarate/commands/nats"
+	"github.com/ixpectus/declarate/commands/redis"
 	"github.com/ixpectus/declarate/commands/request"
+	"github.com/ixpectus/declarate/commands/s3"
 	"github.com/ixpectus/declarate/commands/script"
 	"github.com/ixpectus/declarate/commands/shell"
+	"github.com/ixpectus/declarate/commands/socket"
+	"github.com/ixpectus/declarate/commands/sse"
 	"github.com/ixpectus/declarate/commands/vars"
+	"github.com/ixpectus/declarate/commands/websocket"
 	"github.com/ixpectus/declarate/compare"
 	"github.com/ixpectus/declarate/contract"
 	"github.com/ixpectus/declarate/eval"
@@ -36,6 +47,33 @@ type SuiteConfig struct {
 	Continue        bool
 	FailFast        bool
 	AllPersistent   bool
+	ReproDir        string
+	// MaxDuration is default max duration of steps without maxDuration
+	MaxDuration time.Duration
+	// CookieJar enables cookie jar shared by all request steps of a file
+	CookieJar bool
+	// Transport is default http transport config of request steps
+	Transport request.TransportConfig
+	// Auth is default authentication of request steps
+	Auth *request.AuthConfig
+	// Services are named services selected by `service` field of request step
+	Services map[string]request.ServiceConfig
+	// GraphQLSchema is default schema file used to validate graphql queries
+	GraphQLSchema string
+	// GRPCAddress is default server address of grpc steps
+	GRPCAddress string
+	// KafkaBrokers are default seed brokers of kafka steps
+	KafkaBrokers []string
+	// RedisConns are redis urls or addresses by connection name, `default`
+	// is used by steps without conn
+	RedisConns map[string]string
+	// NATSURL is default server url of nats steps
+	NATSURL string
+	// SMTPAddr is address of smtp sink started for the suite run, for
+	// example `127.0.0.1:2525`, email steps read messages received by it
+	SMTPAddr string
+	// S3 is default endpoint and credentials of s3 steps
+	S3 s3.ConnConfig
 }
 
 func NewDefaultSuite(conf SuiteConfig) *suite.Suite {
@@ -51,6 +89,24 @@ func NewDefaultSuite(conf SuiteConfig) *suite.Suite {
 	)
 	cmp := compare.New(contract.CompareParams{}, vv)
 	connLoader := db.NewPGLoader(conf.DefaultDBConn)
+	requests := request.NewUnmarshaller(
+		conf.DefaultHost,
+		cmp,
+		request.OptionDefaultRequestConfig(request.DefaultConfig{
+			CookieJar: conf.CookieJar,
+			Transport: conf.Transport,
+			Auth:      conf.Auth,
+		}),
+		request.OptionServices(conf.Services),
+	)
+	var (
+		services []contract.Service
+		sink     *email.Sink
+	)
+	if conf.SMTPAddr != "" {
+		sink = email.NewSink(conf.SMTPAddr)
+		services = append(services, sink)
+	}
 	var out contract.Output
 	out = &output.OutputPrintln{
 		WithProgressBar: conf.WithProgresBar,
@@ -72,12 +128,25 @@ func NewDefaultSuite(conf SuiteConfig) *suite.Suite {
 		Output:            out,
 		Continue:          conf.Continue,
 		PersistentStorage: persistentStorage,
+		ReproDir:          conf.ReproDir,
+		MaxDuration:       conf.MaxDuration,
+		Services:          services,
 		Builders: []contract.CommandBuilder{
 			&echo.Unmarshaller{},
 			vars.NewUnmarshaller(evaluator),
 			shell.NewUnmarshaller(cmp),
 			script.NewUnmarshaller(cmp),
-			request.NewUnmarshaller(conf.DefaultHost, cmp),
+			requests,
+			graphql.NewUnmarshaller(requests, cmp, graphql.OptionSchema(conf.GraphQLSchema)),
+			grpc.NewUnmarshaller(conf.GRPCAddress, cmp),
+			websocket.NewUnmarshaller(conf.DefaultHost, cmp),
+			sse.NewUnmarshaller(requests, cmp),
+			kafka.NewUnmarshaller(conf.KafkaBrokers, cmp),
+			redis.NewUnmarshaller(conf.RedisConns, cmp),
+			nats.NewUnmarshaller(conf.NATSURL, cmp),
+			email.NewUnmarshaller(sink, cmp),
+			s3.NewUnmarshaller(conf.S3, cmp),
+			socket.NewUnmarshaller(cmp),
 			db.NewUnmarshaller(connLoader, cmp),
 		},
 	})
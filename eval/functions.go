@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/brianvoe/gofakeit"
 	"github.com/maja42/goval"
@@ -92,6 +93,28 @@ var defaultFunctions = map[string]goval.ExpressionFunction{
 
 		return true, nil
 	},
+	"gt": compareNumbers(func(expected, actual float64) bool {
+		return actual > expected
+	}),
+	"gte": compareNumbers(func(expected, actual float64) bool {
+		return actual >= expected
+	}),
+	"lt": compareNumbers(func(expected, actual float64) bool {
+		return actual < expected
+	}),
+	"lte": compareNumbers(func(expected, actual float64) bool {
+		return actual <= expected
+	}),
+	"notEqual": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return false, nil
+		}
+
+		return fmt.Sprintf("%v", args[0]) != fmt.Sprintf("%v", args[1]), nil
+	},
+	"contains":   compareStrings(strings.Contains),
+	"startsWith": compareStrings(strings.HasPrefix),
+	"endsWith":   compareStrings(strings.HasSuffix),
 	"oneOf": func(args ...interface{}) (interface{}, error) {
 		if len(args) == 0 {
 			return false, nil
@@ -111,6 +134,36 @@ var defaultFunctions = map[string]goval.ExpressionFunction{
 	},
 }
 
+// compareNumbers builds function comparing actual value, passed last, with expected one
+func compareNumbers(f func(expected, actual float64) bool) goval.ExpressionFunction {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return false, nil
+		}
+		expected, err := strconv.ParseFloat(fmt.Sprintf("%v", args[0]), 64)
+		if err != nil {
+			return false, nil
+		}
+		actual, err := strconv.ParseFloat(fmt.Sprintf("%v", args[1]), 64)
+		if err != nil {
+			return false, nil
+		}
+
+		return f(expected, actual), nil
+	}
+}
+
+// compareStrings builds function checking actual value, passed last, against expected substring
+func compareStrings(f func(s, substr string) bool) goval.ExpressionFunction {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return false, nil
+		}
+
+		return f(fmt.Sprintf("%v", args[1]), fmt.Sprintf("%v", args[0])), nil
+	}
+}
+
 func empty(val interface{}) bool {
 	v := reflect.ValueOf(val)
 	switch v.Kind() {
@@ -0,0 +1,40 @@
+package eval
+
+import "testing"
+
+func TestEval_CompareFunctions(t *testing.T) {
+	e := NewEval(nil)
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{expr: "$(gt(18, 20))", expected: "true"},
+		{expr: "$(gt(18, 18))", expected: "false"},
+		{expr: `$(gt(18, "20.5"))`, expected: "true"},
+		{expr: `$(gt(18, "bob"))`, expected: "false"},
+		{expr: "$(gt(18))", expected: "false"},
+		{expr: "$(gte(18, 18))", expected: "true"},
+		{expr: "$(gte(18, 17))", expected: "false"},
+		{expr: "$(lt(18, 17))", expected: "true"},
+		{expr: "$(lt(18, 18))", expected: "false"},
+		{expr: "$(lte(18, 18))", expected: "true"},
+		{expr: "$(lte(18, 19))", expected: "false"},
+		{expr: `$(notEqual("bob", "alice"))`, expected: "true"},
+		{expr: `$(notEqual(1, "1"))`, expected: "false"},
+		{expr: `$(notEqual("bob"))`, expected: "false"},
+		{expr: `$(contains("ob", "bob"))`, expected: "true"},
+		{expr: `$(contains("al", "bob"))`, expected: "false"},
+		{expr: `$(contains("ob"))`, expected: "false"},
+		{expr: `$(startsWith("bo", "bob"))`, expected: "true"},
+		{expr: `$(startsWith("ob", "bob"))`, expected: "false"},
+		{expr: `$(endsWith("ob", "bob"))`, expected: "true"},
+		{expr: `$(endsWith("bo", "bob"))`, expected: "false"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := e.Evaluate(tt.expr); got != tt.expected {
+				t.Errorf("Evaluate(%s) = %v, want %v", tt.expr, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/tools"
+)
+
+// Config sets stop conditions, without count and until messages are
+// collected until timeout
+type Config struct {
+	// Count stops collecting after count messages
+	Count int
+	// Until stops collecting after message matching it
+	Until    string
+	Timeout  time.Duration
+	Comparer contract.Comparer
+	Params   contract.CompareParams
+}
+
+// Collector collects messages until stop condition, reader of the source
+// calls Add for every message and Close when reading is finished
+type Collector struct {
+	cfg      Config
+	messages []string
+	// satisfied is set when count or until condition is met
+	satisfied bool
+	timedOut  bool
+	err       error
+	done      chan struct{}
+}
+
+func New(cfg Config) *Collector {
+	return &Collector{
+		cfg:  cfg,
+		done: make(chan struct{}),
+	}
+}
+
+// Add appends message, it returns true when stop condition is met and
+// reading should be finished
+func (c *Collector) Add(raw string) bool {
+	c.messages = append(c.messages, raw)
+	if c.cfg.Count > 0 && len(c.messages) >= c.cfg.Count {
+		c.satisfied = true
+		return true
+	}
+	if c.cfg.Until != "" {
+		errs, err := c.cfg.Comparer.CompareJsonBody(c.cfg.Until, raw, c.cfg.Params)
+		if len(errs) == 0 && err == nil {
+			c.satisfied = true
+			return true
+		}
+	}
+
+	return false
+}
+
+// Close finishes collecting, err is reading error, timedOut is set when
+// reading is stopped by timeout
+func (c *Collector) Close(err error, timedOut bool) {
+	c.err = err
+	c.timedOut = timedOut
+	close(c.done)
+}
+
+// Wait blocks until collecting is finished and returns json array of
+// messages, failure with title is returned when stop condition is not met
+func (c *Collector) Wait(title, unit string) (string, *contract.TestError, error) {
+	<-c.done
+	if c.err != nil {
+		return "", nil, c.err
+	}
+	res := "[" + strings.Join(c.messages, ",") + "]"
+	if c.satisfied || (c.cfg.Count == 0 && c.cfg.Until == "") {
+		return res, nil, nil
+	}
+	expected := fmt.Sprintf("%d %s", c.cfg.Count, unit)
+	if c.cfg.Until != "" {
+		expected = c.cfg.Until
+	}
+	reason := "closed before stop condition"
+	if c.timedOut {
+		reason = fmt.Sprintf("timeout %v exceeded", c.cfg.Timeout)
+	}
+
+	return res, &contract.TestError{
+		Title:         title,
+		Expected:      expected,
+		Actual:        tools.JSONPrettyPrint(res),
+		Message:       reason,
+		OriginalError: fmt.Errorf("%s, %s", title, reason),
+	}, nil
+}
+
+// Registry keeps background collectors by name, step waiting for
+// collector removes it after successful check, so poll retries see it
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]*Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: map[string]*Collector{},
+	}
+}
+
+func (r *Registry) Set(name string, c *Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors[name] = c
+}
+
+func (r *Registry) Get(name string) *Collector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.collectors[name]
+}
+
+// Remove removes collector when it is still registered with the name
+func (r *Registry) Remove(name string, c *Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.collectors[name] == c {
+		delete(r.collectors, name)
+	}
+}
@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/eval"
+	"github.com/ixpectus/declarate/variables"
+)
+
+func TestCollector_Wait(t *testing.T) {
+	cmp := compare.New(contract.CompareParams{}, variables.New(eval.NewEval(nil), nil, false))
+	tests := []struct {
+		name     string
+		cfg      Config
+		messages []string
+		stopped  bool
+		timedOut bool
+		err      error
+		res      string
+		failure  string
+	}{
+		{
+			name:     "count",
+			cfg:      Config{Count: 2},
+			messages: []string{`{"id": 1}`, `{"id": 2}`},
+			stopped:  true,
+			res:      `[{"id": 1},{"id": 2}]`,
+		},
+		{
+			name:     "until",
+			cfg:      Config{Until: `{"id": 2}`, Comparer: cmp},
+			messages: []string{`{"id": 1}`, `{"id": 2}`},
+			stopped:  true,
+			res:      `[{"id": 1},{"id": 2}]`,
+		},
+		{
+			name:     "no stop condition",
+			messages: []string{`{"id": 1}`},
+			timedOut: true,
+			res:      `[{"id": 1}]`,
+		},
+		{
+			name:     "timeout",
+			cfg:      Config{Count: 2, Timeout: time.Second},
+			messages: []string{`{"id": 1}`},
+			timedOut: true,
+			res:      `[{"id": 1}]`,
+			failure:  "timeout 1s exceeded",
+		},
+		{
+			name:     "closed",
+			cfg:      Config{Until: `{"id": 2}`, Comparer: cmp},
+			messages: []string{`{"id": 1}`},
+			res:      `[{"id": 1}]`,
+			failure:  "closed before stop condition",
+		},
+		{name: "error", cfg: Config{Count: 1}, err: errors.New("connection reset")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.cfg)
+			stopped := false
+			for _, v := range tt.messages {
+				stopped = c.Add(v)
+			}
+			if stopped != tt.stopped {
+				t.Errorf("Add() = %v, want %v", stopped, tt.stopped)
+			}
+			go c.Close(tt.err, tt.timedOut)
+			res, failure, err := c.Wait("messages not received", "messages")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Wait() error = %v, want %v", err, tt.err)
+			}
+			if res != tt.res {
+				t.Errorf("Wait() = %s, want %s", res, tt.res)
+			}
+			if tt.failure == "" && failure != nil {
+				t.Errorf("unexpected failure %v", failure)
+			}
+			if tt.failure != "" && (failure == nil || failure.Message != tt.failure || failure.Title != "messages not received") {
+				t.Errorf("expected failure %q, got %+v", tt.failure, failure)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	first, second := New(Config{}), New(Config{})
+	r.Set("events", first)
+	if r.Get("events") != first {
+		t.Fatalf("Get() returned unexpected collector")
+	}
+	r.Set("events", second)
+	// finished step of replaced collector does not remove the new one
+	r.Remove("events", first)
+	if r.Get("events") != second {
+		t.Fatalf("Remove() removed replaced collector")
+	}
+	r.Remove("events", second)
+	if r.Get("events") != nil {
+		t.Errorf("Remove() kept collector")
+	}
+}
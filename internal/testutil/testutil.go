@@ -0,0 +1,115 @@
+package testutil
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dailymotion/allure-go"
+	"gopkg.in/yaml.v2"
+
+	"github.com/ixpectus/declarate/contract"
+)
+
+// FileName is set to built commands depending on test file, so relative
+// paths of steps are resolved from testdata directory
+const FileName = "testdata/test.yaml"
+
+// Vars replaces {{$name}} with values of the map, without evaluation
+type Vars map[string]string
+
+func (m Vars) Apply(s string) string {
+	for k, v := range m {
+		s = strings.ReplaceAll(s, "{{$"+k+"}}", v)
+	}
+
+	return s
+}
+
+func (m Vars) Set(k, val string) error {
+	m[k] = val
+	return nil
+}
+
+func (m Vars) SetAll(m2 map[string]string) (map[string]string, error) {
+	for k, v := range m2 {
+		m[k] = v
+	}
+	return m2, nil
+}
+
+func (m Vars) Get(k string) string {
+	return m[k]
+}
+
+func (m Vars) SetPersistent(k, val string) error {
+	return m.Set(k, val)
+}
+
+// Report keeps the last attachment of every name
+type Report struct {
+	mu          sync.Mutex
+	attachments map[string]string
+}
+
+func (r *Report) AddAttachment(name string, mimeType allure.MimeType, content []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.attachments == nil {
+		r.attachments = map[string]string{}
+	}
+	r.attachments[name] = string(content)
+
+	return nil
+}
+
+// Attachment returns content of attachment with the name
+func (r *Report) Attachment(name string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.attachments[name]
+}
+
+// Build builds command of yaml step with vars and empty report
+func Build[T contract.Doer](t *testing.T, u contract.CommandBuilder, step string, vv contract.Vars) T {
+	t.Helper()
+	cmd, err := u.Build(func(v any) error {
+		return yaml.Unmarshal([]byte(step), v)
+	})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	res, ok := cmd.(T)
+	if !ok {
+		t.Fatalf("Build() returned %T for step\n%s", cmd, step)
+	}
+	res.SetVars(vv)
+	res.SetReport(&Report{})
+	if f, ok := cmd.(contract.FileAware); ok {
+		f.SetFileName(FileName)
+	}
+
+	return res
+}
+
+// Valid builds command and fails when it is not valid
+func Valid[T contract.Doer](t *testing.T, u contract.CommandBuilder, step string, vv contract.Vars) T {
+	t.Helper()
+	res := Build[T](t, u, step, vv)
+	if err := res.IsValid(); err != nil {
+		t.Fatalf("IsValid() error = %v", err)
+	}
+
+	return res
+}
+
+// Run builds valid command and runs it
+func Run[T contract.Doer](t *testing.T, u contract.CommandBuilder, step string, vv contract.Vars) T {
+	t.Helper()
+	res := Valid[T](t, u, step, vv)
+	if err := res.Do(); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	return res
+}
@@ -2,6 +2,7 @@ package run
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/ixpectus/declarate/contract"
 )
@@ -16,6 +17,9 @@ type runConfig struct {
 	Builders            []contract.CommandBuilder
 	Poll                *Poll  `yaml:"poll,omitempty"`
 	Condition           string `yaml:"condition,omitempty"`
+	// MaxDuration fails step when any of its commands runs longer, it is
+	// checked after command check passes, suite max duration is used when empty
+	MaxDuration time.Duration `yaml:"maxDuration,omitempty"`
 }
 
 func (u *runConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -77,6 +77,26 @@ func (r *Runner) logRunFail(name, fileName string, err error, res *Result) {
 }
 
 func (r *Runner) logErr(res Result) {
+	var errDuration *contract.DurationError
+	if errors.As(res.Err, &errDuration) {
+		r.output.Log(contract.Message{
+			Filename: res.FileName,
+			Name:     res.Name,
+			Message:  res.Err.Error(),
+			Title: fmt.Sprintf(
+				"failed %v:%v\nduration exceeded",
+				res.FileName,
+				res.Name,
+			),
+			Expected:            "<= " + errDuration.Max.String(),
+			Actual:              errDuration.Actual.String(),
+			Lvl:                 res.Lvl,
+			Type:                contract.MessageTypeError,
+			PollResult:          res.PollResult,
+			PollConditionFailed: res.PollConditionFailed,
+		})
+		return
+	}
 	var errTest *contract.TestError
 	if errors.As(res.Err, &errTest) {
 		r.output.Log(contract.Message{
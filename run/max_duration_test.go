@@ -0,0 +1,106 @@
+package run
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ixpectus/declarate/commands/shell"
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/eval"
+	"github.com/ixpectus/declarate/variables"
+)
+
+type recordingOutput struct {
+	messages []contract.Message
+}
+
+func (o *recordingOutput) Log(message contract.Message) {
+	o.messages = append(o.messages, message)
+}
+
+func (o *recordingOutput) SetReport(r contract.Report) {}
+
+func (o *recordingOutput) errors() []contract.Message {
+	res := []contract.Message{}
+	for _, v := range o.messages {
+		if v.Type == contract.MessageTypeError {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
+
+func TestRunner_MaxDuration(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		maxDuration time.Duration
+		title       string
+		expected    string
+	}{
+		{name: "within limit", file: "within.yaml", maxDuration: 50 * time.Millisecond},
+		{
+			name:     "exceeded",
+			file:     "exceeded.yaml",
+			title:    "duration exceeded",
+			expected: "<= 50ms",
+		},
+		{
+			name:        "suite default",
+			file:        "suite_default.yaml",
+			maxDuration: 50 * time.Millisecond,
+			title:       "duration exceeded",
+			expected:    "<= 50ms",
+		},
+		{name: "no limit", file: "suite_default.yaml"},
+		{
+			name:     "check failure reported first",
+			file:     "check_first.yaml",
+			title:    "response body differs",
+			expected: "2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vv := variables.New(eval.NewEval(nil), nil, false)
+			out := &recordingOutput{}
+			r := New(RunnerConfig{
+				Variables: vv,
+				Output:    out,
+				Builders: []contract.CommandBuilder{
+					shell.NewUnmarshaller(compare.New(contract.CompareParams{}, vv)),
+				},
+				MaxDuration: tt.maxDuration,
+			})
+			_, err := r.Run("./testdata/max_duration/"+tt.file, nil)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			errs := out.errors()
+			if tt.title == "" {
+				if len(errs) > 0 {
+					t.Fatalf("expected pass, got %+v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 {
+				t.Fatalf("expected one failure, got %+v", errs)
+			}
+			if !strings.HasSuffix(errs[0].Title, "\n"+tt.title) {
+				t.Errorf("expected title ending with %q, got %q", tt.title, errs[0].Title)
+			}
+			if errs[0].Expected != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, errs[0].Expected)
+			}
+			if tt.title == "duration exceeded" {
+				actual, err := time.ParseDuration(errs[0].Actual)
+				if err != nil || actual < 200*time.Millisecond {
+					t.Errorf("expected actual duration >= 200ms, got %q", errs[0].Actual)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package run
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ixpectus/declarate/contract"
+)
+
+var reproNameRx = regexp.MustCompile(`[^\w-]+`)
+
+type reproStep struct {
+	name string
+	cmd  contract.Doer
+}
+
+// addRepro remembers executed command, repeated execution on poll keeps
+// only the last one
+func (r *Runner) addRepro(name string, cmd contract.Doer) {
+	if r.config.ReproDir == "" {
+		return
+	}
+	if _, ok := cmd.(contract.Reproducer); !ok {
+		return
+	}
+	for i, v := range r.repro {
+		if v.cmd == cmd {
+			r.repro = append(r.repro[:i], r.repro[i+1:]...)
+			break
+		}
+	}
+	r.repro = append(r.repro, reproStep{name: name, cmd: cmd})
+}
+
+// reproFailed writes reproduction script of failed file when repro dir is set
+func (r *Runner) reproFailed(fileName string) {
+	if r.config.ReproDir == "" {
+		return
+	}
+	target, err := r.writeRepro(fileName)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	log.Printf("reproduction script for %s written to %s\n", fileName, target)
+}
+
+// writeRepro writes shell script which repeats all commands of failed file
+// with variables substituted by values they had during run
+func (r *Runner) writeRepro(fileName string) (string, error) {
+	lines := []string{
+		"#!/bin/sh",
+		"# reproduction of failed test " + fileName,
+	}
+	for _, v := range r.repro {
+		cmd := v.cmd.(contract.Reproducer).Repro()
+		if cmd == "" {
+			continue
+		}
+		lines = append(lines, "")
+		if v.name != "" {
+			lines = append(lines, "# "+strings.ReplaceAll(v.name, "\n", " "))
+		}
+		lines = append(lines, cmd)
+	}
+	name := strings.Trim(reproNameRx.ReplaceAllString(strings.TrimSuffix(fileName, filepath.Ext(fileName)), "_"), "_")
+	target := filepath.Join(r.config.ReproDir, name+".sh")
+	if err := os.MkdirAll(r.config.ReproDir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("create repro dir: %w", err)
+	}
+	if err := os.WriteFile(target, []byte(strings.Join(lines, "\n")+"\n"), 0o755); err != nil {
+		return "", fmt.Errorf("write repro script: %w", err)
+	}
+
+	return target, nil
+}
@@ -0,0 +1,126 @@
+package run
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ixpectus/declarate/commands/echo"
+	"github.com/ixpectus/declarate/commands/shell"
+	"github.com/ixpectus/declarate/compare"
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/eval"
+	"github.com/ixpectus/declarate/output"
+	"github.com/ixpectus/declarate/variables"
+)
+
+const reproTest = `
+- name: create user
+  shell_cmd: echo {{$name}}
+- name: no repro for echo
+  echo:
+    message: hello
+- name: check user
+  shell_cmd: echo {{$name}} | tr a-z A-Z
+  shell_response: alice
+- name: never run
+  shell_cmd: echo never
+`
+
+func TestRunner_Repro(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "users.yaml")
+	if err := os.WriteFile(fileName, []byte(reproTest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	vv := variables.New(eval.NewEval(nil), nil, false)
+	if err := vv.Set("name", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	reproDir := filepath.Join(dir, "repro")
+	r := New(RunnerConfig{
+		Variables: vv,
+		Output:    &output.Output{},
+		Builders: []contract.CommandBuilder{
+			&echo.Unmarshaller{},
+			shell.NewUnmarshaller(compare.New(contract.CompareParams{}, vv)),
+		},
+		ReproDir: reproDir,
+	})
+	if _, err := r.Run(fileName, nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(reproDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected one script, got %v", entries)
+	}
+	target := filepath.Join(reproDir, entries[0].Name())
+	if !strings.HasSuffix(target, "_users.sh") {
+		t.Errorf("unexpected script name %s", target)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0o100 == 0 {
+		t.Errorf("script is not executable: %v", info.Mode())
+	}
+	b, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		"#!/bin/sh",
+		"# reproduction of failed test " + fileName,
+		"",
+		"# create user",
+		"echo bob",
+		"",
+		"# check user",
+		"echo bob | tr a-z A-Z",
+		"",
+	}, "\n")
+	if string(b) != expected {
+		t.Errorf("expected script\n%s\ngot\n%s", expected, b)
+	}
+}
+
+type reproCmd struct {
+	contract.Doer
+	cmd string
+}
+
+func (c *reproCmd) Repro() string {
+	return c.cmd
+}
+
+func TestRunner_addRepro(t *testing.T) {
+	first := &reproCmd{cmd: "echo first"}
+	polled := &reproCmd{cmd: "curl http://localhost/status"}
+	r := &Runner{config: RunnerConfig{ReproDir: t.TempDir()}}
+	r.addRepro("first", first)
+	r.addRepro("poll", polled)
+	r.addRepro("no repro", &echo.Echo{})
+	r.addRepro("first again", first)
+	// poll repeats the same command, only the last execution is kept
+	r.addRepro("poll", polled)
+
+	got := []string{}
+	for _, v := range r.repro {
+		got = append(got, v.name)
+	}
+	if strings.Join(got, ",") != "first again,poll" {
+		t.Errorf("unexpected repro steps %v", got)
+	}
+
+	r = &Runner{}
+	r.addRepro("first", first)
+	if len(r.repro) != 0 {
+		t.Errorf("expected no steps without repro dir, got %v", r.repro)
+	}
+}
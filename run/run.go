@@ -24,6 +24,7 @@ type Runner struct {
 	config      RunnerConfig
 	output      contract.Output
 	currentVars contract.Vars
+	repro       []reproStep
 }
 
 type RunnerConfig struct {
@@ -35,6 +36,12 @@ type RunnerConfig struct {
 	comparer     contract.Comparer
 	pollComparer contract.Comparer
 	Report       contract.Report
+	// ReproDir is a directory for reproduction scripts of failed tests,
+	// scripts are not written when empty
+	ReproDir string
+	// MaxDuration is default max duration of step commands, it is used
+	// when step has no maxDuration
+	MaxDuration time.Duration
 }
 
 func New(c RunnerConfig) *Runner {
@@ -82,6 +89,7 @@ func (r *Runner) Run(fileName string, t *testing.T) (bool, error) {
 	if err != nil {
 		return true, fmt.Errorf("unmarshall failed for file %s: %w", fileName, err)
 	}
+	r.repro = nil
 	for _, v := range configs {
 		if len(v.Commands) == 0 && len(v.Steps) == 0 {
 			// nothing to do
@@ -95,21 +103,25 @@ func (r *Runner) Run(fileName string, t *testing.T) (bool, error) {
 		var testResult *Result
 		res := true
 		var err error
+		// fail writes reproduction script before FailNow, it exits the
+		// goroutine and code after the step is not run
+		fail := func() {
+			res = false
+			r.reproFailed(fileName)
+			if t != nil {
+				t.FailNow()
+			}
+		}
 		action := func() {
 			testResult, err = r.run(v, fileName)
 			if err != nil {
 				r.logRunFail(v.Name, fileName, err, testResult)
-				if t != nil {
-					t.FailNow()
-				}
-				res = false
+				fail()
+				return
 			}
 			if testResult.Err != nil {
 				r.logErr(*testResult)
-				if t != nil {
-					t.FailNow()
-				}
-				res = false
+				fail()
 			} else {
 				r.logPass(v.Name, fileName, testResult, 0)
 			}
@@ -234,24 +246,41 @@ func (r *Runner) runWithPollInterval(v runConfig, fileName string) (*Result, err
 	return testResult, err
 }
 
-func (r *Runner) setupCommand(cmd contract.Doer) contract.Doer {
+func (r *Runner) setupCommand(cmd contract.Doer, fileName string) contract.Doer {
 	cmd.SetVars(r.currentVars)
 	cmd.SetReport(r.config.Report)
+	if c, ok := cmd.(contract.FileAware); ok {
+		c.SetFileName(fileName)
+	}
 
 	return cmd
 }
 
-func (r *Runner) runCommand(cmd contract.Doer) (*string, error) {
-	cmd = r.setupCommand(cmd)
+// runCommand runs command and checks its result, max duration is checked
+// only when check passes, so wrong response is reported even for slow command
+func (r *Runner) runCommand(
+	cmd contract.Doer,
+	fileName string,
+	maxDuration time.Duration,
+) (*string, error) {
+	cmd = r.setupCommand(cmd, fileName)
 
+	start := time.Now()
 	if err := cmd.Do(); err != nil {
 		return nil, err
 	}
+	duration := time.Since(start)
 	responseBody := cmd.ResponseBody()
 
 	if err := cmd.Check(); err != nil {
 		return responseBody, err
 	}
+	if maxDuration > 0 && duration > maxDuration {
+		return responseBody, &contract.DurationError{
+			Max:    maxDuration,
+			Actual: duration.Round(time.Millisecond),
+		}
+	}
 	return responseBody, nil
 }
 
@@ -272,12 +301,17 @@ func (r *Runner) runOne(
 		}
 	}()
 	conf.Name = r.currentVars.Apply(conf.Name)
+	maxDuration := conf.MaxDuration
+	if maxDuration == 0 {
+		maxDuration = r.config.MaxDuration
+	}
 
 	for _, command := range conf.Commands {
 		r.beforeTestStep(fileName, &conf, lvl)
 		var err error
 
-		commandResponseBody, err = r.runCommand(command)
+		commandResponseBody, err = r.runCommand(command, fileName, maxDuration)
+		r.addRepro(conf.Name, command)
 		if err != nil {
 			res := &Result{
 				Err:      err,
@@ -5,6 +5,8 @@ import (
 	"os"
 
 	"gopkg.in/yaml.v2"
+
+	"github.com/ixpectus/declarate/contract"
 )
 
 func (r *Runner) Validate(fileName string) error {
@@ -19,6 +21,9 @@ func (r *Runner) Validate(fileName string) error {
 	}
 	for _, v := range configs {
 		for _, c := range v.Commands {
+			if f, ok := c.(contract.FileAware); ok {
+				f.SetFileName(fileName)
+			}
 			if err := c.IsValid(); err != nil {
 				return fmt.Errorf("invalid command, %w", err)
 			}
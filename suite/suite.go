@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/ixpectus/declarate/condition"
@@ -43,6 +44,11 @@ type RunConfig struct {
 	TestRunWrapper    contract.TestWrapper
 	T                 *testing.T
 	PersistentStorage contract.Persistent
+	ReproDir          string
+	// MaxDuration is default max duration of steps without maxDuration
+	MaxDuration time.Duration
+	// Services are started after tests validation and stopped after run
+	Services []contract.Service
 }
 
 type Suite struct {
@@ -166,12 +172,14 @@ func (s *Suite) Run() error {
 	}
 
 	runner := run.New(run.RunnerConfig{
-		Variables: s.Config.Variables,
-		Output:    s.Config.Output,
-		Builders:  s.Config.Builders,
-		Report:    s.Config.Report,
-		Wrapper:   s.Config.TestRunWrapper,
-		T:         s.Config.T,
+		Variables:   s.Config.Variables,
+		Output:      s.Config.Output,
+		Builders:    s.Config.Builders,
+		Report:      s.Config.Report,
+		Wrapper:     s.Config.TestRunWrapper,
+		T:           s.Config.T,
+		ReproDir:    s.Config.ReproDir,
+		MaxDuration: s.Config.MaxDuration,
 	},
 	)
 
@@ -185,6 +193,12 @@ func (s *Suite) Run() error {
 	if err := s.validate(tests, runner); err != nil {
 		return err
 	}
+	stop, err := s.startServices()
+	if err != nil {
+		return err
+	}
+	defer stop()
+	defer s.stopBuilders()
 	failed := false
 	for _, v := range tests {
 		definitions, err := s.testsDefinitions([]string{v})
@@ -257,6 +271,38 @@ func (s *Suite) Run() error {
 	return nil
 }
 
+// startServices starts services, returned func stops started ones
+func (s *Suite) startServices() (func(), error) {
+	started := []contract.Service{}
+	stop := func() {
+		for i := len(started) - 1; i >= 0; i-- {
+			if err := started[i].Stop(); err != nil {
+				log.Printf("stop service: %v\n", err)
+			}
+		}
+	}
+	for _, v := range s.Config.Services {
+		if err := v.Start(); err != nil {
+			stop()
+			return nil, fmt.Errorf("start service: %w", err)
+		}
+		started = append(started, v)
+	}
+
+	return stop, nil
+}
+
+// stopBuilders stops builders keeping resources between steps
+func (s *Suite) stopBuilders() {
+	for _, v := range s.Config.Builders {
+		if stopper, ok := v.(contract.Stopper); ok {
+			if err := stopper.Stop(); err != nil {
+				log.Printf("stop command: %v\n", err)
+			}
+		}
+	}
+}
+
 func (s *Suite) validate(tests []string, runner *run.Runner) error {
 	hasInvalid := false
 	for _, v := range tests {
@@ -0,0 +1,153 @@
+package suite
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ixpectus/declarate/contract"
+	"github.com/ixpectus/declarate/eval"
+	"github.com/ixpectus/declarate/variables"
+)
+
+// events records services calls and steps in order
+type events struct {
+	list []string
+}
+
+func (e *events) add(event string) {
+	e.list = append(e.list, event)
+}
+
+type recordService struct {
+	name     string
+	events   *events
+	startErr error
+}
+
+func (s *recordService) Start() error {
+	s.events.add("start " + s.name)
+	return s.startErr
+}
+
+func (s *recordService) Stop() error {
+	s.events.add("stop " + s.name)
+	return nil
+}
+
+// recordCommand records step execution, `record: <name>`
+type recordCommand struct {
+	name   string
+	events *events
+}
+
+func (c *recordCommand) Do() error {
+	c.events.add("step " + c.name)
+	return nil
+}
+
+func (c *recordCommand) ResponseBody() *string {
+	return nil
+}
+
+func (c *recordCommand) IsValid() error {
+	return nil
+}
+
+func (c *recordCommand) GetConfig() interface{} {
+	return c.name
+}
+
+func (c *recordCommand) Check() error {
+	return nil
+}
+
+func (c *recordCommand) SetVars(vv contract.Vars) {}
+
+func (c *recordCommand) SetReport(r contract.ReportAttachement) {}
+
+type recordBuilder struct {
+	events *events
+}
+
+func (b *recordBuilder) Build(unmarshal func(interface{}) error) (contract.Doer, error) {
+	cfg := struct {
+		Record string `yaml:"record"`
+	}{}
+	if err := unmarshal(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Record == "" {
+		return nil, nil
+	}
+
+	return &recordCommand{name: cfg.Record, events: b.events}, nil
+}
+
+// stopBuilder records stop of builder keeping resources between steps
+type stopBuilder struct {
+	recordBuilder
+}
+
+func (b *stopBuilder) Stop() error {
+	b.events.add("stop builder")
+	return nil
+}
+
+type quietOutput struct{}
+
+func (o *quietOutput) Log(message contract.Message) {}
+
+func (o *quietOutput) SetReport(r contract.Report) {}
+
+func TestSuite_Services(t *testing.T) {
+	startErr := errors.New("port is busy")
+	tests := []struct {
+		name    string
+		failing bool
+		want    []string
+	}{
+		{
+			name: "started before steps and stopped after",
+			want: []string{
+				"start db", "start cache",
+				"step first", "step second", "step last",
+				"stop builder", "stop cache", "stop db",
+			},
+		},
+		{
+			name:    "start error",
+			failing: true,
+			want:    []string{"start db", "start cache", "stop db"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ee := &events{}
+			cache := &recordService{name: "cache", events: ee}
+			if tt.failing {
+				cache.startErr = startErr
+			}
+			s := New("./testdata/services", RunConfig{
+				NoColor:   true,
+				Variables: variables.New(eval.NewEval(nil), nil, false),
+				Output:    &quietOutput{},
+				Builders:  []contract.CommandBuilder{&stopBuilder{recordBuilder{events: ee}}},
+				Services: []contract.Service{
+					&recordService{name: "db", events: ee},
+					cache,
+				},
+			})
+			err := s.Run()
+			if tt.failing && !errors.Is(err, startErr) {
+				t.Errorf("Run() error = %v, want start error", err)
+			}
+			if !tt.failing && err != nil {
+				t.Errorf("Run() error = %v", err)
+			}
+			if strings.Join(ee.list, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("events = %v, want %v", ee.list, tt.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/ixpectus/declarate/contract"
 )
 
 const (
@@ -63,6 +65,38 @@ func JSONPrettyPrint(in string) string {
 	return out.String()
 }
 
+// CompareJSON compares json bodies, differences are returned as test error
+// with pretty printed bodies
+func CompareJSON(comparer contract.Comparer, title, expected, actual string, params contract.CompareParams) error {
+	errs, err := comparer.CompareJsonBody(expected, actual, params)
+	if len(errs) > 0 {
+		return Differs(title, JSONPrettyPrint(expected), JSONPrettyPrint(actual), errs)
+	}
+	if err != nil {
+		return fmt.Errorf("compare json failed: %w", err)
+	}
+
+	return nil
+}
+
+// Differs makes test error from comparison errors, title is like
+// `response differs`
+func Differs(title, expected, actual string, errs []error) *contract.TestError {
+	msgs := make([]string, 0, len(errs))
+	for _, v := range errs {
+		msgs = append(msgs, v.Error())
+	}
+	msg := strings.Join(msgs, "\n")
+
+	return &contract.TestError{
+		Title:         title,
+		Expected:      expected,
+		Actual:        actual,
+		Message:       msg,
+		OriginalError: fmt.Errorf("%s: %v", title, msg),
+	}
+}
+
 func JSONRemarshal(in string) (string, error) {
 	var ifce interface{}
 	err := json.Unmarshal([]byte(in), &ifce)
@@ -144,3 +178,71 @@ func FormatVariables(m map[string]string) string {
 
 	return strings.Join(vv, "\n")
 }
+
+// ShellQuote quotes string for safe usage as single shell argument
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ShellArgs splits text to arguments like posix shell does, single and
+// double quotes group arguments, backslash escapes the next character and
+// joins lines
+func ShellArgs(s string) ([]string, error) {
+	var (
+		res     []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+	)
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+				continue
+			}
+			current.WriteRune(r)
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+				continue
+			}
+			if r == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[i+1]) {
+				i++
+				r = runes[i]
+			}
+			current.WriteRune(r)
+		case r == '\\':
+			if i+1 < len(runes) {
+				i++
+				if runes[i] == '\n' {
+					continue
+				}
+				current.WriteRune(runes[i])
+				inArg = true
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				res = append(res, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in `%s`", s)
+	}
+	if inArg {
+		res = append(res, current.String())
+	}
+
+	return res, nil
+}
@@ -0,0 +1,39 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShellArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{
+			name: "quotes",
+			in:   `SET key "a \"b\" c" 'd e'`,
+			want: []string{"SET", "key", `a "b" c`, "d e"},
+		},
+		{
+			name: "line continuation",
+			in:   "curl -H 'Accept: */*' \\\n  http://localhost/a\\ b",
+			want: []string{"curl", "-H", "Accept: */*", "http://localhost/a b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ShellArgs(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("ShellArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if _, err := ShellArgs(`GET "key`); err == nil {
+		t.Errorf("ShellArgs() error = nil, want unterminated quote error")
+	}
+}